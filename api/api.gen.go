@@ -22,9 +22,124 @@ import (
 )
 
 const (
+	AdminTokenScopes = "AdminToken.Scopes"
 	BearerAuthScopes = "BearerAuth.Scopes"
 )
 
+// Defines values for AccountLoginState.
+const (
+	AccountLoginStateLoggedIn   AccountLoginState = "logged_in"
+	AccountLoginStateNeedsLogin AccountLoginState = "needs_login"
+	AccountLoginStatePending    AccountLoginState = "pending"
+)
+
+// Defines values for ErrorResponseCode.
+const (
+	InstanceLimitReached ErrorResponseCode = "instance_limit_reached"
+	InternalError        ErrorResponseCode = "internal_error"
+	InvalidInstance      ErrorResponseCode = "invalid_instance"
+	InvalidProfile       ErrorResponseCode = "invalid_profile"
+	NotFound             ErrorResponseCode = "not_found"
+	NotLinked            ErrorResponseCode = "not_linked"
+	NotLoggedIn          ErrorResponseCode = "not_logged_in"
+	RateLimited          ErrorResponseCode = "rate_limited"
+	SessionLimitReached  ErrorResponseCode = "session_limit_reached"
+	SubjectBlocked       ErrorResponseCode = "subject_blocked"
+	UnknownAccount       ErrorResponseCode = "unknown_account"
+	UpstreamError        ErrorResponseCode = "upstream_error"
+	UpstreamForbidden    ErrorResponseCode = "upstream_forbidden"
+	UpstreamUnauthorized ErrorResponseCode = "upstream_unauthorized"
+	UpstreamUnavailable  ErrorResponseCode = "upstream_unavailable"
+)
+
+// Defines values for LinkStatusStatus.
+const (
+	LinkStatusStatusFailed  LinkStatusStatus = "failed"
+	LinkStatusStatusLinked  LinkStatusStatus = "linked"
+	LinkStatusStatusNone    LinkStatusStatus = "none"
+	LinkStatusStatusPending LinkStatusStatus = "pending"
+)
+
+// Defines values for PoolStateStrategy.
+const (
+	Claim       PoolStateStrategy = "claim"
+	LeastLoaded PoolStateStrategy = "least-loaded"
+	Sticky      PoolStateStrategy = "sticky"
+)
+
+// AccountLogin defines model for AccountLogin.
+type AccountLogin struct {
+	// Error Why the last login attempt failed
+	Error *string `json:"error,omitempty"`
+
+	// ExpiresAt When the user code expires (pending only)
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+
+	// Name Account name
+	Name string `json:"name"`
+
+	// State Login state of the account
+	State AccountLoginState `json:"state"`
+
+	// UserCode Code to enter at the verification URL (pending only)
+	UserCode *string `json:"userCode,omitempty"`
+
+	// VerificationUri URL to open to log in (pending only)
+	VerificationUri *string `json:"verificationUri,omitempty"`
+
+	// VerificationUriComplete Verification URL with the user code filled in (pending only)
+	VerificationUriComplete *string `json:"verificationUriComplete,omitempty"`
+}
+
+// AccountLoginState Login state of the account
+type AccountLoginState string
+
+// AdminSession defines model for AdminSession.
+type AdminSession struct {
+	// Account Pool account the session was created on, omitted for linked accounts
+	Account *string `json:"account,omitempty"`
+
+	// CreatedAt When the session was created
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+
+	// ExpiresAt Expiration time of the session
+	ExpiresAt time.Time `json:"expiresAt"`
+
+	// Instance Instance of the session, omitted for the default session
+	Instance *string `json:"instance,omitempty"`
+
+	// LeaseExpiresAt Time the session is terminated without a heartbeat (leases enabled)
+	LeaseExpiresAt *time.Time `json:"leaseExpiresAt,omitempty"`
+
+	// Linked Whether the session was created with the subject's own account
+	Linked bool `json:"linked"`
+
+	// Profile UUID of the game profile
+	Profile *string `json:"profile,omitempty"`
+
+	// RefreshError Error of the last failed background refresh
+	RefreshError *string `json:"refreshError,omitempty"`
+
+	// RefreshFailures Consecutive failed background refreshes
+	RefreshFailures *int `json:"refreshFailures,omitempty"`
+
+	// RefreshedAt When the session was last refreshed
+	RefreshedAt *time.Time `json:"refreshedAt,omitempty"`
+
+	// Subject JWT subject owning the session
+	Subject string `json:"subject"`
+}
+
+// AdminSessionList defines model for AdminSessionList.
+type AdminSessionList struct {
+	Sessions []AdminSession `json:"sessions"`
+}
+
+// BlockedSubjects defines model for BlockedSubjects.
+type BlockedSubjects struct {
+	Subjects []string `json:"subjects"`
+}
+
 // DownloadResponse defines model for DownloadResponse.
 type DownloadResponse struct {
 	// Url Signed download URL
@@ -36,10 +151,16 @@ type DownloadResponse struct {
 
 // ErrorResponse defines model for ErrorResponse.
 type ErrorResponse struct {
+	// Code Machine-readable error classification
+	Code *ErrorResponseCode `json:"code,omitempty"`
+
 	// Error Error message
 	Error string `json:"error"`
 }
 
+// ErrorResponseCode Machine-readable error classification
+type ErrorResponseCode string
+
 // GameSession defines model for GameSession.
 type GameSession struct {
 	// ExpiresAt Expiration time of the session
@@ -48,6 +169,15 @@ type GameSession struct {
 	// IdentityToken Identity token for authentication
 	IdentityToken string `json:"identityToken"`
 
+	// Instance Instance of the session, omitted for the default session (multi-user mode only)
+	Instance *string `json:"instance,omitempty"`
+
+	// LeaseExpiresAt Time the session is terminated without a heartbeat (multi-user mode with leases enabled)
+	LeaseExpiresAt *time.Time `json:"leaseExpiresAt,omitempty"`
+
+	// LeaseTtl Seconds a heartbeat keeps the session alive (multi-user mode with leases enabled)
+	LeaseTtl *int `json:"leaseTtl,omitempty"`
+
 	// SessionToken Session token for the game server
 	SessionToken string `json:"sessionToken"`
 }
@@ -61,28 +191,182 @@ type HealthResponse struct {
 	Version string `json:"version"`
 }
 
+// LinkStatus defines model for LinkStatus.
+type LinkStatus struct {
+	// Error Why the last device flow failed or the linked login expired
+	Error *string `json:"error,omitempty"`
+
+	// ExpiresAt When the user code expires (pending only)
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+
+	// Owner Owner of the linked account
+	Owner *string `json:"owner,omitempty"`
+
+	// Profile Username of the default game profile of the linked account
+	Profile *string `json:"profile,omitempty"`
+
+	// Status State of the link
+	Status LinkStatusStatus `json:"status"`
+
+	// UserCode Code to enter at the verification URL (pending only)
+	UserCode *string `json:"userCode,omitempty"`
+
+	// VerificationUri URL to open to authorize HSM (pending only)
+	VerificationUri *string `json:"verificationUri,omitempty"`
+
+	// VerificationUriComplete Verification URL with the user code filled in (pending only)
+	VerificationUriComplete *string `json:"verificationUriComplete,omitempty"`
+}
+
+// LinkStatusStatus State of the link
+type LinkStatusStatus string
+
+// LoginStatus defines model for LoginStatus.
+type LoginStatus struct {
+	Accounts []AccountLogin `json:"accounts"`
+}
+
+// LogoutResponse defines model for LogoutResponse.
+type LogoutResponse struct {
+	// Removed Whether the local session was removed
+	Removed bool `json:"removed"`
+
+	// Revoked Whether the OAuth tokens were revoked
+	Revoked bool `json:"revoked"`
+
+	// TerminatedGameSessions Number of game sessions terminated upstream
+	TerminatedGameSessions int `json:"terminatedGameSessions"`
+}
+
 // MessageResponse defines model for MessageResponse.
 type MessageResponse struct {
 	// Message Response message
 	Message string `json:"message"`
 }
 
+// PoolAccount defines model for PoolAccount.
+type PoolAccount struct {
+	// ActiveSessions Game sessions currently assigned to the account
+	ActiveSessions int `json:"activeSessions"`
+
+	// Available False while the account is skipped after a failure
+	Available bool `json:"available"`
+
+	// CooldownUntil When the account is tried again
+	CooldownUntil *time.Time `json:"cooldownUntil,omitempty"`
+
+	// LastError Last error that put the account in cooldown
+	LastError *string `json:"lastError,omitempty"`
+
+	// LoggedIn Whether the account holds an OAuth session
+	LoggedIn bool `json:"loggedIn"`
+
+	// Name Account name
+	Name string `json:"name"`
+
+	// Owner Account owner reported by the profiles endpoint
+	Owner *string `json:"owner,omitempty"`
+
+	// Profile Username of the default game profile
+	Profile *string `json:"profile,omitempty"`
+}
+
+// PoolState defines model for PoolState.
+type PoolState struct {
+	Accounts []PoolAccount `json:"accounts"`
+
+	// Strategy How subjects are assigned to accounts
+	Strategy PoolStateStrategy `json:"strategy"`
+}
+
+// PoolStateStrategy How subjects are assigned to accounts
+type PoolStateStrategy string
+
+// SessionList defines model for SessionList.
+type SessionList struct {
+	Sessions []GameSession `json:"sessions"`
+}
+
+// TerminateResponse defines model for TerminateResponse.
+type TerminateResponse struct {
+	// Terminated Number of game sessions terminated
+	Terminated int `json:"terminated"`
+}
+
+// Instance defines model for Instance.
+type Instance = string
+
+// AdminLogoutParams defines parameters for AdminLogout.
+type AdminLogoutParams struct {
+	// Force Remove the local session even if token revocation fails
+	Force *bool `form:"force,omitempty" json:"force,omitempty"`
+}
+
+// AdminListSessionsParams defines parameters for AdminListSessions.
+type AdminListSessionsParams struct {
+	// Subject Only list the sessions of this subject
+	Subject *string `form:"subject,omitempty" json:"subject,omitempty"`
+}
+
 // GetDownloadURLParams defines parameters for GetDownloadURL.
 type GetDownloadURLParams struct {
 	// Patchline Patchline to download (defaults to "release")
 	Patchline *string `form:"patchline,omitempty" json:"patchline,omitempty"`
 }
 
+// StreamEventsParams defines parameters for StreamEvents.
+type StreamEventsParams struct {
+	// LastEventID Id of the last event received, the stream resumes after it
+	LastEventID *string `json:"Last-Event-ID,omitempty"`
+}
+
 // DeleteSessionParams defines parameters for DeleteSession.
 type DeleteSessionParams struct {
 	// Token Session token (required in single-user mode)
 	Token *string `form:"token,omitempty" json:"token,omitempty"`
+
+	// Instance Instance of the game session, so a user can run several servers (multi-user
+	// mode only). Without it the user's default session is used. An instance
+	// claim of the JWT takes precedence.
+	Instance *Instance `form:"instance,omitempty" json:"instance,omitempty"`
+}
+
+// GetSessionParams defines parameters for GetSession.
+type GetSessionParams struct {
+	// Instance Instance of the game session, so a user can run several servers (multi-user
+	// mode only). Without it the user's default session is used. An instance
+	// claim of the JWT takes precedence.
+	Instance *Instance `form:"instance,omitempty" json:"instance,omitempty"`
+}
+
+// CreateSessionParams defines parameters for CreateSession.
+type CreateSessionParams struct {
+	// Profile Game profile UUID or username to create the session for (defaults to the configured profile)
+	Profile *string `form:"profile,omitempty" json:"profile,omitempty"`
+
+	// Instance Instance of the game session, so a user can run several servers (multi-user
+	// mode only). Without it the user's default session is used. An instance
+	// claim of the JWT takes precedence.
+	Instance *Instance `form:"instance,omitempty" json:"instance,omitempty"`
+}
+
+// HeartbeatSessionParams defines parameters for HeartbeatSession.
+type HeartbeatSessionParams struct {
+	// Instance Instance of the game session, so a user can run several servers (multi-user
+	// mode only). Without it the user's default session is used. An instance
+	// claim of the JWT takes precedence.
+	Instance *Instance `form:"instance,omitempty" json:"instance,omitempty"`
 }
 
 // RefreshSessionParams defines parameters for RefreshSession.
 type RefreshSessionParams struct {
 	// Token Session token (required in single-user mode)
 	Token *string `form:"token,omitempty" json:"token,omitempty"`
+
+	// Instance Instance of the game session, so a user can run several servers (multi-user
+	// mode only). Without it the user's default session is used. An instance
+	// claim of the JWT takes precedence.
+	Instance *Instance `form:"instance,omitempty" json:"instance,omitempty"`
 }
 
 // GetDownloadURLPlainParams defines parameters for GetDownloadURLPlain.
@@ -91,6 +375,17 @@ type GetDownloadURLPlainParams struct {
 	Patchline *string `form:"patchline,omitempty" json:"patchline,omitempty"`
 }
 
+// CreateGameSessionEnvParams defines parameters for CreateGameSessionEnv.
+type CreateGameSessionEnvParams struct {
+	// Profile Game profile UUID or username to create the session for (defaults to the configured profile)
+	Profile *string `form:"profile,omitempty" json:"profile,omitempty"`
+
+	// Instance Instance of the game session, so a user can run several servers (multi-user
+	// mode only). Without it the user's default session is used. An instance
+	// claim of the JWT takes precedence.
+	Instance *Instance `form:"instance,omitempty" json:"instance,omitempty"`
+}
+
 // GetVersionPlainParams defines parameters for GetVersionPlain.
 type GetVersionPlainParams struct {
 	// Patchline Patchline to check (defaults to "release")
@@ -99,27 +394,75 @@ type GetVersionPlainParams struct {
 
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// List blocked subjects
+	// (GET /admin/v1/blocked)
+	AdminListBlocked(w http.ResponseWriter, r *http.Request)
+	// Unblock a subject
+	// (DELETE /admin/v1/blocked/{subject})
+	AdminUnblockSubject(w http.ResponseWriter, r *http.Request, subject string)
+	// Block a subject
+	// (PUT /admin/v1/blocked/{subject})
+	AdminBlockSubject(w http.ResponseWriter, r *http.Request, subject string)
+	// Get login state
+	// (GET /admin/v1/login)
+	AdminGetLogin(w http.ResponseWriter, r *http.Request)
+	// Log out
+	// (POST /admin/v1/logout)
+	AdminLogout(w http.ResponseWriter, r *http.Request, params AdminLogoutParams)
+	// Get account pool state
+	// (GET /admin/v1/pool)
+	AdminGetPool(w http.ResponseWriter, r *http.Request)
+	// Terminate all game sessions
+	// (DELETE /admin/v1/sessions)
+	AdminTerminateAllSessions(w http.ResponseWriter, r *http.Request)
+	// List tracked game sessions
+	// (GET /admin/v1/sessions)
+	AdminListSessions(w http.ResponseWriter, r *http.Request, params AdminListSessionsParams)
+	// Terminate the game sessions of a subject
+	// (DELETE /admin/v1/sessions/{subject})
+	AdminTerminateSubjectSessions(w http.ResponseWriter, r *http.Request, subject string)
+	// Refresh the game sessions of a subject
+	// (POST /admin/v1/sessions/{subject}/refresh)
+	AdminRefreshSubjectSessions(w http.ResponseWriter, r *http.Request, subject string)
 	// Get download URL
 	// (GET /api/v1/download)
 	GetDownloadURL(w http.ResponseWriter, r *http.Request, params GetDownloadURLParams)
+	// Stream session and version events
+	// (GET /api/v1/events)
+	StreamEvents(w http.ResponseWriter, r *http.Request, params StreamEventsParams)
+	// Unlink own account
+	// (DELETE /api/v1/link)
+	DeleteLink(w http.ResponseWriter, r *http.Request)
+	// Get linked account
+	// (GET /api/v1/link)
+	GetLink(w http.ResponseWriter, r *http.Request)
+	// Link own account
+	// (POST /api/v1/link)
+	CreateLink(w http.ResponseWriter, r *http.Request)
 	// Delete a session
 	// (DELETE /api/v1/session)
 	DeleteSession(w http.ResponseWriter, r *http.Request, params DeleteSessionParams)
 	// Get current session
 	// (GET /api/v1/session)
-	GetSession(w http.ResponseWriter, r *http.Request)
+	GetSession(w http.ResponseWriter, r *http.Request, params GetSessionParams)
 	// Create a new session
 	// (POST /api/v1/session)
-	CreateSession(w http.ResponseWriter, r *http.Request)
+	CreateSession(w http.ResponseWriter, r *http.Request, params CreateSessionParams)
+	// Renew the lease of a session
+	// (POST /api/v1/session/heartbeat)
+	HeartbeatSession(w http.ResponseWriter, r *http.Request, params HeartbeatSessionParams)
 	// Refresh a session
 	// (POST /api/v1/session/refresh)
 	RefreshSession(w http.ResponseWriter, r *http.Request, params RefreshSessionParams)
+	// List sessions
+	// (GET /api/v1/sessions)
+	ListSessions(w http.ResponseWriter, r *http.Request)
 	// Get download URL (plain text)
 	// (GET /download)
 	GetDownloadURLPlain(w http.ResponseWriter, r *http.Request, params GetDownloadURLPlainParams)
 	// Create session (env format)
 	// (POST /game-session)
-	CreateGameSessionEnv(w http.ResponseWriter, r *http.Request)
+	CreateGameSessionEnv(w http.ResponseWriter, r *http.Request, params CreateGameSessionEnvParams)
 	// Health check
 	// (GET /health)
 	GetHealth(w http.ResponseWriter, r *http.Request)
@@ -137,6 +480,276 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(http.Handler) http.Handler
 
+// AdminListBlocked operation middleware
+func (siw *ServerInterfaceWrapper) AdminListBlocked(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, AdminTokenScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AdminListBlocked(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// AdminUnblockSubject operation middleware
+func (siw *ServerInterfaceWrapper) AdminUnblockSubject(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "subject" -------------
+	var subject string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "subject", r.PathValue("subject"), &subject, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "subject", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, AdminTokenScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AdminUnblockSubject(w, r, subject)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// AdminBlockSubject operation middleware
+func (siw *ServerInterfaceWrapper) AdminBlockSubject(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "subject" -------------
+	var subject string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "subject", r.PathValue("subject"), &subject, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "subject", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, AdminTokenScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AdminBlockSubject(w, r, subject)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// AdminGetLogin operation middleware
+func (siw *ServerInterfaceWrapper) AdminGetLogin(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, AdminTokenScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AdminGetLogin(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// AdminLogout operation middleware
+func (siw *ServerInterfaceWrapper) AdminLogout(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, AdminTokenScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params AdminLogoutParams
+
+	// ------------- Optional query parameter "force" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "force", r.URL.Query(), &params.Force)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "force", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AdminLogout(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// AdminGetPool operation middleware
+func (siw *ServerInterfaceWrapper) AdminGetPool(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, AdminTokenScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AdminGetPool(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// AdminTerminateAllSessions operation middleware
+func (siw *ServerInterfaceWrapper) AdminTerminateAllSessions(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, AdminTokenScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AdminTerminateAllSessions(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// AdminListSessions operation middleware
+func (siw *ServerInterfaceWrapper) AdminListSessions(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, AdminTokenScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params AdminListSessionsParams
+
+	// ------------- Optional query parameter "subject" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "subject", r.URL.Query(), &params.Subject)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "subject", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AdminListSessions(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// AdminTerminateSubjectSessions operation middleware
+func (siw *ServerInterfaceWrapper) AdminTerminateSubjectSessions(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "subject" -------------
+	var subject string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "subject", r.PathValue("subject"), &subject, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "subject", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, AdminTokenScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AdminTerminateSubjectSessions(w, r, subject)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// AdminRefreshSubjectSessions operation middleware
+func (siw *ServerInterfaceWrapper) AdminRefreshSubjectSessions(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "subject" -------------
+	var subject string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "subject", r.PathValue("subject"), &subject, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "subject", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, AdminTokenScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AdminRefreshSubjectSessions(w, r, subject)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 // GetDownloadURL operation middleware
 func (siw *ServerInterfaceWrapper) GetDownloadURL(w http.ResponseWriter, r *http.Request) {
 
@@ -170,6 +783,112 @@ func (siw *ServerInterfaceWrapper) GetDownloadURL(w http.ResponseWriter, r *http
 	handler.ServeHTTP(w, r)
 }
 
+// StreamEvents operation middleware
+func (siw *ServerInterfaceWrapper) StreamEvents(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params StreamEventsParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "Last-Event-ID" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("Last-Event-ID")]; found {
+		var LastEventID string
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "Last-Event-ID", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithOptions("simple", "Last-Event-ID", valueList[0], &LastEventID, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: false})
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "Last-Event-ID", Err: err})
+			return
+		}
+
+		params.LastEventID = &LastEventID
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.StreamEvents(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// DeleteLink operation middleware
+func (siw *ServerInterfaceWrapper) DeleteLink(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteLink(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetLink operation middleware
+func (siw *ServerInterfaceWrapper) GetLink(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetLink(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// CreateLink operation middleware
+func (siw *ServerInterfaceWrapper) CreateLink(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateLink(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 // DeleteSession operation middleware
 func (siw *ServerInterfaceWrapper) DeleteSession(w http.ResponseWriter, r *http.Request) {
 
@@ -192,6 +911,14 @@ func (siw *ServerInterfaceWrapper) DeleteSession(w http.ResponseWriter, r *http.
 		return
 	}
 
+	// ------------- Optional query parameter "instance" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "instance", r.URL.Query(), &params.Instance)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "instance", Err: err})
+		return
+	}
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.DeleteSession(w, r, params)
 	}))
@@ -206,14 +933,27 @@ func (siw *ServerInterfaceWrapper) DeleteSession(w http.ResponseWriter, r *http.
 // GetSession operation middleware
 func (siw *ServerInterfaceWrapper) GetSession(w http.ResponseWriter, r *http.Request) {
 
+	var err error
+
 	ctx := r.Context()
 
 	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
 
 	r = r.WithContext(ctx)
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetSessionParams
+
+	// ------------- Optional query parameter "instance" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "instance", r.URL.Query(), &params.Instance)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "instance", Err: err})
+		return
+	}
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetSession(w, r)
+		siw.Handler.GetSession(w, r, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -226,14 +966,68 @@ func (siw *ServerInterfaceWrapper) GetSession(w http.ResponseWriter, r *http.Req
 // CreateSession operation middleware
 func (siw *ServerInterfaceWrapper) CreateSession(w http.ResponseWriter, r *http.Request) {
 
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params CreateSessionParams
+
+	// ------------- Optional query parameter "profile" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "profile", r.URL.Query(), &params.Profile)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "profile", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "instance" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "instance", r.URL.Query(), &params.Instance)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "instance", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateSession(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// HeartbeatSession operation middleware
+func (siw *ServerInterfaceWrapper) HeartbeatSession(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
 	ctx := r.Context()
 
 	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
 
 	r = r.WithContext(ctx)
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params HeartbeatSessionParams
+
+	// ------------- Optional query parameter "instance" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "instance", r.URL.Query(), &params.Instance)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "instance", Err: err})
+		return
+	}
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.CreateSession(w, r)
+		siw.Handler.HeartbeatSession(w, r, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -265,6 +1059,14 @@ func (siw *ServerInterfaceWrapper) RefreshSession(w http.ResponseWriter, r *http
 		return
 	}
 
+	// ------------- Optional query parameter "instance" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "instance", r.URL.Query(), &params.Instance)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "instance", Err: err})
+		return
+	}
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.RefreshSession(w, r, params)
 	}))
@@ -276,6 +1078,26 @@ func (siw *ServerInterfaceWrapper) RefreshSession(w http.ResponseWriter, r *http
 	handler.ServeHTTP(w, r)
 }
 
+// ListSessions operation middleware
+func (siw *ServerInterfaceWrapper) ListSessions(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListSessions(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 // GetDownloadURLPlain operation middleware
 func (siw *ServerInterfaceWrapper) GetDownloadURLPlain(w http.ResponseWriter, r *http.Request) {
 
@@ -312,14 +1134,35 @@ func (siw *ServerInterfaceWrapper) GetDownloadURLPlain(w http.ResponseWriter, r
 // CreateGameSessionEnv operation middleware
 func (siw *ServerInterfaceWrapper) CreateGameSessionEnv(w http.ResponseWriter, r *http.Request) {
 
+	var err error
+
 	ctx := r.Context()
 
 	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
 
 	r = r.WithContext(ctx)
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params CreateGameSessionEnvParams
+
+	// ------------- Optional query parameter "profile" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "profile", r.URL.Query(), &params.Profile)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "profile", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "instance" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "instance", r.URL.Query(), &params.Instance)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "instance", Err: err})
+		return
+	}
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.CreateGameSessionEnv(w, r)
+		siw.Handler.CreateGameSessionEnv(w, r, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -496,11 +1339,27 @@ func HandlerWithOptions(si ServerInterface, options StdHTTPServerOptions) http.H
 		ErrorHandlerFunc:   options.ErrorHandlerFunc,
 	}
 
+	m.HandleFunc("GET "+options.BaseURL+"/admin/v1/blocked", wrapper.AdminListBlocked)
+	m.HandleFunc("DELETE "+options.BaseURL+"/admin/v1/blocked/{subject}", wrapper.AdminUnblockSubject)
+	m.HandleFunc("PUT "+options.BaseURL+"/admin/v1/blocked/{subject}", wrapper.AdminBlockSubject)
+	m.HandleFunc("GET "+options.BaseURL+"/admin/v1/login", wrapper.AdminGetLogin)
+	m.HandleFunc("POST "+options.BaseURL+"/admin/v1/logout", wrapper.AdminLogout)
+	m.HandleFunc("GET "+options.BaseURL+"/admin/v1/pool", wrapper.AdminGetPool)
+	m.HandleFunc("DELETE "+options.BaseURL+"/admin/v1/sessions", wrapper.AdminTerminateAllSessions)
+	m.HandleFunc("GET "+options.BaseURL+"/admin/v1/sessions", wrapper.AdminListSessions)
+	m.HandleFunc("DELETE "+options.BaseURL+"/admin/v1/sessions/{subject}", wrapper.AdminTerminateSubjectSessions)
+	m.HandleFunc("POST "+options.BaseURL+"/admin/v1/sessions/{subject}/refresh", wrapper.AdminRefreshSubjectSessions)
 	m.HandleFunc("GET "+options.BaseURL+"/api/v1/download", wrapper.GetDownloadURL)
+	m.HandleFunc("GET "+options.BaseURL+"/api/v1/events", wrapper.StreamEvents)
+	m.HandleFunc("DELETE "+options.BaseURL+"/api/v1/link", wrapper.DeleteLink)
+	m.HandleFunc("GET "+options.BaseURL+"/api/v1/link", wrapper.GetLink)
+	m.HandleFunc("POST "+options.BaseURL+"/api/v1/link", wrapper.CreateLink)
 	m.HandleFunc("DELETE "+options.BaseURL+"/api/v1/session", wrapper.DeleteSession)
 	m.HandleFunc("GET "+options.BaseURL+"/api/v1/session", wrapper.GetSession)
 	m.HandleFunc("POST "+options.BaseURL+"/api/v1/session", wrapper.CreateSession)
+	m.HandleFunc("POST "+options.BaseURL+"/api/v1/session/heartbeat", wrapper.HeartbeatSession)
 	m.HandleFunc("POST "+options.BaseURL+"/api/v1/session/refresh", wrapper.RefreshSession)
+	m.HandleFunc("GET "+options.BaseURL+"/api/v1/sessions", wrapper.ListSessions)
 	m.HandleFunc("GET "+options.BaseURL+"/download", wrapper.GetDownloadURLPlain)
 	m.HandleFunc("POST "+options.BaseURL+"/game-session", wrapper.CreateGameSessionEnv)
 	m.HandleFunc("GET "+options.BaseURL+"/health", wrapper.GetHealth)
@@ -512,29 +1371,80 @@ func HandlerWithOptions(si ServerInterface, options StdHTTPServerOptions) http.H
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/+xZX3PTOBD/KhrdPaQzbpIe3Itn7qHQHA0HpdMEGKbNdIS9iQW2ZCQ5kGPy3W/0z39i",
-	"p2l7tIU7ntrY0u5q97e/Xa2/4ohnOWfAlMThVyyjBDJi/j3in1nKSXwGMudMgn6WC56DUBTMikKk+k8M",
-	"MhI0V5QzHOIJXTCIUex2o9dnL3CA1SoHHGKpBGULvA7wEoQ0Gzb3v7EvEJ8jlUAppy1jHWABnwoqIMbh",
-	"uTGmEjsrl/P3HyBSWuVICC62nwb067Y9ZhfKQEqygJ1WWCFd2p+RDCYg/aE3dH/JqQB5qDr061dE/0CK",
-	"ZuD9Ip2oAM+5yIjCIY6Jgn29psvfNAamqFpN+Ufo8PrYvUZKv0dzLhApVKIfRkZ5l0xnwxaR7rA1idru",
-	"Bcm08WIJYqczG/I3jxDUnNbl72MgqUq2h1sqogrZNtvuQ+611kKyPNWyE/NmdSM0T0AsaQTIL6jLO+gP",
-	"+8PdTvCGXAXtlxae20/r8dsy0G+5NsL9urYZBhFRIahaTTSNWM1PgAgQh4VK9K/35tefHrLP305xsGHR",
-	"87dTZDc56LSAaEhKa7bSKpMTpXK81oZQNuftwx6ejg0QM8LIgrIFOl4pkjZA6RNLIsIqFtP+V1SZsB1P",
-	"XqJ9v3NiN73UAkGgw9NxLVI+xOsA8xwYySkO8aP+sP8IBzgnKjEOGpCcDpYHg5Lpwq94AaorVKoQTNr0",
-	"b7OsMdiptgmMcqKiJKVMR1WjwThwHOMQPwPl+d0SdE4EyUCBkDg839R86uUgxSudvRjmpEiV1E8vsIAU",
-	"iIQLvKdzVW/7VIDQ6cKIiVbdGltn7BmNEBx6AR0InGkIWpwal/02HOo/EWcKmPEUyfPUAWTwQdpMrHT8",
-	"KmCOQ/zLoCp2A1fpBq0yZ/DTPP9RvZitA/z7N9TfrEodysdMgWAk9QC1JcZkW5FlRKxsOFsVlyx0KEvb",
-	"8Uxv8WCTVSWKIQXVwQxH5rmFW1QIAUz55OijMUOSskUK+4UEgTIeQ4AcTUiXtyb8qARWvwVCq2FSVrIr",
-	"MdgsJz1PSYi2LdkGQOUKRxWY+wTaJkd3hNqf0YYk1lB7fJ9Qe0JiE0SQCvWso6/2szHw4P4MfM10MeCC",
-	"/m29890looU0IrX+zCeix/lsHezmd59wrjLJktT1y1pBhBiZcPSyIlW0Cg3iLF3tddF+lW53hvR6q9vh",
-	"x6dNMnlwED0ePr4/5Se8Fs6CORQf3KcBCpEloSl5n0JXVneUlg363wLqnMsOVD8VQHQZIYjB5waeW+i0",
-	"Sx8eoJ6GI2NP/JPlNlnOBsqF9CpQtDuOgYC5AGkuA92AObMLNGQYgi9UKt2p14HzjdoPp+j/0n9cE/Qu",
-	"Pj+7jx+icHxnxOBSakf/o1nh3992JcpTQhlS8EXtuN+e6oU//iVXn3RgDt0M66akW9xfby36NrdT1Ksi",
-	"t7f9qqopf792Ub1pf2EGIsLBiCrDIQmkKQK2pIKzTLc0bnra3YrUKHPElvjWAapmfsfvpocvRpeT0dmb",
-	"0dnlZDSZjF+dXE5f/TU6+ePCFojLBARc4AvWXDs+Gp1Mx9N35WI/Di3Xd2BqV2ujfQJs6b3QSai3hsbu",
-	"NuZOUeeaFA+GXnXOva2sZEe81+KkpD4nbszeygm9Gf12UZOdMd9lh7sx/e5Egp1MU4n8YLs+wcXh+azu",
-	"TTcWjxKIPtbc505ivVebgu90X6qTVpU+28nm7rPQzZncWPzfoHH/aaz67vAARO4jdg0Ob8Kp+SngfKY9",
-	"ZXV1xfAFj0pbcGA/NeIBXs/W/wQAAP//9efUEbMcAAA=",
+	"H4sIAAAAAAAC/+xdbW/buLL+K4TuBU4LOE522wPcm4P7IW2zbfakL6iTszhYFwEjjW1uZFJLUvHmFvnv",
+	"B8MXiZIov6SJk+76W2JJ5JCceWbm4VD6mqRiXggOXKvk8GtSUEnnoEGa/0640pSngH9noFLJCs0ETw6r",
+	"K0RMiJ4BmdI5EAVKMcEHRAlCSalAkpRyIktOFFyDpDlRIK9BKvJsXuaa7eE9Yz4XGRDB85vnQ/IL0zNR",
+	"asK0aRdv+JsiGUxomWvfA2EKr2RDcsQJc6KMeZpTNvcS/fzLGdH0ChQpJKSQAU9hOObJIGE4gN9LkDfJ",
+	"IOF0Dslh4htJBolKZzCnOOQ5/eMU+FTPksMffvyfQaJvCrxXacn4NLm9vfU3m8k6SlNRcn0qptjB16SQ",
+	"ogCpGZirIKWQ3Xn8ZXZjpM2p0iTHRwnVGuaFJhPKcsiSTreDBP4omAR1pGPtAa8mjqQ4se5u8qwAnjE+",
+	"tTOdDJKJkHOqk8Mkoxr2NJtDrDM7Qe1+3GAJp/GnlKY68piZHGIu+nWitqVkkAAv58nhr0kuplPILhiu",
+	"FQfI1IWZmGSQuBEkXyI94oBfiyzSKf5KtCDANUhCrWJdg2QTllK8h5x/Pu1OT6eH8JFzybodYTNaEFHg",
+	"GghcTsL45g2/FvMih9j0/ast9ILpWWu5JyzPIVun49tBIuH3kknIcNrdUtqVq2dYXP4GqUYxj7I54yNr",
+	"gV0F9+vYEfqTELlfZSOrN+IFVSSVQDVkBFFDzJnGvydCkpzxK8j8Yyo2a+7RpVYQ6WptxV9iZcd4ya4C",
+	"Pu1V2fW2dg9sbXitkDWcI7zQAsZYLzlQBcf9gznDEYSTxRTRIOeMm5VZOECmZAZU6kugmjwzbSoCnF7m",
+	"kK0PJnZZowumZyB71aPSc1UadfybImLBA+xwPV0KkQPl2FUhxYTlkbk9Pz9503Bb/s6IvBImEtTsOA7e",
+	"5mfflEFwi9nkkqZXUylKnhHXwpLGf6IsLyWobvuvBVeQlppdQ3/LEBgH4xqmIIPGN7APM4DqsbWX1K1I",
+	"txN0wO4iLhYCUdNIlsORb7dSmtAiV8HTKVO6C1Gua/M30zA3f/y3hElymPzXfh0I7Tuvvh82mdxWfVIp",
+	"6U1XYN96TLhXuUivIBvZQamIbMGVSrbOZC8XwDcRE+CNWPBc0OwzqALVqitBKfPuIo7YlENGMvc0Op0e",
+	"D+a9Qsdj4QVvJL6dlauPwtTNxgZkjK9/NGk0FHhP0xnjsCeBZghdxIRlJM2pUpVnDUIRxq9pzrKLGiLK",
+	"QmkJdH5RclrqmZDs/yELf58IecmyDLAZLvTFBI01GSSSarjI2ZxZH+S0xf5yIYGm1ui8T4hcsKKEkapd",
+	"8ItLq1wt6a4py3GM4c9muE6wMMwq+RUXC35RA6q5wxse4xokp7l7PhZ+wTKInINSdAorV73dfL3ab+kc",
+	"ekOPLXjqDLhm+uZMXEFEy0/cZaLxunHOqBz4Y6VSW/D+YVZF6qRqW0FBu3O8idw5UsDnznQMkiAVPFON",
+	"vq8ACtWQl+boNdeVqes/XTs9C+5UMVjvIAnGDHe1ewvbbyvYKlf3DmiuZ/3gpzTVZSSgsM8Rdxl7oZhq",
+	"JIfJzFy52QjbRyCvWQrE3xC298PwYHiwehK8IMuA/pTxq1E1oM2z6gyMlJNcLHwU5dbLJRk27bYz/vj5",
+	"tlhwiAzpI/5cxZqN7CjWSn/0qxDJayT0EBJGwut306doozDDx2YCn8oFhyCXD+I7uzrfSXZfeX/ybvT+",
+	"u0ny3YJF7QzNoM/QqkR87dA5ZMNWRa5V6z1yiVL3g52EubhelVTmIjXsY53u+MdiuaOEa7EyT/14VOK6",
+	"IGArsgAJxD8Wa7J2nkEoE7GcD+X80hp6SKk2nK+P5iJ+qzWvPZ3WAxxUsxeb+fc2bOufeh/XdUbhH1k7",
+	"8vP3xcRAAumoZpfaionpcf98vm3MYlpKCVznN4QqZVMbLVo0ZDcWqEPpTvM/0VwBWcwQNYNmMFxSV6wo",
+	"ED4nBpiM7yklRLUjFSLH3Oica5Yv8TJB81oybHxK2fphLPrDHi7jFF2lzYb0jGpSlLrZIydeyGjLJpk4",
+	"4ctNxjc2EzkGcNzZUIcSCGbmbvRzjxP1j5nLREIhJBrUpY0WnPdTBHhWCPYAjnVdDraazUFbv0Nl7LOV",
+	"kefevxHCQ7vrIDj6fkk1TG8iYaZYeOZHESqhYWwBpVsR/kCV3kNewKbGmqVXGIqa/Zzky6pJqwQZLHck",
+	"98sNhRnpN1FDZx6k+3G2xvG7OIxN/EREQpMMpaVk+maEY7ciGWqsLzvSVLOUULzFpUip4BM2LaXncvf2",
+	"zNU9c3XPBp0SI6mLozfvTz5cnH385/EHvxlnIAGoDNOqmdaFIdbM7wgjKIe96ycPhj//cpYMIrSkfciJ",
+	"1snUV/R5a3L3iYjAy6cTkwvOKadTjMne3WiaN/LCen0or2k1Q+EybTInjCb3/JMj+9B7bBAkOfp0EiRL",
+	"PstCvCuA04Ilh8mL4cHwRTJICqpnZqH2zUTvX/+w72miw6/JFIwJoI6ZUZ9kKD7eiNbxquKTpFNJ09KP",
+	"BweJYda4BuuKaVHkbt72f1M2R6y3T5dZT5sPNbPanE13S4UkOM6XBz/cmwhNAjEiwHumlImsJXHkW6jS",
+	"KM7ftynOB6FJBf7oj1G4HGpyo2GqyeGvTSP99cvtF6QM53Mqb9DhM6XJZXuOB4mmU4WYYJ5NvmCbHRXa",
+	"/+oeuLVG4BOZiD6dc/PMqGLzwwqDX5fsGfiNelTkep++vljjl5YlhNv2bW/x5QH1uB0iR9bNDZ2U3Bvg",
+	"U1Tkg+2Jc+LYZA+JlsT5zs3J6Tmhgf62TWmQFGWEQ/pnTV46VZlIMbd7n7hsHBZN3z4kn+H3EpRWxt1w",
+	"WIy5v0asqjs3+/LghXE0hjRobRcMyfEfTJke/MNjrjS9IVZFSsxFUKwbE8PVMYKtoonY+qudpZvl29n5",
+	"n9fOX6208obDzH01mIu42jSJLiWyRy5HxtiPKWLTP8J4XQ6H5XRmAJ7CwltlyQ1ujHnIcTO+V0iRglL/",
+	"cMQE02RBmUMLz1u6TbEZEG8yY27z8iiBiihS8Y9Dcu7BgVfZsjLEwZhzgMxxbsirN/Do7yEeNTYhe0Hl",
+	"LTgS8QFtO6Q+IyrUqlrDUsabijB6aka+iTK/BV90aAa3hjIL68AKoWKbht5FqE5NaKSUx3FxVoCBI0+d",
+	"Pje4VWqKXZCmbG7xYdboNZEaL+maqjRyzA2XZbtuyfOtemlp6VVu7rORO0JBwzVwwiYuB8XBO2NDmlD1",
+	"VKlOhGyVqDqWKTmcIBHZJdAe1CW2mPm45SCSifLp2Qm6oR+3J85ZbJ0hG3TKsK6g0Fa6F9t1kpXXwSnz",
+	"ewwkLCTZLL8UU2INZCmmFELkK/1jwB2rlhljaKoKCRT3KaVQCgfQ2vUfjLlHHSaJZVTbzfCM2D3w0Oei",
+	"cHaBTMww5gH/XrHHVJGxt8NxssyPIaH6kG6sJn8jS4wXHcx/7y7Lr0JRD2mFlqnG/lB83zXwXtbDa0kN",
+	"ORJqSm0YqDFKi0LZ+3B8TMeXvmr5KM8bm3APpAddRjmWrESI4h3DtipV2LLXOPfaJg0SItrwMI968Qii",
+	"3NkjVGpJaJ438TdKlqx0Ca0ND2euXfwnAfq7vG1QYzkasmZzUEPSKOuyvoULTRhP8zJbQnwglxoY9tKI",
+	"8CPPb0jOVOMshLK7hky1OZFWAFhffRwSpFNjHYt03DpUa7tDlXvn7WOuSa3rBPvY+w2SOTExNuxLWFVV",
+	"qeq57rpEZZk/dITZuqbz3XGGO0e8c8RP2hHHLXtdcrMLKPv+uFMvR/TZ3nAnVOFiMSSnpnh7zL1zlsBh",
+	"scQ3uw7/7FCzjmceNYKNel5thZo2VLBdvqeHPy8PXm4Tf2qS0Rzf2QHgnwoAHSbcEf4KhuBXHWVbh7xS",
+	"3WN0Ju9wpTx2Y4YUVKeznHHoINlb0P4Anz2BtxS8Pvl2iBZ1n88cR6Xw13EiwRyDGSfPe3KNUJoI4ewb",
+	"SAZbxbnOOcaIgrwJpnnrUHYengvcNnAFVqpEKVPrIisMe/nj/z6GLBhpmOOMiO8eWO0P/nzjE9qb3oFq",
+	"E1Qb1Gv7ILDDR29xTYjEPS6tegFyZDpUrshxbwRck2PzyJCcdEkcg6NWxpRKyfxeoe2lep1IXc0JmX99",
+	"TJfrn+bikubEPZuzKyT3zR6ih2TBQ0T+R8wJE6YrUWjuWxuO+dkM7D9kwiDPTLl+9ROiJXkGw+nQizSs",
+	"Tt0PqkKeoTlGxvh0UN1V54cDL+WwWqvnZlgZ1ZQws+/pu6OK/Dz6+GFIXufMDFaCKudQHUuQkArOTdnK",
+	"DVHuMBE+zrIxD99tICEFdg2Za5fZ4gE8N7BnVm3v5A1uoWQgY7G4XWy7vKv810nWeKmC7c93PwgVwY5F",
+	"ucGwKla3ctS+rCHlN7JnGv7QVrX3rBRNK4y8JKh1JNkMxz362N5pq1Gt1QGzU6KMY+pDGntjfZY3iJTA",
+	"a5CHHl+D30Aec+TwbuRWt1CheQyyqlQYc6ZVRRW3ahRMJcKQnFLUyyb8lKpxVEgZK8NyHnOWJ2Y7b8wo",
+	"Tu0xykfczT/ndir+WjHVB9HSgKeXDRL3xiRnWhkztWJZy6rs8rVfnOPMyGjXyl2XRXiYq+Np/TzZnRbs",
+	"xx1jcN31v5+gk+08uLLXh8pjZSs4T+6o7F8Koe9J00xdWefweFvT4gThSFOpFaHNc/uiT+eG5CNPwZYp",
+	"26PZGBNGyxftCfHGQepBC5tdP3NCJb5PsOUJ4krNuNJAq5DF/WwC0nej98Mx/yTynLw9PiOhd6qGhCFM",
+	"rmOw/9p0/7iW8CZYBIUrA9nOItaziC3nkkeNut5mWnvb3EBc5QSCQErV79zpi6VsdKIaNZ0+ZSEn3Qkb",
+	"EMeYu+CJGN6JVBnBsCf+GVWnhJcmD81XszxzfWWxpetjvrR7CUt/UD+IL1Yt2H716tRHP5Tg5sMun7Pe",
+	"LTItr2hmFhyUJs98teuyNdkRdjvCbkfY3QdhZ3GTUL+E0ax5jTorj+qNNLk/JNsgzO/F9CeBro0j/t0V",
+	"eN10dn+5rPjJb5F2cpJWeNJjD/G8xEbjyp00CU2hJ3BfM155G754y76bVhozMi8T0cKfXmmcfBGyuZ2H",
+	"F4NXLLjmenf2qreRPP0IZ4UNjpqk3dajm3P74kw/47vgZRe87IKXewleLIg6uF0G2N1sdb96MeiyOjQO",
+	"C8fVA1XVa5uisU48oBmOuXkxl3udKJXgXyk6qBml2JtS8Te3fWZrMPjZ2WnrkH3zoIl7S1uMJXrnm/2u",
+	"YylT1edr+XaR1JOOpIzpNC2HbmqhG9SJIkj5F1WEVnlP5JKvD92xS3eNvepvBuy4pacOLLuYbBeT3T0m",
+	"83Wzm8K9WqtIdr3DAHfnm1qn9B4MNjeswkexd3tqywIOnMnYKbemyn17QbYiRU4ZJ1jhtaIE+1Nu37v7",
+	"nddh40j3zaA3LGJbXWJ956ZX+8y7N70pYXEPPd3V9d256w0c231M5Fpu69s7Wr8smTyrrfh5f40yepu9",
+	"YHt7U9bX5eUWUpiph1UzwCpgfs2k4HNT/mvfRBsniIPw+phf73jiJXpSf1jk3b/Pjk6PL0bHn/91/Pli",
+	"dDwanXx0rwv+v7HNrS5mIGGcjHnz3pM3xx/OTs7+Xd3sv7lS3Z8MVitki3DGZQd+7Rc6mgB9AxKuQS7v",
+	"cHaHs1vBWUfIVpxorfbPe2My+0qltSKyWfhtokbJd/UVLPO5oVhgZr9r9JAxfeuLS1FgMOIRpoj/mFLr",
+	"TGLjCKJtkKQzSK+C6bM/u9kLvry0cvpydFO6mrOVsaz7MN/mcayR+M8RxLo5IP6OHbzu4PVRw1hvvWtE",
+	"sO3jzuF3ENxxZzNvMXs+dW+idB+qMx/+TPaT2y+3/xkAV78+fQ9+AAA=",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"hsm/internal/config"
+	"hsm/internal/sessionstore"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	accountProfile   string
+	accountPatchline string
+	accountUse       bool
+)
+
+var accountCmd = &cobra.Command{
+	Use:   "account",
+	Short: "Manage named accounts",
+	Long: `Manage named Hytale accounts, each with its own session store, default profile
+and patchline. Select one per command with --account or HSM_ACCOUNT, or make it
+the current account with 'hsm account use'.`,
+	// Account management must work even if the selected account is broken
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+}
+
+var accountAddCmd = &cobra.Command{
+	Use:   "add NAME",
+	Short: "Add a named account",
+	Long: `Add a named account. Its session is kept in the store given with
+--session-store or --session-location, by default in accounts/NAME/session.json
+next to the config file. Log in with 'hsm login --account NAME' afterwards.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := getConfigPath()
+		cfg, err := config.Load(path)
+		if err != nil {
+			return err
+		}
+
+		store := sessionStoreURI
+		if store == "" {
+			store = sessionLocation
+		}
+		if store == "" {
+			store = config.DefaultSessionPath(path, args[0])
+		}
+		if _, err := sessionstore.Open(store); err != nil {
+			return err
+		}
+
+		if err := cfg.Add(config.Account{
+			Name:         args[0],
+			SessionStore: store,
+			Profile:      accountProfile,
+			Patchline:    accountPatchline,
+		}); err != nil {
+			return err
+		}
+		if accountUse {
+			_ = cfg.Use(args[0])
+		}
+		if err := cfg.Save(path); err != nil {
+			return err
+		}
+
+		fmt.Printf("Added account %s (session: %s)\n", args[0], store)
+		if cfg.CurrentAccount == args[0] {
+			fmt.Printf("Switched to account %s\n", args[0])
+		}
+		return nil
+	},
+}
+
+var accountListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List named accounts",
+	Long:    "List the named accounts. The current account is marked with '*'.",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load(getConfigPath())
+		if err != nil {
+			return err
+		}
+		if len(cfg.Accounts) == 0 {
+			fmt.Println("No accounts configured, add one with 'hsm account add NAME'")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "\tNAME\tSESSION\tPROFILE\tPATCHLINE")
+		for _, a := range cfg.Accounts {
+			marker := ""
+			if a.Name == cfg.CurrentAccount {
+				marker = "*"
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", marker, a.Name, a.SessionStore, a.Profile, a.Patchline)
+		}
+		return tw.Flush()
+	},
+}
+
+var accountUseCmd = &cobra.Command{
+	Use:   "use NAME",
+	Short: "Switch the current account",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := getConfigPath()
+		cfg, err := config.Load(path)
+		if err != nil {
+			return err
+		}
+		if err := cfg.Use(args[0]); err != nil {
+			return err
+		}
+		if err := cfg.Save(path); err != nil {
+			return err
+		}
+		fmt.Printf("Switched to account %s\n", args[0])
+		return nil
+	},
+}
+
+var accountRemoveCmd = &cobra.Command{
+	Use:     "remove NAME",
+	Aliases: []string{"rm"},
+	Short:   "Remove a named account",
+	Long:    "Remove a named account from the config. Its session is kept, run 'hsm logout --account NAME' first to revoke it.",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := getConfigPath()
+		cfg, err := config.Load(path)
+		if err != nil {
+			return err
+		}
+		if err := cfg.Remove(args[0]); err != nil {
+			return err
+		}
+		if err := cfg.Save(path); err != nil {
+			return err
+		}
+		fmt.Printf("Removed account %s\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	accountAddCmd.Flags().StringVar(&accountProfile, "profile", "", "Default game profile (UUID or username)")
+	accountAddCmd.Flags().StringVar(&accountPatchline, "patchline", "", "Default patchline (release or prerelease)")
+	accountAddCmd.Flags().BoolVar(&accountUse, "use", false, "Switch to the account after adding it")
+
+	accountCmd.AddCommand(accountAddCmd, accountListCmd, accountUseCmd, accountRemoveCmd)
+	rootCmd.AddCommand(accountCmd)
+}
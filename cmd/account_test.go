@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"hsm/internal/config"
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+	"hsm/internal/utils"
+)
+
+func TestAccountSelection(t *testing.T) {
+	backend := hsmtest.New(t)
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	backend.SetVersion(services.PatchlinePrerelease, "2026.03.01-prerelease")
+	t.Cleanup(func() {
+		configPath, accountName, profileSelector, accountPatchline, accountProfile = "", "", "", "", ""
+		accountUse = false
+		patchline = services.PatchlineRelease
+	})
+
+	endpoints := backend.Endpoints()
+	run := func(args ...string) {
+		t.Helper()
+		args = append(args, "--config", configPath,
+			"--oauth-url", endpoints.OAuth,
+			"--account-data-url", endpoints.AccountData,
+			"--game-assets-url", endpoints.GameAssets,
+			"--sessions-url", endpoints.Sessions,
+		)
+		rootCmd.SetArgs(args)
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+
+	run("account", "add", "prod")
+	run("account", "add", "staging", "--patchline", services.PatchlinePrerelease)
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	staging, err := cfg.Account("staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CurrentAccount != "prod" {
+		t.Fatalf("expected prod to be current, got %q", cfg.CurrentAccount)
+	}
+	if want := filepath.Join(dir, "accounts", "staging", "session.json"); staging.SessionStore != want {
+		t.Fatalf("expected default session store %s, got %s", want, staging.SessionStore)
+	}
+	backend.WriteSessionFile(t, staging.SessionStore)
+
+	// --account picks the staging session and its prerelease patchline
+	serverDir := filepath.Join(dir, "server")
+	run("update", "--account", "staging", "--output", serverDir)
+	if got := utils.GetVersion(filepath.Join(serverDir, versionFile)); got != "2026.03.01-prerelease" {
+		t.Fatalf("expected the account's patchline to be installed, got %q", got)
+	}
+
+	run("account", "use", "staging")
+	run("account", "remove", "prod")
+	cfg, err = config.Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CurrentAccount != "staging" || len(cfg.Accounts) != 1 {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+}
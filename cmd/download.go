@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"os"
 
-	"hsm/internal/client"
 	"hsm/internal/services"
 	"hsm/internal/utils"
 
@@ -21,7 +20,7 @@ var downloadCmd = &cobra.Command{
 	Short: "Download and extract Hytale game files",
 	Long:  "Download the Hytale game files from the specified patchline and extract them to the output directory.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		sessionService, err := services.NewSessionService(client.New(), GetSessionLocation())
+		sessionService, err := newSessionService(cmd.Context())
 		if err != nil {
 			return fmt.Errorf("failed to initialize session: %w (run 'hsm login' first)", err)
 		}
@@ -30,7 +29,7 @@ var downloadCmd = &cobra.Command{
 		downloadService := services.NewDownloadService(sessionService.Client())
 
 		fmt.Printf("Fetching download URL for patchline: %s\n", patchline)
-		url, version, err := downloadService.GetDownloadURL(patchline)
+		url, version, err := downloadService.GetDownloadURL(cmd.Context(), patchline)
 		if err != nil {
 			return fmt.Errorf("failed to get download URL: %w", err)
 		}
@@ -40,7 +39,7 @@ var downloadCmd = &cobra.Command{
 			return fmt.Errorf("failed to create output directory: %w", err)
 		}
 
-		if err := utils.DownloadAndExtract(url, outputDir); err != nil {
+		if err := utils.DownloadAndExtract(cmd.Context(), url, outputDir); err != nil {
 			return fmt.Errorf("download failed: %w", err)
 		}
 
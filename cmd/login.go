@@ -2,26 +2,44 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"hsm/internal/client"
 	"hsm/internal/services"
-	"hsm/internal/utils"
 	"os"
 
 	"github.com/spf13/cobra"
 )
 
-var stdoutFlag bool
+var (
+	stdoutFlag    bool
+	loginRestarts int
+)
 
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Login via device flow",
 	Long:  "Authenticate with Hytale using the OAuth2 device flow and save the session.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		deviceFlow := services.NewDeviceFlowService(client.New())
-		session, err := deviceFlow.Flow(context.Background())
+		c := newClient()
+		deviceFlow := services.NewDeviceFlowService(c, services.WithRestartOnExpiry(loginRestarts))
+		session, err := deviceFlow.Flow(cmd.Context())
 		if err != nil {
-			return fmt.Errorf("failed to initiate device flow: %w", err)
+			return loginError(err)
+		}
+
+		// Remember the selected profile for later commands
+		if profileSelector != "" {
+			profiles, err := c.WithToken(session.AccessToken).GetProfiles(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to get profiles: %w", err)
+			}
+			profile, err := services.ResolveProfile(profiles.Profiles, profileSelector)
+			if err != nil {
+				return err
+			}
+			session.Profile = profile.UUID
+			fmt.Printf("Using profile %s (%s)\n", profile.Username, profile.UUID)
 		}
 
 		if stdoutFlag {
@@ -47,19 +65,37 @@ var loginCmd = &cobra.Command{
 			_, _ = fmt.Fprintln(os.Stdout, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 			_, _ = fmt.Fprintln(os.Stdout, "")
 		} else {
-			// Save session to JSON file
-			sessionPath := GetSessionLocation()
-			if err := utils.SaveSessionToFile(sessionPath, session); err != nil {
+			// Save session to the configured store
+			store, err := GetSessionStore()
+			if err != nil {
+				return err
+			}
+			if err := store.Save(cmd.Context(), session); err != nil {
 				return fmt.Errorf("failed to save session: %w", err)
 			}
 
-			fmt.Printf("Session saved to %s\n", sessionPath)
+			fmt.Printf("Session saved to %s\n", store)
 		}
 
 		return nil
 	},
 }
 
+// loginError turns device flow failures into actionable messages
+func loginError(err error) error {
+	switch {
+	case errors.Is(err, client.ErrAccessDenied):
+		return fmt.Errorf("login was denied in the browser, run 'hsm login' again to retry")
+	case errors.Is(err, client.ErrExpiredToken):
+		return fmt.Errorf("the login code expired before it was confirmed, run 'hsm login' again or use --restart-on-expiry")
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("login cancelled")
+	}
+	return fmt.Errorf("failed to complete device flow: %w", err)
+}
+
 func init() {
 	loginCmd.Flags().BoolVar(&stdoutFlag, "stdout", false, "Output session token to stdout instead of saving to file")
+	loginCmd.Flags().StringVar(&profileSelector, "profile", "", "Game profile (UUID or username) to remember for this login")
+	loginCmd.Flags().IntVar(&loginRestarts, "restart-on-expiry", 0, "Restart the login with a new code up to this many times when the code expires")
 }
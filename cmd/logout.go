@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"hsm/internal/services"
+
+	"github.com/spf13/cobra"
+)
+
+var logoutForce bool
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Log out and revoke the session",
+	Long: `Terminate the game sessions created with the saved login, revoke the OAuth
+tokens at the authorization server and securely remove the session file.
+
+If revocation fails the session file is kept so the logout can be retried,
+use --force to remove it anyway.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := GetSessionStore()
+		if err != nil {
+			return err
+		}
+		result, err := services.Logout(cmd.Context(), newClient(), store, logoutForce)
+		if result != nil && result.TerminatedGameSessions > 0 {
+			fmt.Printf("Terminated %d game session(s)\n", result.TerminatedGameSessions)
+		}
+		if err != nil && (result == nil || !result.Removed) {
+			if result != nil && !result.Revoked {
+				return fmt.Errorf("%w\nthe session at %s was kept, retry or use --force to remove it", err, store)
+			}
+			return err
+		}
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+
+		fmt.Printf("Logged out, removed %s\n", store)
+		return nil
+	},
+}
+
+func init() {
+	logoutCmd.Flags().BoolVar(&logoutForce, "force", false, "Remove the session file even if token revocation fails")
+	addRetryFlags(logoutCmd)
+	rootCmd.AddCommand(logoutCmd)
+}
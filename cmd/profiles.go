@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var profilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "List the game profiles of the logged in account",
+	Long:  "List the Hytale game profiles owned by the logged in account. The profile used for game sessions is marked with '*'.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sessionService, err := newSessionService(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to initialize session: %w (run 'hsm login' first)", err)
+		}
+		defer sessionService.Close()
+
+		fmt.Printf("Owner: %s\n\n", sessionService.Owner())
+
+		selected := sessionService.Profile()
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "\tUSERNAME\tUUID")
+		for _, p := range sessionService.Profiles() {
+			marker := ""
+			if p.UUID == selected.UUID {
+				marker = "*"
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", marker, p.Username, p.UUID)
+		}
+		return tw.Flush()
+	},
+}
+
+func init() {
+	profilesCmd.Flags().StringVar(&profileSelector, "profile", "", "Mark this profile (UUID or username) as selected instead of the saved one")
+	rootCmd.AddCommand(profilesCmd)
+}
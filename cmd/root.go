@@ -1,23 +1,60 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"hsm/internal/client"
+	"hsm/internal/config"
+	"hsm/internal/services"
+	"hsm/internal/sessionstore"
 
 	"github.com/spf13/cobra"
 )
 
-var sessionLocation string
+var (
+	sessionLocation string
+	sessionStoreURI string
+	sessionKeyFile  string
+	configPath      string
+	accountName     string
+	// account is the account selected by --account, HSM_ACCOUNT or the config file
+	account *config.Account
+	// accountExplicit is set when the account was selected by flag or environment
+	accountExplicit bool
+	oauthURL        string
+	accountDataURL  string
+	gameAssetsURL   string
+	sessionsURL     string
+	profileSelector string
+	retryPolicy     = client.DefaultRetryPolicy()
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "hsm",
 	Short: "HSM service",
 	Long:  "HSM is a service that provides various functionalities.",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := selectAccount(cmd); err != nil {
+			return err
+		}
+		return bootstrapSession(cmd)
+	},
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&sessionLocation, "session-location", "", "Path to session.json file (default: ~/.config/hsm/session.json)")
+	rootCmd.PersistentFlags().StringVar(&sessionStoreURI, "session-store", "", "Session store URI, e.g. file:///data/session.json, env://HSM_SESSION or k8s-secret://namespace/name (env: HSM_SESSION_STORE; overrides --session-location)")
+	rootCmd.PersistentFlags().StringVar(&sessionKeyFile, "session-key-file", "", "File containing the passphrase that encrypts the session at rest (env: HSM_SESSION_KEY_FILE, or the passphrase itself in HSM_SESSION_KEY)")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Path to the config file (env: HSM_CONFIG, default: ~/.config/hsm/config.json)")
+	rootCmd.PersistentFlags().StringVar(&accountName, "account", "", "Named account from the config file to use (env: HSM_ACCOUNT, default: the current account)")
+	rootCmd.PersistentFlags().StringVar(&oauthURL, "oauth-url", "", "Base URL of the Hytale OAuth server (env: HSM_OAUTH_URL)")
+	rootCmd.PersistentFlags().StringVar(&accountDataURL, "account-data-url", "", "Base URL of the Hytale account-data service (env: HSM_ACCOUNT_DATA_URL)")
+	rootCmd.PersistentFlags().StringVar(&gameAssetsURL, "game-assets-url", "", "Base URL of the Hytale game-assets service (env: HSM_GAME_ASSETS_URL)")
+	rootCmd.PersistentFlags().StringVar(&sessionsURL, "sessions-url", "", "Base URL of the Hytale sessions service (env: HSM_SESSIONS_URL)")
 }
 
 // GetSessionLocation returns the session file path, using ~/.config/hsm/session.json as default
@@ -33,6 +70,191 @@ func GetSessionLocation() string {
 	return filepath.Join(homeDir, ".config", "hsm", "session.json")
 }
 
+// GetSessionStore returns the store selected by --session-store or HSM_SESSION_STORE,
+// falling back to the file at GetSessionLocation. Sessions are encrypted if a
+// session key is configured.
+func GetSessionStore() (sessionstore.Store, error) {
+	opts, err := sessionStoreOptions()
+	if err != nil {
+		return nil, err
+	}
+	return openSessionStore(opts...)
+}
+
+// sessionStoreOptions returns the store options selected by the session key flags
+func sessionStoreOptions() ([]sessionstore.Option, error) {
+	cipher, err := loadCipher(flagOrEnv(sessionKeyFile, "HSM_SESSION_KEY_FILE"), "HSM_SESSION_KEY")
+	if err != nil {
+		return nil, err
+	}
+	var opts []sessionstore.Option
+	if cipher != nil {
+		opts = append(opts, sessionstore.WithCipher(cipher))
+	}
+	return opts, nil
+}
+
+// openSessionStore opens the selected session store with opts. Explicit flags
+// win over an explicitly selected account, which wins over HSM_SESSION_STORE,
+// which wins over the current account of the config file.
+func openSessionStore(opts ...sessionstore.Option) (sessionstore.Store, error) {
+	switch {
+	case sessionStoreURI != "":
+		return sessionstore.Open(sessionStoreURI, opts...)
+	case sessionLocation != "":
+		return sessionstore.NewFileStore(sessionLocation, opts...), nil
+	case account != nil && accountExplicit:
+		return sessionstore.Open(account.SessionStore, opts...)
+	case os.Getenv("HSM_SESSION_STORE") != "":
+		return sessionstore.Open(os.Getenv("HSM_SESSION_STORE"), opts...)
+	case account != nil:
+		return sessionstore.Open(account.SessionStore, opts...)
+	}
+	return sessionstore.NewFileStore(GetSessionLocation(), opts...), nil
+}
+
+// getConfigPath returns the config file path from --config or HSM_CONFIG
+func getConfigPath() string {
+	if path := flagOrEnv(configPath, "HSM_CONFIG"); path != "" {
+		return path
+	}
+	return config.DefaultPath()
+}
+
+// selectAccount resolves the account for cmd and applies its defaults to the
+// --profile and --patchline flags that were not set explicitly
+func selectAccount(cmd *cobra.Command) error {
+	account, accountExplicit = nil, false
+
+	cfg, err := config.Load(getConfigPath())
+	if err != nil {
+		return err
+	}
+
+	name := flagOrEnv(accountName, "HSM_ACCOUNT")
+	if name != "" {
+		accountExplicit = true
+		account, err = cfg.Account(name)
+	} else {
+		account, err = cfg.Current()
+	}
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return nil
+	}
+
+	if account.Profile != "" && !cmd.Flags().Changed("profile") {
+		profileSelector = account.Profile
+	}
+	if account.Patchline != "" && cmd.Flags().Lookup("patchline") != nil && !cmd.Flags().Changed("patchline") {
+		patchline = account.Patchline
+	}
+	return nil
+}
+
+// bootstrapSession creates the session from HSM_REFRESH_TOKEN or the file named
+// by HSM_REFRESH_TOKEN_FILE when the selected store holds none, so headless
+// environments work without a device flow login
+func bootstrapSession(cmd *cobra.Command) error {
+	if cmd == loginCmd || cmd == sessionImportCmd {
+		return nil
+	}
+
+	refreshToken := os.Getenv("HSM_REFRESH_TOKEN")
+	if path := os.Getenv("HSM_REFRESH_TOKEN_FILE"); refreshToken == "" && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read refresh token file: %w", err)
+		}
+		refreshToken = strings.TrimSpace(string(data))
+	}
+	if refreshToken == "" {
+		return nil
+	}
+
+	store, err := GetSessionStore()
+	if err != nil {
+		return err
+	}
+	created, err := services.Bootstrap(cmd.Context(), newClient(), store, refreshToken)
+	if err != nil {
+		return fmt.Errorf("failed to bootstrap session from refresh token: %w", err)
+	}
+	if created {
+		fmt.Fprintf(os.Stderr, "Session created from refresh token and saved to %s\n", store)
+	}
+	return nil
+}
+
+// loadCipher returns the session cipher for the passphrase in keyFile, falling
+// back to the passphrase in the environment variable envKey. It returns nil if
+// neither is set.
+func loadCipher(keyFile, envKey string) (*sessionstore.Cipher, error) {
+	if keyFile != "" {
+		passphrase, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read session key file: %w", err)
+		}
+		return sessionstore.NewCipher(passphrase)
+	}
+	if passphrase := os.Getenv(envKey); passphrase != "" {
+		return sessionstore.NewCipher([]byte(passphrase))
+	}
+	return nil, nil
+}
+
+// newSessionService loads the session from the selected store
+func newSessionService(ctx context.Context) (*services.SessionService, error) {
+	store, err := GetSessionStore()
+	if err != nil {
+		return nil, err
+	}
+	return services.NewSessionService(ctx, newClient(), store, sessionOptions()...)
+}
+
+// GetEndpoints returns the upstream endpoints from flags, falling back to
+// environment variables and then to the production defaults
+func GetEndpoints() client.Endpoints {
+	return client.Endpoints{
+		OAuth:       flagOrEnv(oauthURL, "HSM_OAUTH_URL"),
+		AccountData: flagOrEnv(accountDataURL, "HSM_ACCOUNT_DATA_URL"),
+		GameAssets:  flagOrEnv(gameAssetsURL, "HSM_GAME_ASSETS_URL"),
+		Sessions:    flagOrEnv(sessionsURL, "HSM_SESSIONS_URL"),
+	}
+}
+
+// newClient creates an API client configured with the selected endpoints and retry policy
+func newClient() *client.Client {
+	return client.New(client.WithEndpoints(GetEndpoints()), client.WithRetryPolicy(retryPolicy))
+}
+
+// sessionOptions returns the SessionService options selected by flags
+func sessionOptions() []services.SessionOption {
+	var opts []services.SessionOption
+	if profileSelector != "" {
+		opts = append(opts, services.WithProfile(profileSelector))
+	}
+	return opts
+}
+
+// addRetryFlags registers flags tuning the retry policy for upstream calls
+func addRetryFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&retryPolicy.MaxAttempts, "retry-attempts", retryPolicy.MaxAttempts, "Maximum attempts per upstream call (1 disables retries)")
+	cmd.Flags().DurationVar(&retryPolicy.InitialBackoff, "retry-backoff", retryPolicy.InitialBackoff, "Initial backoff between retries, doubled on every attempt")
+	cmd.Flags().DurationVar(&retryPolicy.MaxBackoff, "retry-max-backoff", retryPolicy.MaxBackoff, "Maximum backoff between retries")
+	cmd.Flags().Float64Var(&retryPolicy.Jitter, "retry-jitter", retryPolicy.Jitter, "Fraction of the backoff that is randomized (0-1)")
+	cmd.Flags().DurationVar(&retryPolicy.CallTimeout, "upstream-timeout", retryPolicy.CallTimeout, "Deadline for an upstream call including retries (0 disables it)")
+}
+
+func flagOrEnv(value, envKey string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(envKey)
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
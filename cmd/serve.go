@@ -1,16 +1,44 @@
 package cmd
 
 import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hsm/internal/config"
 	"hsm/internal/server"
+	"hsm/internal/services"
+	"hsm/internal/sessionstore"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	port         string
-	jwksEndpoint string
-	jwksCACert   string
-	jwksJWTToken string
+	port           string
+	jwksEndpoint   string
+	jwksCACert     string
+	jwksJWTToken   string
+	adminTokenFile string
+	poolAccounts   []string
+	poolStores     []string
+	poolStrategy   string
+	poolClaim      string
+	linkDir        string
+	stateFile      string
+	refreshMargin  time.Duration
+	refreshWorkers int
+	leaseTTL       time.Duration
+	maxSessions    int
+	instanceClaim  string
+	adminPort      string
+	webhooks       []string
+	webhookSecret  string
+	webhookOutbox  string
+	versionCheck   time.Duration
 )
 
 var serveCmd = &cobra.Command{
@@ -18,22 +46,195 @@ var serveCmd = &cobra.Command{
 	Short: "Start the HTTP server",
 	Long:  "Start the HSM HTTP server on the specified port.",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		adminToken, err := getAdminToken()
+		if err != nil {
+			return err
+		}
+		store, err := GetSessionStore()
+		if err != nil {
+			return err
+		}
+		strategy, err := services.ParsePoolStrategy(poolStrategy)
+		if err != nil {
+			return err
+		}
+		pool, err := getPoolAccounts()
+		if err != nil {
+			return err
+		}
+		linkStore, err := getLinkStore()
+		if err != nil {
+			return err
+		}
+		webhookURLs, secret, err := getWebhooks()
+		if err != nil {
+			return err
+		}
+		var stateStore services.StateStore
+		if path := getStateFile(); path != "" {
+			stateStore = services.NewFileStateStore(path)
+		}
 		config := server.Config{
 			Port:         port,
+			AdminPort:    adminPort,
 			JWKSEndpoint: jwksEndpoint,
 			JWKSCACert:   jwksCACert,
 			JWKSJWTToken: jwksJWTToken,
-			SessionPath:  GetSessionLocation(),
+			SessionStore: store,
+			Endpoints:    GetEndpoints(),
+			RetryPolicy:  retryPolicy,
+			Profile:      profileSelector,
+			AdminToken:   adminToken,
+			Pool:         pool,
+			PoolStrategy: strategy,
+			PoolClaim:    poolClaim,
+			LinkStore:    linkStore,
+			StateStore:   stateStore,
+
+			GameSessionRefreshMargin:      refreshMargin,
+			GameSessionRefreshConcurrency: refreshWorkers,
+			LeaseTTL:                      leaseTTL,
+			MaxSessionsPerSubject:         maxSessions,
+			InstanceClaim:                 instanceClaim,
+			Webhooks:                      webhookURLs,
+			WebhookSecret:                 secret,
+			WebhookOutbox:                 cmp.Or(webhookOutbox, os.Getenv("HSM_WEBHOOK_OUTBOX")),
+			VersionCheckInterval:          versionCheck,
 		}
 		return server.Start(config)
 	},
 }
 
+// getAdminToken returns the admin token from --admin-token-file or HSM_ADMIN_TOKEN
+func getAdminToken() (string, error) {
+	if adminTokenFile == "" {
+		return os.Getenv("HSM_ADMIN_TOKEN"), nil
+	}
+	data, err := os.ReadFile(adminTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read admin token file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// getPoolAccounts returns the accounts selected by --pool-account and
+// --pool-session-store. Named accounts keep their own default profile.
+func getPoolAccounts() ([]server.PoolAccount, error) {
+	if len(poolAccounts) == 0 && len(poolStores) == 0 {
+		return nil, nil
+	}
+	opts, err := sessionStoreOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []server.PoolAccount
+	if len(poolAccounts) > 0 {
+		cfg, err := config.Load(getConfigPath())
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range poolAccounts {
+			acc, err := cfg.Account(name)
+			if err != nil {
+				return nil, err
+			}
+			store, err := sessionstore.Open(acc.SessionStore, opts...)
+			if err != nil {
+				return nil, err
+			}
+			profile := acc.Profile
+			if profile == "" {
+				profile = profileSelector
+			}
+			accounts = append(accounts, server.PoolAccount{Name: acc.Name, SessionStore: store, Profile: profile})
+		}
+	}
+	for _, uri := range poolStores {
+		store, err := sessionstore.Open(uri, opts...)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, server.PoolAccount{Name: store.String(), SessionStore: store, Profile: profileSelector})
+	}
+	return accounts, nil
+}
+
+// getLinkStore returns the stores of linked accounts below --link-dir, one
+// directory per subject named after a hash of the subject
+func getLinkStore() (services.LinkStoreFunc, error) {
+	if linkDir == "" {
+		return nil, nil
+	}
+	opts, err := sessionStoreOptions()
+	if err != nil {
+		return nil, err
+	}
+	return func(subject string) (sessionstore.Store, error) {
+		sum := sha256.Sum256([]byte(subject))
+		return sessionstore.NewFileStore(filepath.Join(linkDir, hex.EncodeToString(sum[:]), "session.json"), opts...), nil
+	}, nil
+}
+
+// getStateFile returns the file of the tracked game sessions from --state-file or HSM_STATE_FILE
+func getStateFile() string {
+	if stateFile != "" {
+		return stateFile
+	}
+	return os.Getenv("HSM_STATE_FILE")
+}
+
+// getWebhooks returns the webhook URLs from --webhook-url or HSM_WEBHOOK_URLS
+// and their signing secret from --webhook-secret-file or HSM_WEBHOOK_SECRET
+func getWebhooks() ([]string, string, error) {
+	urls := webhooks
+	if len(urls) == 0 {
+		if env := os.Getenv("HSM_WEBHOOK_URLS"); env != "" {
+			urls = strings.Split(env, ",")
+		}
+	}
+	if len(urls) == 0 {
+		return nil, "", nil
+	}
+
+	secret := os.Getenv("HSM_WEBHOOK_SECRET")
+	if webhookSecret != "" {
+		data, err := os.ReadFile(webhookSecret)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read webhook secret file: %w", err)
+		}
+		secret = strings.TrimSpace(string(data))
+	}
+	if secret == "" {
+		return nil, "", fmt.Errorf("webhooks need a signing secret, set --webhook-secret-file or HSM_WEBHOOK_SECRET")
+	}
+	return urls, secret, nil
+}
+
 func init() {
 	serveCmd.Flags().StringVarP(&port, "port", "p", "8080", "Port to listen on")
 	serveCmd.Flags().StringVar(&jwksEndpoint, "jwks-endpoint", "", "JWKS endpoint URL for JWT validation (optional, enables multi-user mode)")
 	serveCmd.Flags().StringVar(&jwksCACert, "jwks-ca-cert", "", "CA certificate file for JWKS endpoint TLS verification (optional, for Kubernetes)")
 	serveCmd.Flags().StringVar(&jwksJWTToken, "jwks-jwt-token-file", "", "Path to JWT token file for JWKS endpoint authentication (optional, for Kubernetes service account)")
+	serveCmd.Flags().StringVar(&profileSelector, "profile", "", "Default game profile (UUID or username) for game sessions (default: profile saved at login, else the first profile)")
+	serveCmd.Flags().StringVar(&adminPort, "admin-port", "", "Serve the /admin API on this port only instead of --port (optional)")
+	serveCmd.Flags().StringVar(&adminTokenFile, "admin-token-file", "", "File containing the bearer token for the /admin API (env: HSM_ADMIN_TOKEN; without a token the admin API is only available in single-user mode)")
+	serveCmd.Flags().StringSliceVar(&poolAccounts, "pool-account", nil, "Named account to add to the account pool, repeatable (multi-user mode)")
+	serveCmd.Flags().StringSliceVar(&poolStores, "pool-session-store", nil, "Session store URI to add to the account pool, repeatable (multi-user mode)")
+	serveCmd.Flags().StringVar(&poolStrategy, "pool-strategy", string(services.StrategyLeastLoaded), "How subjects are assigned to pool accounts: least-loaded, sticky or claim")
+	serveCmd.Flags().StringVar(&poolClaim, "pool-claim", "hsm_account", "JWT claim naming the pool account of a subject (claim strategy)")
+	serveCmd.Flags().StringVar(&linkDir, "link-dir", "", "Directory for the logins of subjects that link their own Hytale account (multi-user mode, linking is disabled without it)")
+	serveCmd.Flags().StringVar(&stateFile, "state-file", "", "File that keeps the tracked game sessions across restarts (multi-user mode, env: HSM_STATE_FILE)")
+	serveCmd.Flags().DurationVar(&refreshMargin, "game-session-refresh-margin", services.RefreshThreshold, "Refresh tracked game sessions this long before they expire (multi-user mode)")
+	serveCmd.Flags().IntVar(&refreshWorkers, "game-session-refresh-concurrency", services.DefaultRefreshConcurrency, "Maximum number of tracked game sessions refreshed at the same time (multi-user mode)")
+	serveCmd.Flags().DurationVar(&leaseTTL, "lease-ttl", 0, "Terminate game sessions without a heartbeat within this duration (multi-user mode, 0 disables leases)")
+	serveCmd.Flags().IntVar(&maxSessions, "max-sessions-per-subject", 0, "Maximum number of game sessions a subject may run at the same time (multi-user mode, 0 means unlimited)")
+	serveCmd.Flags().StringVar(&instanceClaim, "instance-claim", "", "JWT claim naming the instance of a game session, overrides the instance query parameter (multi-user mode)")
+	serveCmd.Flags().StringSliceVar(&webhooks, "webhook-url", nil, "URL session lifecycle events are posted to, repeatable (env: HSM_WEBHOOK_URLS, comma separated)")
+	serveCmd.Flags().StringVar(&webhookSecret, "webhook-secret-file", "", "File containing the secret webhook payloads are signed with (env: HSM_WEBHOOK_SECRET)")
+	serveCmd.Flags().StringVar(&webhookOutbox, "webhook-outbox", "", "File that keeps undelivered webhook events across restarts (env: HSM_WEBHOOK_OUTBOX)")
+	serveCmd.Flags().DurationVar(&versionCheck, "version-check-interval", services.DefaultVersionCheckInterval, "How often to look for new versions to notify /api/v1/events (0 disables it)")
+	addRetryFlags(serveCmd)
 	rootCmd.AddCommand(serveCmd)
 	rootCmd.AddCommand(loginCmd)
 }
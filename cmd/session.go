@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"hsm/internal/sessionstore"
+	"hsm/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	newSessionKeyFile string
+	rekeyPlaintext    bool
+	bundleKeyFile     string
+	exportOutput      string
+)
+
+var sessionCmd = &cobra.Command{
+	Use:   "session",
+	Short: "Manage the stored session",
+}
+
+var sessionRekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "Re-encrypt the stored session with a new key",
+	Long: `Decrypt the stored session with the current key (--session-key-file or
+HSM_SESSION_KEY) and save it encrypted with the new key from --new-key-file or
+HSM_SESSION_NEW_KEY. Plaintext sessions are migrated the same way, --plaintext
+removes the encryption instead.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		current, err := GetSessionStore()
+		if err != nil {
+			return err
+		}
+
+		newCipher, err := loadCipher(newSessionKeyFile, "HSM_SESSION_NEW_KEY")
+		if err != nil {
+			return err
+		}
+		switch {
+		case newCipher == nil && !rekeyPlaintext:
+			return fmt.Errorf("no new key given, use --new-key-file or HSM_SESSION_NEW_KEY (or --plaintext to decrypt)")
+		case newCipher != nil && rekeyPlaintext:
+			return fmt.Errorf("--plaintext cannot be combined with a new key")
+		}
+
+		// A running serve may rotate the refresh token in the meantime
+		unlock, err := sessionstore.Lock(cmd.Context(), current)
+		if err != nil {
+			return fmt.Errorf("failed to lock session store: %w", err)
+		}
+		defer unlock()
+
+		session, err := current.Load(cmd.Context())
+		if errors.Is(err, sessionstore.ErrEncrypted) {
+			return fmt.Errorf("%w: pass the current key with --session-key-file or HSM_SESSION_KEY", err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load session: %w", err)
+		}
+
+		var opts []sessionstore.Option
+		if newCipher != nil {
+			opts = append(opts, sessionstore.WithCipher(newCipher))
+		}
+		target, err := openSessionStore(opts...)
+		if err != nil {
+			return err
+		}
+		if err := target.Save(cmd.Context(), session); err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+
+		if rekeyPlaintext {
+			fmt.Printf("Session at %s is now stored in plaintext\n", target)
+		} else {
+			fmt.Printf("Session at %s re-encrypted with the new key\n", target)
+		}
+		return nil
+	},
+}
+
+var sessionExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the stored session as a portable bundle",
+	Long: `Write the stored session to --output (or stdout) so it can be imported on a
+machine without a browser. The bundle is encrypted with the passphrase from
+--bundle-key-file or HSM_BUNDLE_KEY, if given.
+
+Refresh tokens rotate: once one copy of the session is refreshed the other
+stops working, so stop using the session on this machine after exporting it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := GetSessionStore()
+		if err != nil {
+			return err
+		}
+		session, err := store.Load(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to load session: %w", err)
+		}
+
+		bundleCipher, err := loadCipher(bundleKeyFile, "HSM_BUNDLE_KEY")
+		if err != nil {
+			return err
+		}
+		bundle, err := sessionstore.Encode(session, bundleCipher)
+		if err != nil {
+			return err
+		}
+
+		if exportOutput == "" || exportOutput == "-" {
+			_, err := fmt.Fprintln(cmd.OutOrStdout(), string(bundle))
+			return err
+		}
+		if err := utils.WriteSecretFile(exportOutput, bundle); err != nil {
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Session exported to %s\n", exportOutput)
+		return nil
+	},
+}
+
+var sessionImportCmd = &cobra.Command{
+	Use:   "import [FILE]",
+	Short: "Import a session bundle created by 'hsm session export'",
+	Long: `Read a bundle from FILE (or stdin) and save the session to the configured
+store, replacing the current one. Encrypted bundles need the passphrase from
+--bundle-key-file or HSM_BUNDLE_KEY.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var data []byte
+		var err error
+		if len(args) == 0 || args[0] == "-" {
+			data, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+
+		bundleCipher, err := loadCipher(bundleKeyFile, "HSM_BUNDLE_KEY")
+		if err != nil {
+			return err
+		}
+		session, err := sessionstore.Decode(bytes.TrimSpace(data), bundleCipher)
+		if errors.Is(err, sessionstore.ErrEncrypted) {
+			return fmt.Errorf("%w: pass the bundle key with --bundle-key-file or HSM_BUNDLE_KEY", err)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid bundle: %w", err)
+		}
+		// Stored sessions need an access token, a lone refresh token is
+		// bootstrapped with HSM_REFRESH_TOKEN instead
+		if session.Token == "" {
+			return fmt.Errorf("invalid bundle: no access token, pass a lone refresh token in HSM_REFRESH_TOKEN instead")
+		}
+
+		store, err := GetSessionStore()
+		if err != nil {
+			return err
+		}
+		// A running serve may rotate the refresh token in the meantime
+		unlock, err := sessionstore.Lock(cmd.Context(), store)
+		if err != nil {
+			return fmt.Errorf("failed to lock session store: %w", err)
+		}
+		defer unlock()
+		if err := store.Save(cmd.Context(), session); err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+		fmt.Printf("Session imported to %s\n", store)
+		return nil
+	},
+}
+
+func init() {
+	sessionRekeyCmd.Flags().StringVar(&newSessionKeyFile, "new-key-file", "", "File containing the new passphrase (env: HSM_SESSION_NEW_KEY holds the passphrase itself)")
+	sessionRekeyCmd.Flags().BoolVar(&rekeyPlaintext, "plaintext", false, "Store the session unencrypted")
+	sessionCmd.AddCommand(sessionRekeyCmd)
+	sessionExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "File to write the bundle to (default: stdout)")
+	for _, c := range []*cobra.Command{sessionExportCmd, sessionImportCmd} {
+		c.Flags().StringVar(&bundleKeyFile, "bundle-key-file", "", "File containing the passphrase that encrypts the bundle (env: HSM_BUNDLE_KEY holds the passphrase itself)")
+		sessionCmd.AddCommand(c)
+	}
+	rootCmd.AddCommand(sessionCmd)
+}
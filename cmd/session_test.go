@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hsm/internal/client"
+	"hsm/internal/hsmtest"
+	"hsm/internal/sessionstore"
+)
+
+func TestSessionRekey(t *testing.T) {
+	dir := t.TempDir()
+	sessionPath := filepath.Join(dir, "session.json")
+	oldKey := filepath.Join(dir, "old.key")
+	newKey := filepath.Join(dir, "new.key")
+	for path, key := range map[string]string{oldKey: "old passphrase", newKey: "new passphrase"} {
+		if err := os.WriteFile(path, []byte(key), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sessionstore.NewFileStore(sessionPath).Save(t.Context(), &client.Session{Token: "at", RefreshToken: "rt"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sessionLocation, sessionKeyFile, newSessionKeyFile, rekeyPlaintext = "", "", "", false
+	})
+
+	load := func(passphrase string) (*client.Session, error) {
+		c, err := sessionstore.NewCipher([]byte(passphrase))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sessionstore.NewFileStore(sessionPath, sessionstore.WithCipher(c)).Load(t.Context())
+	}
+
+	// Migrate the plaintext file, then rotate the key
+	steps := []struct {
+		args []string
+		key  string
+	}{
+		{[]string{"session", "rekey", "--session-location", sessionPath, "--new-key-file", oldKey}, "old passphrase"},
+		{[]string{"session", "rekey", "--session-location", sessionPath, "--session-key-file", oldKey, "--new-key-file", newKey}, "new passphrase"},
+	}
+	for _, step := range steps {
+		rootCmd.SetArgs(step.args)
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("%v: %v", step.args, err)
+		}
+		session, err := load(step.key)
+		if err != nil {
+			t.Fatalf("expected session to be readable with %q: %v", step.key, err)
+		}
+		if session.RefreshToken != "rt" {
+			t.Fatalf("unexpected session %+v", session)
+		}
+	}
+
+	if _, err := load("old passphrase"); err == nil {
+		t.Fatal("expected the old key to be rejected after rekey")
+	}
+}
+
+func TestSessionExportImport(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.json")
+	target := filepath.Join(dir, "target.json")
+	bundle := filepath.Join(dir, "bundle.json")
+	bundleKey := filepath.Join(dir, "bundle.key")
+	if err := os.WriteFile(bundleKey, []byte("bundle passphrase"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := sessionstore.NewFileStore(source).Save(t.Context(), &client.Session{Token: "at", RefreshToken: "super-secret-refresh-token"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sessionLocation, bundleKeyFile, exportOutput = "", "", ""
+	})
+
+	rootCmd.SetArgs([]string{"session", "export", "--session-location", source, "--bundle-key-file", bundleKey, "-o", bundle})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	data, err := os.ReadFile(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sessionstore.IsEncrypted(data) || strings.Contains(string(data), "super-secret-refresh-token") {
+		t.Fatal("expected an encrypted bundle")
+	}
+
+	bundleKeyFile = ""
+	rootCmd.SetArgs([]string{"session", "import", bundle, "--session-location", target})
+	if err := rootCmd.Execute(); !errors.Is(err, sessionstore.ErrEncrypted) {
+		t.Fatalf("import without key: expected ErrEncrypted, got %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"session", "import", bundle, "--session-location", target, "--bundle-key-file", bundleKey})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	session, err := sessionstore.NewFileStore(target).Load(t.Context())
+	if err != nil {
+		t.Fatalf("expected imported session: %v", err)
+	}
+	if session.RefreshToken != "super-secret-refresh-token" {
+		t.Fatalf("unexpected session %+v", session)
+	}
+}
+
+func TestSessionImportRejectsRefreshTokenOnly(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.json")
+	bundle := filepath.Join(dir, "bundle.json")
+	if err := os.WriteFile(bundle, []byte(`{"refresh_token":"rt"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sessionLocation, bundleKeyFile = "", ""
+	})
+
+	rootCmd.SetArgs([]string{"session", "import", bundle, "--session-location", target})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "no access token") {
+		t.Fatalf("expected a bundle without access token to be rejected, got %v", err)
+	}
+	if _, err := os.Stat(target); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected no session to be saved, got %v", err)
+	}
+}
+
+func TestBootstrapFromRefreshToken(t *testing.T) {
+	backend := hsmtest.New(t)
+	dir := t.TempDir()
+	sessionPath := filepath.Join(dir, "session.json")
+	tokenFile := filepath.Join(dir, "refresh-token")
+	issued := backend.IssueSession()
+	if err := os.WriteFile(tokenFile, []byte(issued.RefreshToken+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HSM_REFRESH_TOKEN_FILE", tokenFile)
+	t.Cleanup(func() {
+		sessionLocation, exportOutput, oauthURL = "", "", ""
+	})
+
+	// Any command bootstraps the session before it runs
+	rootCmd.SetArgs([]string{"session", "export", "--session-location", sessionPath, "--oauth-url", backend.Endpoints().OAuth, "-o", filepath.Join(dir, "bundle.json")})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	session, err := sessionstore.NewFileStore(sessionPath).Load(t.Context())
+	if err != nil {
+		t.Fatalf("expected the bootstrapped session to be saved: %v", err)
+	}
+	if !backend.TokenActive(session.Token) || session.RefreshToken == issued.RefreshToken {
+		t.Fatalf("expected a fresh session, got %+v", session)
+	}
+	if got := backend.Calls(hsmtest.RouteToken); got != 1 {
+		t.Fatalf("expected 1 token exchange, got %d", got)
+	}
+}
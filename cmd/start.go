@@ -9,9 +9,6 @@ import (
 	"sync"
 	"syscall"
 
-	"hsm/internal/client"
-	"hsm/internal/services"
-
 	"github.com/spf13/cobra"
 )
 
@@ -23,8 +20,7 @@ var startCmd = &cobra.Command{
 	Long:  "Create a game session and start the Hytale server with the session tokens set as environment variables.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Create session service (handles session loading, refresh, and profile fetching)
-		sessionPath := GetSessionLocation()
-		sessionService, err := services.NewSessionService(client.New(), sessionPath)
+		sessionService, err := newSessionService(cmd.Context())
 		if err != nil {
 			return fmt.Errorf("failed to initialize session: %w (run 'hsm login' first)", err)
 		}
@@ -32,12 +28,12 @@ var startCmd = &cobra.Command{
 
 		// Create game session
 		fmt.Println("Creating game session...")
-		gameSession, err := sessionService.CreateGameSession()
+		gameSession, err := sessionService.CreateGameSession(cmd.Context())
 		if err != nil {
 			return fmt.Errorf("failed to create game session: %w", err)
 		}
 
-		fmt.Println("Game session created successfully!")
+		fmt.Printf("Game session created successfully for profile %s!\n", sessionService.Profile().Username)
 
 		// Determine which launcher script to use based on OS
 		var serverCmd *exec.Cmd
@@ -100,7 +96,7 @@ var startCmd = &cobra.Command{
 
 		// Clean up the game session
 		fmt.Println("Terminating game session...")
-		if err := sessionService.DeleteGameSession(gameSession.SessionToken); err != nil {
+		if err := sessionService.DeleteGameSession(cmd.Context(), gameSession.SessionToken); err != nil {
 			fmt.Printf("Warning: failed to terminate game session: %v\n", err)
 		} else {
 			fmt.Println("Game session terminated successfully")
@@ -112,5 +108,7 @@ var startCmd = &cobra.Command{
 
 func init() {
 	startCmd.Flags().StringArrayVar(&additionalArgs, "additional-args", nil, "Additional arguments to pass to the server (passed through to start.sh/start.bat)")
+	startCmd.Flags().StringVar(&profileSelector, "profile", "", "Game profile (UUID or username) to host the server with (default: profile saved at login, else the first profile)")
+	addRetryFlags(startCmd)
 	rootCmd.AddCommand(startCmd)
 }
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"hsm/internal/services"
+
+	"github.com/spf13/cobra"
+)
+
+var statusJSON bool
+
+var statusCmd = &cobra.Command{
+	Use:     "status",
+	Aliases: []string{"whoami"},
+	Short:   "Show account, token and install state",
+	Long: `Show whether this machine is logged in, as which account and profile, when the
+access token expires, which game version is installed and whether an update is
+available. The session is only read, an expired token is not refreshed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := GetSessionStore()
+		if err != nil {
+			return err
+		}
+		status := services.GetStatus(cmd.Context(), newClient(), services.StatusOptions{
+			SessionStore:    store,
+			VersionFile:     filepath.Join(outputDir, versionFile),
+			Patchline:       patchline,
+			ProfileSelector: profileSelector,
+		})
+
+		if statusJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(status)
+		}
+		return printStatus(os.Stdout, status)
+	},
+}
+
+// printStatus renders status as a human readable table
+func printStatus(out io.Writer, status *services.Status) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	row := func(key, format string, args ...any) {
+		_, _ = fmt.Fprintf(tw, "%s:\t%s\n", key, fmt.Sprintf(format, args...))
+	}
+
+	row("Session", "%s", status.SessionStore)
+	row("Logged in", "%s", yesNo(status.LoggedIn))
+
+	if token := status.Token; token != nil {
+		expiry := "unknown"
+		if !token.ExpiresAt.IsZero() {
+			expiry = token.ExpiresAt.Local().Format("2006-01-02 15:04:05 MST")
+			if token.Expired {
+				expiry += " (expired)"
+			} else {
+				expiry += fmt.Sprintf(" (in %s)", time.Until(token.ExpiresAt).Round(time.Second))
+			}
+		}
+		row("Token", "%s", token.Format)
+		if token.Subject != "" {
+			row("Subject", "%s", token.Subject)
+		}
+		row("Token expires", "%s", expiry)
+		row("Refresh token", "%s", yesNo(token.HasRefreshToken))
+		row("Token accepted", "%s", yesNo(token.Accepted))
+	}
+
+	if account := status.Account; account != nil {
+		row("Owner", "%s", account.Owner)
+		for _, p := range account.Profiles {
+			marker := ""
+			if p.UUID == account.SelectedProfile {
+				marker = " *"
+			}
+			row("Profile", "%s (%s)%s", p.Username, p.UUID, marker)
+		}
+	}
+
+	installed := status.Install.InstalledVersion
+	if installed == "" {
+		installed = "not installed"
+	}
+	row("Installed version", "%s", installed)
+	if status.Install.LatestVersion != "" {
+		row("Latest version", "%s (%s)", status.Install.LatestVersion, status.Install.Patchline)
+		row("Update available", "%s", yesNo(status.Install.UpdateAvailable))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, msg := range status.Errors {
+		_, _ = fmt.Fprintf(out, "\n! %s", msg)
+	}
+	if len(status.Errors) > 0 {
+		_, _ = fmt.Fprintln(out)
+	}
+	return nil
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Print the status as JSON")
+	statusCmd.Flags().StringVarP(&outputDir, "output", "o", ".", "Directory of the installed game files")
+	statusCmd.Flags().StringVar(&patchline, "patchline", services.PatchlineRelease, "Patchline to compare against (release or prerelease)")
+	statusCmd.Flags().StringVar(&profileSelector, "profile", "", "Mark this profile (UUID or username) as selected instead of the saved one")
+	rootCmd.AddCommand(statusCmd)
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"path/filepath"
 
-	"hsm/internal/client"
 	"hsm/internal/services"
 	"hsm/internal/utils"
 
@@ -22,13 +21,13 @@ var updateCmd = &cobra.Command{
 	Short: "Update the Hytale game files",
 	Long:  "Update the Hytale game files to the latest version.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		sessionService, err := services.NewSessionService(client.New(), GetSessionLocation())
+		sessionService, err := newSessionService(cmd.Context())
 		if err != nil {
 			return fmt.Errorf("failed to initialize session: %w (run 'hsm login' first)", err)
 		}
 		defer sessionService.Close()
 		downloadService := services.NewDownloadService(sessionService.Client())
-		downloadURL, version, err := downloadService.GetDownloadURL(patchline)
+		downloadURL, version, err := downloadService.GetDownloadURL(cmd.Context(), patchline)
 		if err != nil {
 			return fmt.Errorf("failed to get latest version: %w", err)
 		}
@@ -51,7 +50,7 @@ var updateCmd = &cobra.Command{
 			fmt.Println("Updating to latest version")
 		}
 
-		if err := utils.DownloadAndExtract(downloadURL, outputDir); err != nil {
+		if err := utils.DownloadAndExtract(cmd.Context(), downloadURL, outputDir); err != nil {
 			return fmt.Errorf("download failed: %w", err)
 		}
 
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"hsm/internal/hsmtest"
+	"hsm/internal/utils"
+)
+
+func TestUpdateCommand(t *testing.T) {
+	backend := hsmtest.New(t)
+	dir := t.TempDir()
+	sessionPath := filepath.Join(dir, "session.json")
+	serverDir := filepath.Join(dir, "server")
+	backend.WriteSessionFile(t, sessionPath)
+	backend.SetVersion("release", "2026.04.05-test")
+
+	endpoints := backend.Endpoints()
+	args := []string{
+		"update",
+		"--session-location", sessionPath,
+		"--oauth-url", endpoints.OAuth,
+		"--account-data-url", endpoints.AccountData,
+		"--game-assets-url", endpoints.GameAssets,
+		"--sessions-url", endpoints.Sessions,
+		"--output", serverDir,
+	}
+
+	if err := os.MkdirAll(serverDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	rootCmd.SetArgs(args)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	if got := utils.GetVersion(filepath.Join(serverDir, versionFile)); got != "2026.04.05-test" {
+		t.Fatalf("expected version file to be written, got %q", got)
+	}
+	jar, err := os.ReadFile(filepath.Join(serverDir, "Server", "HytaleServer.jar"))
+	if err != nil {
+		t.Fatalf("expected server files to be extracted: %v", err)
+	}
+	if string(jar) != "fake server 2026.04.05-test" {
+		t.Fatalf("unexpected server jar contents %q", jar)
+	}
+
+	// A second run must detect that the installation is current
+	rootCmd.SetArgs(args)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	// version.json twice, archive once
+	if got := backend.Calls(hsmtest.RouteCDN); got != 3 {
+		t.Fatalf("expected no second archive download (3 CDN calls), got %d", got)
+	}
+}
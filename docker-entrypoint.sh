@@ -3,8 +3,11 @@ set -e
 
 # Auto-login if:
 # - session.json doesn't exist AND
-# - user is not explicitly running the login command
-if [ ! -f "$SESSION_FILE" ] && [ "$1" != "login" ]; then
+# - user is not explicitly running the login command AND
+# - user is not running serve, which logs in by itself AND
+# - no refresh token is provided to bootstrap the session from
+if [ ! -f "$SESSION_FILE" ] && [ "$1" != "login" ] && [ "$1" != "serve" ] \
+    && [ -z "$HSM_REFRESH_TOKEN" ] && [ -z "$HSM_REFRESH_TOKEN_FILE" ]; then
     hsm login --session-location "$SESSION_FILE" 
 fi
 
@@ -4,37 +4,120 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
+// Default upstream hosts used when no override is configured
+const (
+	DefaultOAuthURL       = "https://oauth.accounts.hytale.com"
+	DefaultAccountDataURL = "https://account-data.hytale.com"
+	DefaultGameAssetsURL  = "https://account-data.hytale.com/game-assets"
+	DefaultSessionsURL    = "https://sessions.hytale.com"
+)
+
+// Endpoints holds the base URLs of the upstream Hytale services.
+// Empty fields fall back to the corresponding default.
+type Endpoints struct {
+	OAuth       string // OAuth2 device flow and token endpoints
+	AccountData string // my-account endpoints (profiles)
+	GameAssets  string // signed game asset URLs
+	Sessions    string // game session endpoints
+}
+
+// DefaultEndpoints returns the production Hytale endpoints
+func DefaultEndpoints() Endpoints {
+	return Endpoints{
+		OAuth:       DefaultOAuthURL,
+		AccountData: DefaultAccountDataURL,
+		GameAssets:  DefaultGameAssetsURL,
+		Sessions:    DefaultSessionsURL,
+	}
+}
+
+// withDefaults fills empty fields with the default endpoints and strips trailing slashes
+func (e Endpoints) withDefaults() Endpoints {
+	d := DefaultEndpoints()
+	pick := func(v, def string) string {
+		if v == "" {
+			return def
+		}
+		return strings.TrimRight(v, "/")
+	}
+	return Endpoints{
+		OAuth:       pick(e.OAuth, d.OAuth),
+		AccountData: pick(e.AccountData, d.AccountData),
+		GameAssets:  pick(e.GameAssets, d.GameAssets),
+		Sessions:    pick(e.Sessions, d.Sessions),
+	}
+}
+
 // Client provides methods to interact with the HSM API
 type Client struct {
-	baseURL    string
-	httpClient *http.Client
-	token      string // Optional authentication token
+	baseURL     string
+	endpoints   Endpoints
+	httpClient  *http.Client
+	retryPolicy RetryPolicy
+	token       string // Optional authentication token
+	tokenMu     sync.RWMutex
+}
+
+// Option is a functional option for configuring the Client
+type Option func(*Client)
+
+// WithEndpoints overrides the upstream endpoints used by the client
+func WithEndpoints(endpoints Endpoints) Option {
+	return func(c *Client) {
+		c.endpoints = endpoints.withDefaults()
+		c.baseURL = c.endpoints.OAuth
+	}
 }
 
 // New creates a new HSM client
-func New() *Client {
-	return &Client{
-		baseURL: "https://oauth.accounts.hytale.com",
+func New(opts ...Option) *Client {
+	endpoints := DefaultEndpoints()
+	c := &Client{
+		baseURL:   endpoints.OAuth,
+		endpoints: endpoints,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		retryPolicy: DefaultRetryPolicy(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
+}
+
+// Endpoints returns the upstream endpoints used by the client
+func (c *Client) Endpoints() Endpoints {
+	return c.endpoints
 }
 
 // WithToken sets an authentication token for the client
 func (c *Client) WithToken(token string) *Client {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
 	c.token = token
 	return c
 }
 
+// currentToken returns the authentication token, which may be replaced while
+// requests are in flight
+func (c *Client) currentToken() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.token
+}
+
 // WithHTTPClient allows setting a custom HTTP client
 func (c *Client) WithHTTPClient(httpClient *http.Client) *Client {
 	c.httpClient = httpClient
@@ -52,6 +135,35 @@ type Session struct {
 	ExpiresIn    int       `json:"expires_in,omitempty"`
 	ExpiresAt    time.Time `json:"expires_at,omitempty"`
 	CreatedAt    time.Time `json:"created_at,omitempty"`
+
+	// Profile is the UUID of the game profile selected for this login.
+	// It is local state persisted with the session, not part of the OAuth response.
+	Profile string `json:"profile,omitempty"`
+	// GameSessions holds the game sessions created with this login that have not
+	// been terminated or expired yet, so logout can clean them up. Local state as well.
+	GameSessions []TrackedGameSession `json:"game_sessions,omitempty"`
+}
+
+// TrackedGameSession is a game session created with a login
+type TrackedGameSession struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"` // zero if unknown
+}
+
+// Expired reports whether the game session expired before now
+func (t TrackedGameSession) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && t.ExpiresAt.Before(now)
+}
+
+// UnmarshalJSON also accepts the bare token stored by earlier versions
+func (t *TrackedGameSession) UnmarshalJSON(data []byte) error {
+	var token string
+	if err := json.Unmarshal(data, &token); err == nil {
+		*t = TrackedGameSession{Token: token}
+		return nil
+	}
+	type plain TrackedGameSession
+	return json.Unmarshal(data, (*plain)(t))
 }
 
 // NeedsRefresh returns true if the token is expired or will expire within the threshold
@@ -80,47 +192,30 @@ type DeviceAuthorizationResponse struct {
 }
 
 // CreateDeviceFlow initiates the OAuth2 device authorization flow
-func (c *Client) CreateDeviceFlow() (*DeviceAuthorizationResponse, error) {
+func (c *Client) CreateDeviceFlow(ctx context.Context) (*DeviceAuthorizationResponse, error) {
 	// Prepare form data
 	data := url.Values{}
 	data.Set("client_id", "hytale-server")
 	data.Set("scope", "openid offline auth:server")
 
-	body := bytes.NewBufferString(data.Encode())
-
-	httpReq, err := http.NewRequest("POST", c.baseURL+"/oauth2/device/auth", body)
+	respBody, err := c.do(ctx, retryIdempotent, func(ctx context.Context) (*http.Request, error) {
+		return newFormRequest(ctx, c.baseURL+"/oauth2/device/auth", data)
+	}, http.StatusOK, http.StatusCreated)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, err
 	}
 
 	var deviceAuth DeviceAuthorizationResponse
-	if err := json.NewDecoder(resp.Body).Decode(&deviceAuth); err != nil {
+	if err := json.Unmarshal(respBody, &deviceAuth); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	return &deviceAuth, nil
 }
 
-var (
-	// ErrAuthorizationPending indicates the user hasn't authorized the device yet
-	ErrAuthorizationPending = errors.New("authorization_pending")
-)
-
 // ExchangeDeviceCodeForToken makes a single request to exchange a device code for tokens.
-// Returns a Session on success, or ErrAuthorizationPending/ErrSlowDown for special conditions.
+// Returns a Session on success, or an *APIError matching one of the RFC 8628 errors
+// ErrAuthorizationPending, ErrSlowDown, ErrExpiredToken or ErrAccessDenied.
 func (c *Client) ExchangeDeviceCodeForToken(ctx context.Context, deviceCode string) (*Session, error) {
 	// Prepare form data for token exchange
 	data := url.Values{}
@@ -128,94 +223,46 @@ func (c *Client) ExchangeDeviceCodeForToken(ctx context.Context, deviceCode stri
 	data.Set("grant_type", "urn:ietf:params:oauth:grant-type:device_code")
 	data.Set("device_code", deviceCode)
 
-	body := bytes.NewBufferString(data.Encode())
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/oauth2/token", body)
+	respBody, err := c.do(ctx, retryUnprocessed, func(ctx context.Context) (*http.Request, error) {
+		return newFormRequest(ctx, c.baseURL+"/oauth2/token", data)
+	}, http.StatusOK)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-
-	bodyBytes, _ := io.ReadAll(resp.Body)
-	_ = resp.Body.Close()
-
-	if resp.StatusCode == http.StatusBadRequest {
-		var errorResp struct {
-			Error            string `json:"error"`
-			ErrorDescription string `json:"error_description"`
-		}
-		if json.Unmarshal(bodyBytes, &errorResp) == nil {
-			if errorResp.Error == "authorization_pending" {
-				return nil, ErrAuthorizationPending
-			}
-		}
-		return nil, fmt.Errorf("token request failed: %s", string(bodyBytes))
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	// Success response
-	session := &Session{
-		CreatedAt: time.Now(),
-	}
-
-	if err := json.Unmarshal(bodyBytes, session); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	// Set Token field to match AccessToken for backward compatibility
-	session.Token = session.AccessToken
-
-	// Calculate ExpiresAt from ExpiresIn if provided
-	if session.ExpiresIn > 0 {
-		session.ExpiresAt = time.Now().Add(time.Duration(session.ExpiresIn) * time.Second)
-	}
-
-	return session, nil
+	return decodeTokenResponse(respBody)
 }
 
 // CreateSession creates a new session and returns authentication tokens
-func (c *Client) CreateSession(req *CreateSessionRequest) (*Session, error) {
-	var body io.Reader
+func (c *Client) CreateSession(ctx context.Context, req *CreateSessionRequest) (*Session, error) {
+	var jsonData []byte
 	if req != nil {
-		jsonData, err := json.Marshal(req)
+		var err error
+		jsonData, err = json.Marshal(req)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal request: %w", err)
 		}
-		body = bytes.NewBuffer(jsonData)
-	}
-
-	httpReq, err := http.NewRequest("POST", c.baseURL+"/api/v1/session", body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	if c.token != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.token)
-	}
-
-	resp, err := c.httpClient.Do(httpReq)
+	respBody, err := c.do(ctx, retryUnprocessed, func(ctx context.Context) (*http.Request, error) {
+		var body io.Reader
+		if jsonData != nil {
+			body = bytes.NewReader(jsonData)
+		}
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/v1/session", body)
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		c.setAuth(httpReq, c.currentToken())
+		return httpReq, nil
+	}, http.StatusOK, http.StatusCreated)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, err
 	}
 
 	var session Session
-	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+	if err := json.Unmarshal(respBody, &session); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
@@ -223,29 +270,16 @@ func (c *Client) CreateSession(req *CreateSessionRequest) (*Session, error) {
 }
 
 // ListSessions retrieves all sessions for the authenticated user
-func (c *Client) ListSessions() ([]Session, error) {
-	httpReq, err := http.NewRequest("GET", c.baseURL+"/api/v1/session", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	if c.token != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.token)
-	}
-
-	resp, err := c.httpClient.Do(httpReq)
+func (c *Client) ListSessions(ctx context.Context) ([]Session, error) {
+	respBody, err := c.do(ctx, retryIdempotent, func(ctx context.Context) (*http.Request, error) {
+		return c.newAuthRequest(ctx, "GET", c.baseURL+"/api/v1/session", c.currentToken())
+	}, http.StatusOK)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, err
 	}
 
 	var sessions []Session
-	if err := json.NewDecoder(resp.Body).Decode(&sessions); err != nil {
+	if err := json.Unmarshal(respBody, &sessions); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
@@ -253,81 +287,57 @@ func (c *Client) ListSessions() ([]Session, error) {
 }
 
 // DeleteSession deletes a session by ID
-func (c *Client) DeleteSession(sessionID string) error {
+func (c *Client) DeleteSession(ctx context.Context, sessionID string) error {
 	url := c.baseURL + "/api/v1/session"
 	if sessionID != "" {
 		url += "?id=" + sessionID
 	}
 
-	httpReq, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	if c.token != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.token)
-	}
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	return nil
+	_, err := c.do(ctx, retryIdempotent, func(ctx context.Context) (*http.Request, error) {
+		return c.newAuthRequest(ctx, "DELETE", url, c.currentToken())
+	}, http.StatusOK, http.StatusNoContent)
+	return err
 }
 
 // RefreshAccessToken refreshes an access token using a refresh token via OAuth2
-func (c *Client) RefreshAccessToken(refreshToken string) (*Session, error) {
+func (c *Client) RefreshAccessToken(ctx context.Context, refreshToken string) (*Session, error) {
 	// Use OAuth2 refresh_token grant type
 	data := url.Values{}
 	data.Set("client_id", "hytale-server")
 	data.Set("grant_type", "refresh_token")
 	data.Set("refresh_token", refreshToken)
 
-	body := bytes.NewBufferString(data.Encode())
-
-	httpReq, err := http.NewRequest("POST", c.baseURL+"/oauth2/token", body)
+	// Refresh tokens may rotate, so only retry if the upstream did not process the request
+	respBody, err := c.do(ctx, retryUnprocessed, func(ctx context.Context) (*http.Request, error) {
+		return newFormRequest(ctx, c.baseURL+"/oauth2/token", data)
+	}, http.StatusOK)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	bodyBytes, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	session := &Session{
-		CreatedAt: time.Now(),
-	}
-
-	if err := json.Unmarshal(bodyBytes, session); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
+	return decodeTokenResponse(respBody)
+}
 
-	// Set Token field to match AccessToken for backward compatibility
-	session.Token = session.AccessToken
+// Token type hints for RevokeToken (RFC 7009 section 2.1)
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
 
-	// Calculate ExpiresAt from ExpiresIn if provided
-	if session.ExpiresIn > 0 {
-		session.ExpiresAt = time.Now().Add(time.Duration(session.ExpiresIn) * time.Second)
+// RevokeToken revokes an access or refresh token at the OAuth server (RFC 7009).
+// Revoking an already invalid token succeeds.
+func (c *Client) RevokeToken(ctx context.Context, token string, tokenTypeHint string) error {
+	data := url.Values{}
+	data.Set("client_id", "hytale-server")
+	data.Set("token", token)
+	if tokenTypeHint != "" {
+		data.Set("token_type_hint", tokenTypeHint)
 	}
 
-	return session, nil
+	_, err := c.do(ctx, retryIdempotent, func(ctx context.Context) (*http.Request, error) {
+		return newFormRequest(ctx, c.baseURL+"/oauth2/revoke", data)
+	}, http.StatusOK, http.StatusNoContent)
+	return err
 }
 
 // Profile represents a Hytale game profile
@@ -343,29 +353,16 @@ type ProfilesResponse struct {
 }
 
 // GetProfiles retrieves available game profiles for the authenticated user
-func (c *Client) GetProfiles() (*ProfilesResponse, error) {
-	httpReq, err := http.NewRequest("GET", "https://account-data.hytale.com/my-account/get-profiles", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	if c.token != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.token)
-	}
-
-	resp, err := c.httpClient.Do(httpReq)
+func (c *Client) GetProfiles(ctx context.Context) (*ProfilesResponse, error) {
+	respBody, err := c.do(ctx, retryIdempotent, func(ctx context.Context) (*http.Request, error) {
+		return c.newAuthRequest(ctx, "GET", c.endpoints.AccountData+"/my-account/get-profiles", c.currentToken())
+	}, http.StatusOK)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, err
 	}
 
 	var profiles ProfilesResponse
-	if err := json.NewDecoder(resp.Body).Decode(&profiles); err != nil {
+	if err := json.Unmarshal(respBody, &profiles); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
@@ -380,36 +377,29 @@ type GameSession struct {
 }
 
 // CreateGameSession creates a new game session for a specific profile UUID
-func (c *Client) CreateGameSession(profileUUID string) (*GameSession, error) {
+func (c *Client) CreateGameSession(ctx context.Context, profileUUID string) (*GameSession, error) {
 	payload := map[string]string{"uuid": profileUUID}
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", "https://sessions.hytale.com/game-session/new", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	if c.token != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.token)
-	}
-
-	resp, err := c.httpClient.Do(httpReq)
+	// Creating a session is not idempotent, only retry if the upstream did not process the request
+	respBody, err := c.do(ctx, retryUnprocessed, func(ctx context.Context) (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", c.endpoints.Sessions+"/game-session/new", bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		c.setAuth(httpReq, c.currentToken())
+		return httpReq, nil
+	}, http.StatusOK, http.StatusCreated)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, err
 	}
 
 	var gameSession GameSession
-	if err := json.NewDecoder(resp.Body).Decode(&gameSession); err != nil {
+	if err := json.Unmarshal(respBody, &gameSession); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
@@ -417,27 +407,16 @@ func (c *Client) CreateGameSession(profileUUID string) (*GameSession, error) {
 }
 
 // RefreshGameSession refreshes an existing game session using its session token
-func (c *Client) RefreshGameSession(sessionToken string) (*GameSession, error) {
-	httpReq, err := http.NewRequest("POST", "https://sessions.hytale.com/game-session/refresh", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Authorization", "Bearer "+sessionToken)
-
-	resp, err := c.httpClient.Do(httpReq)
+func (c *Client) RefreshGameSession(ctx context.Context, sessionToken string) (*GameSession, error) {
+	respBody, err := c.do(ctx, retryIdempotent, func(ctx context.Context) (*http.Request, error) {
+		return c.newAuthRequest(ctx, "POST", c.endpoints.Sessions+"/game-session/refresh", sessionToken)
+	}, http.StatusOK)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, err
 	}
 
 	var gameSession GameSession
-	if err := json.NewDecoder(resp.Body).Decode(&gameSession); err != nil {
+	if err := json.Unmarshal(respBody, &gameSession); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
@@ -445,26 +424,11 @@ func (c *Client) RefreshGameSession(sessionToken string) (*GameSession, error) {
 }
 
 // TerminateGameSession terminates a game session using its session token
-func (c *Client) TerminateGameSession(sessionToken string) error {
-	httpReq, err := http.NewRequest("DELETE", "https://sessions.hytale.com/game-session", nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Authorization", "Bearer "+sessionToken)
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	return nil
+func (c *Client) TerminateGameSession(ctx context.Context, sessionToken string) error {
+	_, err := c.do(ctx, retryIdempotent, func(ctx context.Context) (*http.Request, error) {
+		return c.newAuthRequest(ctx, "DELETE", c.endpoints.Sessions+"/game-session", sessionToken)
+	}, http.StatusOK, http.StatusNoContent)
+	return err
 }
 
 // SignedURLResponse represents the response containing a signed download URL
@@ -473,33 +437,68 @@ type SignedURLResponse struct {
 }
 
 // GetSignedURL fetches the signed download URL for a patchline
-func (c *Client) GetSignedURL(file string) (*SignedURLResponse, error) {
-	url := fmt.Sprintf("https://account-data.hytale.com/game-assets/%s", file)
+func (c *Client) GetSignedURL(ctx context.Context, file string) (*SignedURLResponse, error) {
+	url := fmt.Sprintf("%s/%s", c.endpoints.GameAssets, file)
 
-	httpReq, err := http.NewRequest("GET", url, nil)
+	respBody, err := c.do(ctx, retryIdempotent, func(ctx context.Context) (*http.Request, error) {
+		return c.newAuthRequest(ctx, "GET", url, c.currentToken())
+	}, http.StatusOK)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	if c.token != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	var result SignedURLResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(httpReq)
+	return &result, nil
+}
+
+// newAuthRequest creates a body-less request authorized with the given bearer token
+func (c *Client) newAuthRequest(ctx context.Context, method, url, token string) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
-	defer func() { _ = resp.Body.Close() }()
+	c.setAuth(httpReq, token)
+	return httpReq, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(bodyBytes))
+// setAuth adds a bearer token to the request if one is set
+func (c *Client) setAuth(httpReq *http.Request, token string) {
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
 	}
+}
 
-	var result SignedURLResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+// newFormRequest creates a form-encoded POST request
+func newFormRequest(ctx context.Context, target string, data url.Values) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", target, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return httpReq, nil
+}
+
+// decodeTokenResponse decodes an OAuth2 token response into a Session
+func decodeTokenResponse(body []byte) (*Session, error) {
+	session := &Session{
+		CreatedAt: time.Now(),
+	}
+
+	if err := json.Unmarshal(body, session); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return &result, nil
+	// Set Token field to match AccessToken for backward compatibility
+	session.Token = session.AccessToken
+
+	// Calculate ExpiresAt from ExpiresIn if provided
+	if session.ExpiresIn > 0 {
+		session.ExpiresAt = time.Now().Add(time.Duration(session.ExpiresIn) * time.Second)
+	}
+
+	return session, nil
 }
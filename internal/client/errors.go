@@ -0,0 +1,171 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sentinel errors used to classify upstream failures with errors.Is
+var (
+	// ErrAuthorizationPending indicates the user hasn't authorized the device yet
+	ErrAuthorizationPending = errors.New("authorization_pending")
+	// ErrSlowDown indicates the device flow is polled too often and the interval must grow
+	ErrSlowDown = errors.New("slow_down")
+	// ErrExpiredToken indicates the device code expired before the user authorized it
+	ErrExpiredToken = errors.New("expired_token")
+	// ErrAccessDenied indicates the user denied the device authorization request
+	ErrAccessDenied = errors.New("access_denied")
+	// ErrUnauthorized indicates the upstream rejected our credentials
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden indicates the credentials are valid but not allowed to perform the operation
+	ErrForbidden = errors.New("forbidden")
+	// ErrNotFound indicates the requested upstream resource does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited indicates the upstream is throttling requests
+	ErrRateLimited = errors.New("rate limited")
+	// ErrSessionLimitReached indicates the account cannot hold more game sessions
+	ErrSessionLimitReached = errors.New("session limit reached")
+	// ErrUpstreamUnavailable indicates a server-side upstream failure
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+)
+
+// Upstream error codes that map to sentinel errors
+const (
+	codeSessionLimitReached = "session_limit_reached"
+	codeTooManySessions     = "too_many_sessions"
+)
+
+// APIError is returned for every non-successful upstream response
+type APIError struct {
+	StatusCode int    // HTTP status code
+	Code       string // upstream error code, if any
+	Message    string // upstream error message, if any
+
+	OAuthError            string // RFC 6749 error, e.g. invalid_grant
+	OAuthErrorDescription string // RFC 6749 error_description
+
+	Retryable  bool          // whether repeating the request may succeed
+	RetryAfter time.Duration // delay requested by the upstream via Retry-After
+	Body       []byte        // raw response body
+}
+
+func (e *APIError) Error() string {
+	detail := strings.TrimSpace(string(e.Body))
+	switch {
+	case e.OAuthError != "" && e.OAuthErrorDescription != "":
+		detail = e.OAuthError + ": " + e.OAuthErrorDescription
+	case e.OAuthError != "":
+		detail = e.OAuthError
+	case e.Code != "" && e.Message != "":
+		detail = e.Code + ": " + e.Message
+	case e.Message != "":
+		detail = e.Message
+	case e.Code != "":
+		detail = e.Code
+	}
+	if detail == "" {
+		return fmt.Sprintf("unexpected status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, detail)
+}
+
+// Is classifies the error against the sentinel errors of this package
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrAuthorizationPending:
+		return e.OAuthError == "authorization_pending"
+	case ErrSlowDown:
+		return e.OAuthError == "slow_down"
+	case ErrExpiredToken:
+		return e.OAuthError == "expired_token"
+	case ErrAccessDenied:
+		return e.OAuthError == "access_denied"
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.OAuthError == "invalid_grant" || e.OAuthError == "invalid_token"
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden && !e.isSessionLimit()
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests && !e.isSessionLimit()
+	case ErrSessionLimitReached:
+		return e.isSessionLimit()
+	case ErrUpstreamUnavailable:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+func (e *APIError) isSessionLimit() bool {
+	for _, code := range []string{e.Code, e.OAuthError} {
+		switch strings.ToLower(code) {
+		case codeSessionLimitReached, codeTooManySessions:
+			return true
+		}
+	}
+	return false
+}
+
+// newAPIError builds an APIError from an upstream response and its already-read body
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       body,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	var payload struct {
+		Error            any    `json:"error"`
+		ErrorDescription string `json:"error_description"`
+		Code             any    `json:"code"`
+		Message          string `json:"message"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		if s, ok := payload.Error.(string); ok {
+			apiErr.OAuthError = s
+		}
+		apiErr.OAuthErrorDescription = payload.ErrorDescription
+		apiErr.Message = payload.Message
+		switch code := payload.Code.(type) {
+		case string:
+			apiErr.Code = code
+		case float64:
+			apiErr.Code = strconv.FormatFloat(code, 'f', -1, 64)
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		apiErr.Retryable = !apiErr.isSessionLimit()
+	}
+
+	return apiErr
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// IsRetryable reports whether err is an upstream error that may succeed on retry
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Retryable
+}
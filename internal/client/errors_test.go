@@ -0,0 +1,101 @@
+package client_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"hsm/internal/client"
+	"hsm/internal/hsmtest"
+)
+
+func TestAPIErrorClassification(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		want      error
+		retryable bool
+	}{
+		{"unauthorized", http.StatusUnauthorized, `{"code":"unauthorized"}`, client.ErrUnauthorized, false},
+		{"forbidden", http.StatusForbidden, `{"code":"forbidden"}`, client.ErrForbidden, false},
+		{"not found", http.StatusNotFound, `{"code":"profile_not_found"}`, client.ErrNotFound, false},
+		{"rate limited", http.StatusTooManyRequests, `{"code":"slow down"}`, client.ErrRateLimited, true},
+		{"session limit", http.StatusForbidden, `{"code":"session_limit_reached","message":"too many"}`, client.ErrSessionLimitReached, false},
+		{"bad gateway", http.StatusBadGateway, `<html>bad gateway</html>`, client.ErrUpstreamUnavailable, true},
+		{"service unavailable", http.StatusServiceUnavailable, ``, client.ErrUpstreamUnavailable, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := hsmtest.New(t)
+			c := backend.Client().WithToken(backend.IssueSession().AccessToken)
+			backend.FailNext(hsmtest.RouteProfiles, tt.status, tt.body)
+
+			_, err := c.GetProfiles(t.Context())
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+
+			var apiErr *client.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected *client.APIError, got %T", err)
+			}
+			if apiErr.StatusCode != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, apiErr.StatusCode)
+			}
+			if apiErr.Retryable != tt.retryable {
+				t.Fatalf("expected retryable=%v", tt.retryable)
+			}
+			if string(apiErr.Body) != tt.body {
+				t.Fatalf("expected raw body to be preserved, got %q", apiErr.Body)
+			}
+		})
+	}
+}
+
+func TestAPIErrorOAuthFields(t *testing.T) {
+	backend := hsmtest.New(t)
+
+	_, err := backend.Client().RefreshAccessToken(t.Context(), "unknown-refresh-token")
+	if !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("expected invalid_grant to classify as ErrUnauthorized, got %v", err)
+	}
+
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.OAuthError != "invalid_grant" || apiErr.OAuthErrorDescription == "" {
+		t.Fatalf("expected OAuth error fields, got %+v", apiErr)
+	}
+}
+
+func TestAPIErrorAuthorizationPending(t *testing.T) {
+	backend := hsmtest.New(t)
+	backend.SetPendingAuthorizations(1)
+	c := backend.Client()
+
+	deviceAuth, err := c.CreateDeviceFlow(t.Context())
+	if err != nil {
+		t.Fatalf("CreateDeviceFlow: %v", err)
+	}
+
+	_, err = c.ExchangeDeviceCodeForToken(t.Context(), deviceAuth.DeviceCode)
+	if !errors.Is(err, client.ErrAuthorizationPending) {
+		t.Fatalf("expected ErrAuthorizationPending, got %v", err)
+	}
+}
+
+func TestAPIErrorRetryAfter(t *testing.T) {
+	backend := hsmtest.New(t)
+	c := backend.Client().WithToken(backend.IssueSession().AccessToken)
+	backend.FailNextWith(hsmtest.RouteProfiles, hsmtest.Failure{
+		Status: http.StatusTooManyRequests,
+		Header: http.Header{"Retry-After": []string{"7"}},
+	})
+
+	_, err := c.GetProfiles(t.Context())
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != 7*time.Second {
+		t.Fatalf("expected Retry-After of 7s, got %+v", apiErr)
+	}
+}
@@ -1,6 +1,7 @@
 package client_test
 
 import (
+	"context"
 	"fmt"
 	"hsm/internal/client"
 )
@@ -10,7 +11,7 @@ func ExampleClient_CreateSession() {
 	cl := client.New()
 
 	// Create a new session with credentials
-	session, err := cl.CreateSession(&client.CreateSessionRequest{
+	session, err := cl.CreateSession(context.Background(), &client.CreateSessionRequest{
 		Username: "user@example.com",
 		Password: "password123",
 		TTL:      3600, // 1 hour
@@ -29,7 +30,7 @@ func ExampleClient_ListSessions() {
 	cl := client.New().WithToken("your-access-token")
 
 	// List all sessions
-	sessions, err := cl.ListSessions()
+	sessions, err := cl.ListSessions(context.Background())
 	if err != nil {
 		fmt.Printf("Error listing sessions: %v\n", err)
 		return
@@ -46,7 +47,7 @@ func ExampleClient_DeleteSession() {
 	cl := client.New().WithToken("your-access-token")
 
 	// Delete a session by ID
-	err := cl.DeleteSession("session-id-123")
+	err := cl.DeleteSession(context.Background(), "session-id-123")
 	if err != nil {
 		fmt.Printf("Error deleting session: %v\n", err)
 		return
@@ -59,7 +60,7 @@ func ExampleClient_RefreshAccessToken() {
 	cl := client.New()
 
 	// Refresh an access token using a refresh token (OAuth2)
-	session, err := cl.RefreshAccessToken("your-refresh-token")
+	session, err := cl.RefreshAccessToken(context.Background(), "your-refresh-token")
 	if err != nil {
 		fmt.Printf("Error refreshing token: %v\n", err)
 		return
@@ -0,0 +1,173 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// RetryPolicy controls how failed upstream calls are retried
+type RetryPolicy struct {
+	MaxAttempts    int           // total attempts including the first one; values <= 1 disable retries
+	InitialBackoff time.Duration // delay before the first retry
+	MaxBackoff     time.Duration // upper bound for the exponential backoff
+	Jitter         float64       // fraction (0-1) of each backoff that is randomized
+	CallTimeout    time.Duration // deadline for a whole call including retries; 0 disables it
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Jitter:         0.2,
+		CallTimeout:    60 * time.Second,
+	}
+}
+
+// WithRetryPolicy sets the retry policy used for upstream calls
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// maxBackoffShift bounds the doublings of the backoff
+const maxBackoffShift = 30
+
+// backoff returns the delay before the given retry (1-based)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	// Cap the shifts so that an uncapped backoff cannot overflow
+	shift := min(max(retry-1, 0), maxBackoffShift)
+	d := time.Duration(math.MaxInt64)
+	if p.InitialBackoff <= d>>shift {
+		d = p.InitialBackoff << shift
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 && d > 0 {
+		spread := float64(d) * p.Jitter
+		d += time.Duration(spread * (2*rand.Float64() - 1))
+	}
+	return d
+}
+
+// retryMode describes how safely a request may be repeated
+type retryMode int
+
+const (
+	// retryNever sends the request exactly once
+	retryNever retryMode = iota
+	// retryUnprocessed only retries responses indicating the upstream did not
+	// process the request (429, 502, 503, 504). Used for non-idempotent calls.
+	retryUnprocessed
+	// retryIdempotent retries every transient failure including network errors
+	retryIdempotent
+)
+
+// unprocessedStatuses are responses after which a non-idempotent request can be repeated
+var unprocessedStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// do sends the request built by newRequest, retrying according to mode and the
+// client's retry policy. It returns the body of the first response whose status
+// is one of okStatuses, or an *APIError for the last failed response.
+func (c *Client) do(ctx context.Context, mode retryMode, newRequest func(ctx context.Context) (*http.Request, error), okStatuses ...int) ([]byte, error) {
+	policy := c.retryPolicy
+	if policy.CallTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.CallTimeout)
+		defer cancel()
+	}
+
+	attempts := policy.MaxAttempts
+	if mode == retryNever || attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		body, err := c.doOnce(ctx, newRequest, okStatuses)
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+
+		if attempt >= attempts || !shouldRetry(mode, err) {
+			return nil, lastErr
+		}
+
+		wait := policy.backoff(attempt)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > wait {
+			wait = apiErr.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return nil, lastErr
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, lastErr
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) doOnce(ctx context.Context, newRequest func(ctx context.Context) (*http.Request, error), okStatuses []int) ([]byte, error) {
+	httpReq, err := newRequest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if !slices.Contains(okStatuses, resp.StatusCode) {
+		return nil, newAPIError(resp, body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return body, nil
+}
+
+func shouldRetry(mode retryMode, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	isAPIErr := errors.As(err, &apiErr)
+
+	switch mode {
+	case retryUnprocessed:
+		return isAPIErr && apiErr.Retryable && slices.Contains(unprocessedStatuses, apiErr.StatusCode)
+	case retryIdempotent:
+		if isAPIErr {
+			return apiErr.Retryable
+		}
+		// Transport errors (connection refused, reset, timeouts) are worth retrying
+		return true
+	}
+	return false
+}
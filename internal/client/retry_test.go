@@ -0,0 +1,147 @@
+package client_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"hsm/internal/client"
+	"hsm/internal/hsmtest"
+)
+
+func fastRetries(attempts int) client.Option {
+	return client.WithRetryPolicy(client.RetryPolicy{
+		MaxAttempts:    attempts,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		CallTimeout:    5 * time.Second,
+	})
+}
+
+func TestRetryTransientCreateGameSession(t *testing.T) {
+	backend := hsmtest.New(t)
+	c := backend.Client(fastRetries(3)).WithToken(backend.IssueSession().AccessToken)
+	backend.FailNext(hsmtest.RouteGameSessionNew, http.StatusBadGateway, `{"code":"bad_gateway"}`)
+	backend.FailNext(hsmtest.RouteGameSessionNew, http.StatusTooManyRequests, `{}`)
+
+	if _, err := c.CreateGameSession(t.Context(), hsmtest.DefaultProfile.UUID); err != nil {
+		t.Fatalf("CreateGameSession: %v", err)
+	}
+	if got := backend.Calls(hsmtest.RouteGameSessionNew); got != 3 {
+		t.Fatalf("expected 3 attempts, got %d", got)
+	}
+}
+
+func TestRetryNonIdempotentSkipsInternalError(t *testing.T) {
+	backend := hsmtest.New(t)
+	c := backend.Client(fastRetries(3)).WithToken(backend.IssueSession().AccessToken)
+	backend.FailNext(hsmtest.RouteGameSessionNew, http.StatusInternalServerError, `{}`)
+
+	if _, err := c.CreateGameSession(t.Context(), hsmtest.DefaultProfile.UUID); err == nil {
+		t.Fatal("expected error, a 500 may have created the session")
+	}
+	if got := backend.Calls(hsmtest.RouteGameSessionNew); got != 1 {
+		t.Fatalf("expected a single attempt, got %d", got)
+	}
+}
+
+func TestRetryIdempotentRetriesInternalError(t *testing.T) {
+	backend := hsmtest.New(t)
+	c := backend.Client(fastRetries(3)).WithToken(backend.IssueSession().AccessToken)
+	backend.FailNext(hsmtest.RouteProfiles, http.StatusInternalServerError, `{}`)
+
+	if _, err := c.GetProfiles(t.Context()); err != nil {
+		t.Fatalf("GetProfiles: %v", err)
+	}
+	if got := backend.Calls(hsmtest.RouteProfiles); got != 2 {
+		t.Fatalf("expected 2 attempts, got %d", got)
+	}
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	backend := hsmtest.New(t)
+	c := backend.Client(fastRetries(2)).WithToken(backend.IssueSession().AccessToken)
+	for range 3 {
+		backend.FailNext(hsmtest.RouteProfiles, http.StatusServiceUnavailable, `{}`)
+	}
+
+	_, err := c.GetProfiles(t.Context())
+	if !errors.Is(err, client.ErrUpstreamUnavailable) {
+		t.Fatalf("expected ErrUpstreamUnavailable, got %v", err)
+	}
+	if got := backend.Calls(hsmtest.RouteProfiles); got != 2 {
+		t.Fatalf("expected 2 attempts, got %d", got)
+	}
+}
+
+func TestRetryDoesNotRetryClientErrors(t *testing.T) {
+	backend := hsmtest.New(t)
+	c := backend.Client(fastRetries(3)).WithToken("invalid")
+
+	if _, err := c.GetProfiles(t.Context()); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+	if got := backend.Calls(hsmtest.RouteProfiles); got != 1 {
+		t.Fatalf("expected a single attempt, got %d", got)
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	backend := hsmtest.New(t)
+	c := backend.Client(fastRetries(2)).WithToken(backend.IssueSession().AccessToken)
+	backend.FailNextWith(hsmtest.RouteProfiles, hsmtest.Failure{
+		Status: http.StatusTooManyRequests,
+		Header: http.Header{"Retry-After": []string{"1"}},
+	})
+
+	start := time.Now()
+	if _, err := c.GetProfiles(t.Context()); err != nil {
+		t.Fatalf("GetProfiles: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("expected to wait for Retry-After, waited %v", elapsed)
+	}
+}
+
+func TestRetryStopsAtCallTimeout(t *testing.T) {
+	backend := hsmtest.New(t)
+	c := backend.Client(client.WithRetryPolicy(client.RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Millisecond,
+		CallTimeout:    200 * time.Millisecond,
+	})).WithToken(backend.IssueSession().AccessToken)
+	backend.FailNextWith(hsmtest.RouteProfiles, hsmtest.Failure{
+		Status: http.StatusTooManyRequests,
+		Header: http.Header{"Retry-After": []string{"30"}},
+	})
+
+	start := time.Now()
+	if _, err := c.GetProfiles(t.Context()); !errors.Is(err, client.ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected to give up before the deadline, took %v", elapsed)
+	}
+}
+
+func TestRetryBackoffGrowsWithoutMaxBackoff(t *testing.T) {
+	backend := hsmtest.New(t)
+	c := backend.Client(client.WithRetryPolicy(client.RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 10 * time.Millisecond,
+		CallTimeout:    5 * time.Second,
+	})).WithToken(backend.IssueSession().AccessToken)
+	for range 3 {
+		backend.FailNext(hsmtest.RouteProfiles, http.StatusServiceUnavailable, `{}`)
+	}
+
+	start := time.Now()
+	if _, err := c.GetProfiles(t.Context()); err != nil {
+		t.Fatalf("GetProfiles: %v", err)
+	}
+	// 10ms + 20ms + 40ms, a flat backoff would wait 30ms
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Fatalf("expected the backoff to double without MaxBackoff, retries took %v", elapsed)
+	}
+}
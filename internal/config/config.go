@@ -0,0 +1,142 @@
+// Package config reads and writes the HSM CLI configuration file.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"hsm/internal/utils"
+)
+
+// ErrAccountNotFound is returned for account names missing from the config
+var ErrAccountNotFound = errors.New("account not found")
+
+// validName restricts account names to something safe to use in paths
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Account is a named Hytale login with its defaults
+type Account struct {
+	Name string `json:"name"`
+	// SessionStore is a session store URI or a path to a session file
+	SessionStore string `json:"session_store"`
+	Profile      string `json:"profile,omitempty"`
+	Patchline    string `json:"patchline,omitempty"`
+}
+
+// Config is the content of the config file
+type Config struct {
+	CurrentAccount string    `json:"current_account,omitempty"`
+	Accounts       []Account `json:"accounts,omitempty"`
+}
+
+// Dir returns the HSM config directory, ~/.config/hsm
+func Dir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		// Fallback to current directory if home dir cannot be determined
+		return "."
+	}
+	return filepath.Join(homeDir, ".config", "hsm")
+}
+
+// DefaultPath returns the default config file location, ~/.config/hsm/config.json
+func DefaultPath() string {
+	return filepath.Join(Dir(), "config.json")
+}
+
+// Load reads the config file at path. A missing file yields an empty config.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Save writes the config file at path
+func (c *Config) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return utils.WriteSecretFile(path, data)
+}
+
+// Account returns the account with name
+func (c *Config) Account(name string) (*Account, error) {
+	for i := range c.Accounts {
+		if c.Accounts[i].Name == name {
+			return &c.Accounts[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, name)
+}
+
+// Current returns the current account, or nil if none is selected
+func (c *Config) Current() (*Account, error) {
+	if c.CurrentAccount == "" {
+		return nil, nil
+	}
+	return c.Account(c.CurrentAccount)
+}
+
+// Add adds an account. The first account becomes the current one.
+func (c *Config) Add(account Account) error {
+	if !validName.MatchString(account.Name) {
+		return fmt.Errorf("invalid account name %q: use letters, digits, '.', '_' and '-'", account.Name)
+	}
+	if account.SessionStore == "" {
+		return fmt.Errorf("account %s has no session store", account.Name)
+	}
+	if _, err := c.Account(account.Name); err == nil {
+		return fmt.Errorf("account %s already exists", account.Name)
+	}
+
+	c.Accounts = append(c.Accounts, account)
+	if c.CurrentAccount == "" {
+		c.CurrentAccount = account.Name
+	}
+	return nil
+}
+
+// Remove removes an account, clearing the current account if it was selected
+func (c *Config) Remove(name string) error {
+	if _, err := c.Account(name); err != nil {
+		return err
+	}
+	c.Accounts = slices.DeleteFunc(c.Accounts, func(a Account) bool {
+		return a.Name == name
+	})
+	if c.CurrentAccount == name {
+		c.CurrentAccount = ""
+	}
+	return nil
+}
+
+// Use makes the account with name the current one
+func (c *Config) Use(name string) error {
+	if _, err := c.Account(name); err != nil {
+		return err
+	}
+	c.CurrentAccount = name
+	return nil
+}
+
+// DefaultSessionPath returns the session file used for a new account without
+// an explicit store, accounts/<name>/session.json next to the config file
+func DefaultSessionPath(configPath, name string) string {
+	return filepath.Join(filepath.Dir(configPath), "accounts", name, "session.json")
+}
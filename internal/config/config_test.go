@@ -0,0 +1,63 @@
+package config_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"hsm/internal/config"
+)
+
+func TestConfigAccounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load missing file: %v", err)
+	}
+	if current, err := cfg.Current(); current != nil || err != nil {
+		t.Fatalf("expected no current account, got %v, %v", current, err)
+	}
+
+	if err := cfg.Add(config.Account{Name: "prod", SessionStore: "/data/prod.json"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := cfg.Add(config.Account{Name: "staging", SessionStore: "/data/staging.json", Patchline: "prerelease"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := cfg.Add(config.Account{Name: "prod", SessionStore: "/data/other.json"}); err == nil {
+		t.Fatal("expected duplicate account to be rejected")
+	}
+	if err := cfg.Add(config.Account{Name: "../escape", SessionStore: "/data/x.json"}); err == nil {
+		t.Fatal("expected invalid account name to be rejected")
+	}
+	if cfg.CurrentAccount != "prod" {
+		t.Fatalf("expected the first account to become current, got %q", cfg.CurrentAccount)
+	}
+
+	if err := cfg.Use("staging"); err != nil {
+		t.Fatalf("Use: %v", err)
+	}
+	if err := cfg.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	current, err := loaded.Current()
+	if err != nil || current.Name != "staging" || current.Patchline != "prerelease" {
+		t.Fatalf("unexpected current account %+v, %v", current, err)
+	}
+
+	if err := loaded.Remove("staging"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if loaded.CurrentAccount != "" {
+		t.Fatal("expected removing the current account to clear it")
+	}
+	if err := loaded.Use("staging"); !errors.Is(err, config.ErrAccountNotFound) {
+		t.Fatalf("expected ErrAccountNotFound, got %v", err)
+	}
+}
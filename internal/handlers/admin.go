@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"hsm/api"
+	"hsm/internal/services"
+	"hsm/internal/utils"
+)
+
+// AdminLogout terminates tracked game sessions, revokes the OAuth tokens and
+// removes the session of every account in the pool
+// (POST /admin/v1/logout)
+func (s *Server) AdminLogout(w http.ResponseWriter, r *http.Request, params api.AdminLogoutParams) {
+	force := params.Force != nil && *params.Force
+
+	members := s.pool.Members()
+	response := api.LogoutResponse{Revoked: true, Removed: true}
+	loggedOut := 0
+	var errs []error
+	for _, member := range members {
+		result, err := member.Service.Logout(r.Context(), force)
+		if errors.Is(err, services.ErrNotLoggedIn) && len(members) > 1 {
+			// Other accounts of the pool may still be logged in
+			continue
+		}
+		if err != nil && len(members) > 1 {
+			err = fmt.Errorf("account %s: %w", member.Name, err)
+		}
+		if err != nil && (result == nil || !result.Removed) {
+			// Keep going, the other accounts are logged out regardless
+			errs = append(errs, err)
+			continue
+		}
+		if err != nil {
+			// Forced logout, reported through Revoked in the response
+			log.Printf("logged out without revoking tokens: %v", err)
+		}
+
+		loggedOut++
+		response.TerminatedGameSessions += result.TerminatedGameSessions
+		response.Revoked = response.Revoked && result.Revoked
+		response.Removed = response.Removed && result.Removed
+	}
+
+	// The subjects' game sessions were terminated with the login
+	if loggedOut > 0 && s.isMultiUser() {
+		s.userSessionService.Reset()
+	}
+
+	if len(errs) > 0 {
+		writeError(w, "log out", errors.Join(errs...))
+		return
+	}
+	if loggedOut == 0 {
+		writeError(w, "log out", services.ErrNotLoggedIn)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// AdminGetPool returns the accounts game sessions are spread across
+// (GET /admin/v1/pool)
+func (s *Server) AdminGetPool(w http.ResponseWriter, r *http.Request) {
+	response := api.PoolState{
+		Strategy: api.PoolStateStrategy(s.pool.Strategy()),
+		Accounts: []api.PoolAccount{},
+	}
+	for _, state := range s.pool.State() {
+		account := api.PoolAccount{
+			Name:           state.Name,
+			LoggedIn:       state.LoggedIn,
+			ActiveSessions: state.ActiveSessions,
+			Available:      state.CooldownUntil.IsZero(),
+		}
+		if state.Owner != "" {
+			account.Owner = &state.Owner
+		}
+		if state.Profile.Username != "" {
+			account.Profile = &state.Profile.Username
+		}
+		if !state.CooldownUntil.IsZero() {
+			account.CooldownUntil = &state.CooldownUntil
+		}
+		if state.LastError != "" {
+			account.LastError = &state.LastError
+		}
+		response.Accounts = append(response.Accounts, account)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
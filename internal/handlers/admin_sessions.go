@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"net/http"
+
+	"hsm/api"
+	"hsm/internal/services"
+	"hsm/internal/utils"
+)
+
+// AdminListSessions returns the tracked game sessions
+// (GET /admin/v1/sessions)
+func (s *Server) AdminListSessions(w http.ResponseWriter, r *http.Request, params api.AdminListSessionsParams) {
+	if !s.requireMultiUser(w) {
+		return
+	}
+	subject := ""
+	if params.Subject != nil {
+		subject = *params.Subject
+	}
+	utils.WriteJSON(w, http.StatusOK, toAPIAdminSessions(s.userSessionService.Sessions(subject)))
+}
+
+// AdminTerminateAllSessions terminates every tracked game session
+// (DELETE /admin/v1/sessions)
+func (s *Server) AdminTerminateAllSessions(w http.ResponseWriter, r *http.Request) {
+	if !s.requireMultiUser(w) {
+		return
+	}
+	terminated, err := s.userSessionService.TerminateAll(r.Context())
+	if err != nil {
+		writeError(w, "terminate game sessions", err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, api.TerminateResponse{Terminated: terminated})
+}
+
+// AdminTerminateSubjectSessions terminates the game sessions of a subject
+// (DELETE /admin/v1/sessions/{subject})
+func (s *Server) AdminTerminateSubjectSessions(w http.ResponseWriter, r *http.Request, subject string) {
+	if !s.requireMultiUser(w) {
+		return
+	}
+	terminated, err := s.userSessionService.TerminateSubject(r.Context(), subject)
+	if err != nil {
+		writeError(w, "terminate game sessions", err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, api.TerminateResponse{Terminated: terminated})
+}
+
+// AdminRefreshSubjectSessions refreshes the game sessions of a subject
+// (POST /admin/v1/sessions/{subject}/refresh)
+func (s *Server) AdminRefreshSubjectSessions(w http.ResponseWriter, r *http.Request, subject string) {
+	if !s.requireMultiUser(w) {
+		return
+	}
+	refreshed, err := s.userSessionService.RefreshSubject(r.Context(), subject)
+	if err != nil {
+		writeError(w, "refresh game sessions", err)
+		return
+	}
+	if refreshed == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, api.ErrorResponse{Error: "no session"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, toAPIAdminSessions(s.userSessionService.Sessions(subject)))
+}
+
+// AdminListBlocked returns the blocked subjects
+// (GET /admin/v1/blocked)
+func (s *Server) AdminListBlocked(w http.ResponseWriter, r *http.Request) {
+	if !s.requireMultiUser(w) {
+		return
+	}
+	subjects := s.userSessionService.Blocked()
+	if subjects == nil {
+		subjects = []string{}
+	}
+	utils.WriteJSON(w, http.StatusOK, api.BlockedSubjects{Subjects: subjects})
+}
+
+// AdminBlockSubject keeps a subject from creating new game sessions
+// (PUT /admin/v1/blocked/{subject})
+func (s *Server) AdminBlockSubject(w http.ResponseWriter, r *http.Request, subject string) {
+	if !s.requireMultiUser(w) {
+		return
+	}
+	if err := s.userSessionService.Block(r.Context(), subject); err != nil {
+		writeError(w, "block subject", err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, api.MessageResponse{Message: "blocked"})
+}
+
+// AdminUnblockSubject lets a subject create game sessions again
+// (DELETE /admin/v1/blocked/{subject})
+func (s *Server) AdminUnblockSubject(w http.ResponseWriter, r *http.Request, subject string) {
+	if !s.requireMultiUser(w) {
+		return
+	}
+	if err := s.userSessionService.Unblock(r.Context(), subject); err != nil {
+		writeError(w, "unblock subject", err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, api.MessageResponse{Message: "unblocked"})
+}
+
+// requireMultiUser writes a 501 unless the server runs in multi-user mode
+func (s *Server) requireMultiUser(w http.ResponseWriter) bool {
+	if s.isMultiUser() {
+		return true
+	}
+	utils.WriteJSON(w, http.StatusNotImplemented, api.ErrorResponse{Error: "not available in single-user mode"})
+	return false
+}
+
+// toAPIAdminSessions converts tracked sessions to api.AdminSessionList
+func toAPIAdminSessions(sessions []services.SessionInfo) api.AdminSessionList {
+	result := api.AdminSessionList{Sessions: []api.AdminSession{}}
+	for _, info := range sessions {
+		session := api.AdminSession{
+			Subject:   info.Key.Subject,
+			Linked:    info.Linked,
+			ExpiresAt: info.ExpiresAt,
+		}
+		if info.Key.Instance != "" {
+			session.Instance = &info.Key.Instance
+		}
+		if info.Account != "" {
+			session.Account = &info.Account
+		}
+		if info.Profile != "" {
+			session.Profile = &info.Profile
+		}
+		if !info.CreatedAt.IsZero() {
+			session.CreatedAt = &info.CreatedAt
+		}
+		if !info.RefreshedAt.IsZero() {
+			session.RefreshedAt = &info.RefreshedAt
+		}
+		if !info.LeaseExpiresAt.IsZero() {
+			session.LeaseExpiresAt = &info.LeaseExpiresAt
+		}
+		if info.RefreshFailure != nil {
+			session.RefreshError = &info.RefreshFailure.Error
+			session.RefreshFailures = &info.RefreshFailure.Attempts
+		}
+		result.Sessions = append(result.Sessions, session)
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"hsm/api"
+	"hsm/internal/hsmtest"
+)
+
+func TestAdminSessions(t *testing.T) {
+	backend := hsmtest.New(t)
+	h := newTestHandler(t, backend, true)
+
+	for _, target := range []string{"/api/v1/session", "/api/v1/session?instance=lobby"} {
+		if rec := do(t, h, http.MethodPost, target, "alice"); rec.Code != http.StatusOK {
+			t.Fatalf("create: expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+	}
+	if rec := do(t, h, http.MethodPost, "/api/v1/session", "bob"); rec.Code != http.StatusOK {
+		t.Fatalf("create: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := do(t, h, http.MethodGet, "/admin/v1/sessions", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var list api.AdminSessionList
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(list.Sessions) != 3 {
+		t.Fatalf("expected 3 sessions, got %d", len(list.Sessions))
+	}
+	if session := list.Sessions[1]; session.Subject != "alice" || session.Instance == nil || *session.Instance != "lobby" ||
+		session.Account == nil || *session.Account != "default" || session.CreatedAt == nil {
+		t.Fatalf("unexpected session %+v", session)
+	}
+
+	if rec := do(t, h, http.MethodPost, "/admin/v1/sessions/alice/refresh", ""); rec.Code != http.StatusOK {
+		t.Fatalf("refresh: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(t, h, http.MethodPost, "/admin/v1/sessions/carol/refresh", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("refresh unknown subject: expected 404, got %d", rec.Code)
+	}
+
+	rec = do(t, h, http.MethodDelete, "/admin/v1/sessions/alice", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("terminate: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var terminated api.TerminateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&terminated); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if terminated.Terminated != 2 || backend.GameSessionCount() != 1 {
+		t.Fatalf("expected alice's 2 sessions to be terminated, got %d, %d left upstream", terminated.Terminated, backend.GameSessionCount())
+	}
+
+	if rec := do(t, h, http.MethodDelete, "/admin/v1/sessions", ""); rec.Code != http.StatusOK {
+		t.Fatalf("terminate all: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if backend.GameSessionCount() != 0 {
+		t.Fatalf("expected no upstream sessions, got %d", backend.GameSessionCount())
+	}
+}
+
+func TestAdminBlockSubject(t *testing.T) {
+	backend := hsmtest.New(t)
+	h := newTestHandler(t, backend, true)
+
+	if rec := do(t, h, http.MethodPut, "/admin/v1/blocked/alice", ""); rec.Code != http.StatusOK {
+		t.Fatalf("block: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := do(t, h, http.MethodPost, "/api/v1/session", "alice")
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("create while blocked: expected 403, got %d: %s", rec.Code, rec.Body)
+	}
+	var errResp api.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&errResp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if errResp.Code == nil || *errResp.Code != api.SubjectBlocked {
+		t.Fatalf("expected code %s, got %v", api.SubjectBlocked, errResp.Code)
+	}
+
+	rec = do(t, h, http.MethodGet, "/admin/v1/blocked", "")
+	var blocked api.BlockedSubjects
+	if err := json.NewDecoder(rec.Body).Decode(&blocked); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(blocked.Subjects) != 1 || blocked.Subjects[0] != "alice" {
+		t.Fatalf("unexpected blocked subjects %v", blocked.Subjects)
+	}
+
+	if rec := do(t, h, http.MethodDelete, "/admin/v1/blocked/alice", ""); rec.Code != http.StatusOK {
+		t.Fatalf("unblock: expected 200, got %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodPost, "/api/v1/session", "alice"); rec.Code != http.StatusOK {
+		t.Fatalf("create after unblock: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestAdminSessionsSingleUser(t *testing.T) {
+	backend := hsmtest.New(t)
+	h := newTestHandler(t, backend, false)
+
+	if rec := do(t, h, http.MethodGet, "/admin/v1/sessions", ""); rec.Code != http.StatusNotImplemented {
+		t.Fatalf("expected 501 in single-user mode, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,125 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"hsm/api"
+	"hsm/internal/handlers"
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+	"hsm/internal/sessionstore"
+)
+
+func TestAdminLogout(t *testing.T) {
+	backend := hsmtest.New(t)
+	h := newTestHandler(t, backend, true)
+
+	if rec := do(t, h, http.MethodPost, "/api/v1/session", "alice"); rec.Code != http.StatusOK {
+		t.Fatalf("create: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := do(t, h, http.MethodPost, "/admin/v1/logout", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("logout: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var result api.LogoutResponse
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.TerminatedGameSessions != 1 || !result.Revoked || !result.Removed {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if backend.GameSessionCount() != 0 {
+		t.Fatalf("expected no game sessions, got %d", backend.GameSessionCount())
+	}
+
+	if rec := do(t, h, http.MethodGet, "/api/v1/session", "alice"); rec.Code != http.StatusNotFound {
+		t.Fatalf("get after logout: expected 404, got %d", rec.Code)
+	}
+
+	rec = do(t, h, http.MethodPost, "/api/v1/session", "alice")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("create after logout: expected 503, got %d: %s", rec.Code, rec.Body)
+	}
+	var errResp api.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&errResp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if errResp.Code == nil || *errResp.Code != api.NotLoggedIn {
+		t.Fatalf("expected code %s, got %v", api.NotLoggedIn, errResp.Code)
+	}
+}
+
+func TestAdminGetPool(t *testing.T) {
+	backend := hsmtest.New(t)
+	h := newTestHandler(t, backend, true)
+
+	if rec := do(t, h, http.MethodPost, "/api/v1/session", "alice"); rec.Code != http.StatusOK {
+		t.Fatalf("create: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := do(t, h, http.MethodGet, "/admin/v1/pool", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("pool: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var state api.PoolState
+	if err := json.NewDecoder(rec.Body).Decode(&state); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if state.Strategy != api.LeastLoaded || len(state.Accounts) != 1 {
+		t.Fatalf("unexpected pool state %+v", state)
+	}
+	account := state.Accounts[0]
+	if account.Name != "default" || account.ActiveSessions != 1 || !account.LoggedIn || !account.Available {
+		t.Fatalf("unexpected account %+v", account)
+	}
+}
+
+func TestAdminLogoutContinuesAfterFailure(t *testing.T) {
+	backend := hsmtest.New(t)
+	var members []services.PoolMember
+	for _, name := range []string{"a", "b"} {
+		sessionPath := filepath.Join(t.TempDir(), "session.json")
+		backend.WriteSessionFile(t, sessionPath)
+		sessionService, err := services.NewSessionService(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath))
+		if err != nil {
+			t.Fatalf("NewSessionService: %v", err)
+		}
+		t.Cleanup(sessionService.Close)
+		members = append(members, services.PoolMember{Name: name, Service: sessionService})
+	}
+	pool, err := services.NewAccountPool(services.StrategyLeastLoaded, members...)
+	if err != nil {
+		t.Fatalf("NewAccountPool: %v", err)
+	}
+	server := handlers.NewServer("test", pool.Primary(), services.NewDownloadService(backend.Client()),
+		handlers.WithUserSessionService(services.NewUserSessionService(pool)))
+	h := api.Handler(server)
+
+	for _, subject := range []string{"alice", "bob"} {
+		if rec := do(t, h, http.MethodPost, "/api/v1/session", subject); rec.Code != http.StatusOK {
+			t.Fatalf("create: expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+	}
+
+	// Revoking the tokens of the first account fails
+	backend.FailNext(hsmtest.RouteRevoke, http.StatusBadRequest, `{"error":"invalid_request"}`)
+	if rec := do(t, h, http.MethodPost, "/admin/v1/logout", ""); rec.Code == http.StatusOK {
+		t.Fatal("expected the failed account to be reported")
+	}
+
+	if !members[0].Service.LoggedIn() || members[1].Service.LoggedIn() {
+		t.Fatalf("expected a to stay and b to be logged out, got a=%v b=%v", members[0].Service.LoggedIn(), members[1].Service.LoggedIn())
+	}
+	for _, state := range pool.State() {
+		if state.ActiveSessions != 0 {
+			t.Fatalf("expected the tracked sessions to be reset, %s has %d", state.Name, state.ActiveSessions)
+		}
+	}
+	if rec := do(t, h, http.MethodGet, "/api/v1/session", "bob"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected bob's terminated session to be forgotten, got %d", rec.Code)
+	}
+}
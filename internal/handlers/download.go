@@ -16,9 +16,13 @@ func (s *Server) GetDownloadURL(w http.ResponseWriter, r *http.Request, params a
 		patchline = *params.Patchline
 	}
 
-	url, version, err := s.downloadService.GetDownloadURL(patchline)
+	if !s.requireLogin(w, "get download URL", false) {
+		return
+	}
+
+	url, version, err := s.downloadService.GetDownloadURL(r.Context(), patchline)
 	if err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		writeError(w, "get download URL", err)
 		return
 	}
 
@@ -33,9 +37,13 @@ func (s *Server) GetDownloadURLPlain(w http.ResponseWriter, r *http.Request, par
 		patchline = *params.Patchline
 	}
 
-	url, _, err := s.downloadService.GetDownloadURL(patchline)
+	if !s.requireLogin(w, "get download URL", true) {
+		return
+	}
+
+	url, _, err := s.downloadService.GetDownloadURL(r.Context(), patchline)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writePlainError(w, "get download URL", err)
 		return
 	}
 
@@ -52,9 +60,13 @@ func (s *Server) GetVersionPlain(w http.ResponseWriter, r *http.Request, params
 		patchline = *params.Patchline
 	}
 
-	_, version, err := s.downloadService.GetDownloadURL(patchline)
+	if !s.requireLogin(w, "get version", true) {
+		return
+	}
+
+	_, version, err := s.downloadService.GetDownloadURL(r.Context(), patchline)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writePlainError(w, "get version", err)
 		return
 	}
 
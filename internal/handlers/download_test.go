@@ -0,0 +1,38 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"hsm/api"
+	"hsm/internal/hsmtest"
+)
+
+func TestDownloadEndpoints(t *testing.T) {
+	backend := hsmtest.New(t)
+	backend.SetVersion("release", "2026.03.04-test")
+	h := newTestHandler(t, backend, false)
+
+	rec := do(t, h, http.MethodGet, "/api/v1/download", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("download: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var download api.DownloadResponse
+	if err := json.NewDecoder(rec.Body).Decode(&download); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if download.Version != "2026.03.04-test" || download.Url == "" {
+		t.Fatalf("unexpected download response %+v", download)
+	}
+
+	rec = do(t, h, http.MethodGet, "/version", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "2026.03.04-test" {
+		t.Fatalf("version: got %d %q", rec.Code, rec.Body)
+	}
+
+	rec = do(t, h, http.MethodGet, "/download?patchline=prerelease", "")
+	if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+		t.Fatalf("download plain: got %d %q", rec.Code, rec.Body)
+	}
+}
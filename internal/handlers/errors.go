@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"hsm/api"
+	"hsm/internal/client"
+	"hsm/internal/services"
+	"hsm/internal/utils"
+)
+
+// classifyError maps an upstream error to the HTTP status and error code returned to callers
+func classifyError(err error) (int, api.ErrorResponseCode) {
+	switch {
+	case errors.Is(err, services.ErrProfileNotFound):
+		return http.StatusBadRequest, api.InvalidProfile
+	case errors.Is(err, services.ErrUnknownAccount):
+		return http.StatusBadRequest, api.UnknownAccount
+	case errors.Is(err, services.ErrInvalidInstance):
+		return http.StatusBadRequest, api.InvalidInstance
+	case errors.Is(err, services.ErrSubjectBlocked):
+		return http.StatusForbidden, api.SubjectBlocked
+	case errors.Is(err, services.ErrInstanceLimit):
+		return http.StatusTooManyRequests, api.InstanceLimitReached
+	case errors.Is(err, services.ErrNotLinked):
+		return http.StatusNotFound, api.NotLinked
+	case errors.Is(err, services.ErrNotLoggedIn):
+		return http.StatusServiceUnavailable, api.NotLoggedIn
+	case errors.Is(err, client.ErrUnauthorized):
+		return http.StatusUnauthorized, api.UpstreamUnauthorized
+	case errors.Is(err, client.ErrNotFound):
+		return http.StatusNotFound, api.NotFound
+	case errors.Is(err, client.ErrSessionLimitReached):
+		return http.StatusTooManyRequests, api.SessionLimitReached
+	case errors.Is(err, client.ErrRateLimited):
+		return http.StatusTooManyRequests, api.RateLimited
+	case errors.Is(err, client.ErrUpstreamUnavailable):
+		return http.StatusServiceUnavailable, api.UpstreamUnavailable
+	case errors.Is(err, client.ErrForbidden):
+		return http.StatusBadGateway, api.UpstreamForbidden
+	}
+
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		return http.StatusBadGateway, api.UpstreamError
+	}
+	return http.StatusInternalServerError, api.InternalError
+}
+
+// setRetryAfter forwards the upstream Retry-After hint to the caller
+func setRetryAfter(w http.ResponseWriter, err error) {
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(apiErr.RetryAfter.Seconds()))))
+	}
+}
+
+// writeError logs err and writes it as a JSON ErrorResponse with a matching status
+func writeError(w http.ResponseWriter, action string, err error) {
+	status, code := classifyError(err)
+	log.Printf("failed to %s: %v", action, err)
+	setRetryAfter(w, err)
+	utils.WriteJSON(w, status, api.ErrorResponse{Error: err.Error(), Code: &code})
+}
+
+// writePlainError logs err and writes it as plain text with a matching status
+func writePlainError(w http.ResponseWriter, action string, err error) {
+	status, _ := classifyError(err)
+	log.Printf("failed to %s: %v", action, err)
+	setRetryAfter(w, err)
+	http.Error(w, err.Error(), status)
+}
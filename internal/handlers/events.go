@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"hsm/api"
+	"hsm/internal/middleware"
+	"hsm/internal/services"
+	"hsm/internal/utils"
+)
+
+// keepAliveInterval is how often an idle event stream sends a comment, so
+// proxies keep the connection open
+const keepAliveInterval = 30 * time.Second
+
+// StreamEvents streams session and version events as Server-Sent Events
+// (GET /api/v1/events)
+func (s *Server) StreamEvents(w http.ResponseWriter, r *http.Request, params api.StreamEventsParams) {
+	if s.broker == nil {
+		utils.WriteJSON(w, http.StatusNotImplemented, api.ErrorResponse{Error: "event streaming is not enabled"})
+		return
+	}
+
+	// Single-user mode streams the events of all sessions
+	subject := ""
+	if s.isMultiUser() {
+		var ok bool
+		subject, ok = middleware.GetSubjectFromContext(r.Context())
+		if !ok {
+			utils.WriteJSON(w, http.StatusUnauthorized, api.ErrorResponse{Error: "unauthorized"})
+			return
+		}
+	}
+
+	lastID := ""
+	if params.LastEventID != nil {
+		lastID = *params.LastEventID
+	}
+	missed, events, cancel := s.broker.Subscribe(subject, lastID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	var err error
+	for _, event := range missed {
+		if err = writeStreamEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err = rc.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				// The stream fell behind, the client resumes with Last-Event-ID
+				return
+			}
+			err = writeStreamEvent(w, event)
+		case <-ticker.C:
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// writeStreamEvent writes event in the text/event-stream format
+func writeStreamEvent(w io.Writer, event services.StreamEvent) error {
+	data, err := json.Marshal(event.Event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
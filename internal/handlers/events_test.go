@@ -0,0 +1,146 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hsm/api"
+	"hsm/internal/handlers"
+	"hsm/internal/hsmtest"
+	"hsm/internal/middleware"
+	"hsm/internal/services"
+	"hsm/internal/sessionstore"
+)
+
+// newEventServer serves the API in multi-user mode with an event stream. The
+// subject is taken from the X-Subject header.
+func newEventServer(t *testing.T, backend *hsmtest.Backend) (*httptest.Server, *services.UserSessionService) {
+	t.Helper()
+	sessionPath := filepath.Join(t.TempDir(), "session.json")
+	backend.WriteSessionFile(t, sessionPath)
+	sessionService, err := services.NewSessionService(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath))
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+	t.Cleanup(sessionService.Close)
+
+	broker := services.NewEventBroker(0)
+	userSessions := services.NewUserSessionService(services.NewSingleAccountPool(sessionService), services.WithEventSink(broker))
+	server := handlers.NewServer("test", sessionService, services.NewDownloadService(backend.Client()),
+		handlers.WithUserSessionService(userSessions), handlers.WithEventBroker(broker))
+	h := api.Handler(server)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.SubjectContextKey, r.Header.Get("X-Subject"))
+		h.ServeHTTP(w, r.WithContext(ctx))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, userSessions
+}
+
+type streamedEvent struct {
+	id, event string
+	data      services.Event
+}
+
+// openStream connects to the event stream and returns its events
+func openStream(t *testing.T, url, subject, lastID string) <-chan streamedEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(t.Context())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url+"/api/v1/events", nil)
+	req.Header.Set("X-Subject", subject)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	events := make(chan streamedEvent, 16)
+	go func() {
+		defer func() { _ = resp.Body.Close() }()
+		scanner := bufio.NewScanner(resp.Body)
+		var event streamedEvent
+		for scanner.Scan() {
+			field, value, _ := strings.Cut(scanner.Text(), ": ")
+			switch field {
+			case "id":
+				event.id = value
+			case "event":
+				event.event = value
+			case "data":
+				_ = json.Unmarshal([]byte(value), &event.data)
+			case "":
+				events <- event
+				event = streamedEvent{}
+			}
+		}
+	}()
+	return events
+}
+
+func next(t *testing.T, events <-chan streamedEvent) streamedEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+		return streamedEvent{}
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	backend := hsmtest.New(t)
+	srv, userSessions := newEventServer(t, backend)
+	alice := openStream(t, srv.URL, "alice", "")
+	bob := openStream(t, srv.URL, "bob", "")
+
+	key := services.SessionKey{Subject: "alice"}
+	if _, err := userSessions.GetOrCreateSession(t.Context(), key, "", ""); err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	if err := userSessions.DeleteSession(t.Context(), key); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+
+	created := next(t, alice)
+	if created.event != string(services.EventSessionCreated) || created.data.Subject != "alice" || created.id == "" {
+		t.Fatalf("unexpected event %+v", created)
+	}
+	if terminated := next(t, alice); terminated.event != string(services.EventSessionTerminated) {
+		t.Fatalf("expected the termination, got %+v", terminated)
+	}
+	select {
+	case event := <-bob:
+		t.Fatalf("expected no events for bob, got %+v", event)
+	default:
+	}
+
+	// A reconnecting client receives the events it missed
+	resumed := openStream(t, srv.URL, "alice", created.id)
+	if event := next(t, resumed); event.event != string(services.EventSessionTerminated) {
+		t.Fatalf("expected the missed termination, got %+v", event)
+	}
+}
+
+func TestStreamEventsDisabled(t *testing.T) {
+	backend := hsmtest.New(t)
+	h := newTestHandler(t, backend, false)
+
+	if rec := do(t, h, http.MethodGet, "/api/v1/events", ""); rec.Code != http.StatusNotImplemented {
+		t.Fatalf("expected 501 without a broker, got %d", rec.Code)
+	}
+}
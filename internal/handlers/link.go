@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+
+	"hsm/api"
+	"hsm/internal/middleware"
+	"hsm/internal/services"
+	"hsm/internal/utils"
+)
+
+// GetLink returns whether the subject linked their own account
+// (GET /api/v1/link)
+func (s *Server) GetLink(w http.ResponseWriter, r *http.Request) {
+	subject, links, ok := s.linkSubject(w, r)
+	if !ok {
+		return
+	}
+
+	link, err := links.Status(r.Context(), subject)
+	if err != nil {
+		writeError(w, "get link", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, toAPILinkStatus(link))
+}
+
+// CreateLink starts a device flow that links the subject's own account
+// (POST /api/v1/link)
+func (s *Server) CreateLink(w http.ResponseWriter, r *http.Request) {
+	subject, links, ok := s.linkSubject(w, r)
+	if !ok {
+		return
+	}
+
+	link, err := links.Start(r.Context(), subject)
+	if err != nil {
+		writeError(w, "start link", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, toAPILinkStatus(link))
+}
+
+// DeleteLink unlinks the subject's own account
+// (DELETE /api/v1/link)
+func (s *Server) DeleteLink(w http.ResponseWriter, r *http.Request) {
+	subject, _, ok := s.linkSubject(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := s.userSessionService.Unlink(r.Context(), subject)
+	if err != nil {
+		writeError(w, "unlink", err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, api.LogoutResponse{
+		TerminatedGameSessions: result.TerminatedGameSessions,
+		Revoked:                result.Revoked,
+		Removed:                result.Removed,
+	})
+}
+
+// linkSubject returns the subject of the request and the link service, writing
+// an error response if linking is not available
+func (s *Server) linkSubject(w http.ResponseWriter, r *http.Request) (string, *services.LinkService, bool) {
+	if !s.isMultiUser() || s.userSessionService.Links() == nil {
+		utils.WriteJSON(w, http.StatusNotImplemented, api.ErrorResponse{Error: "account linking is not enabled"})
+		return "", nil, false
+	}
+
+	subject, ok := middleware.GetSubjectFromContext(r.Context())
+	if !ok {
+		utils.WriteJSON(w, http.StatusUnauthorized, api.ErrorResponse{Error: "unauthorized"})
+		return "", nil, false
+	}
+	return subject, s.userSessionService.Links(), true
+}
+
+// toAPILinkStatus converts a services.Link to api.LinkStatus
+func toAPILinkStatus(link services.Link) api.LinkStatus {
+	status := api.LinkStatus{Status: api.LinkStatusStatus(link.State)}
+	if link.UserCode != "" {
+		status.UserCode = &link.UserCode
+		status.VerificationUri = &link.VerificationURI
+	}
+	if link.VerificationURIComplete != "" {
+		status.VerificationUriComplete = &link.VerificationURIComplete
+	}
+	if !link.ExpiresAt.IsZero() {
+		status.ExpiresAt = &link.ExpiresAt
+	}
+	if link.Owner != "" {
+		status.Owner = &link.Owner
+	}
+	if link.Profile.Username != "" {
+		status.Profile = &link.Profile.Username
+	}
+	if link.Error != "" {
+		status.Error = &link.Error
+	}
+	return status
+}
@@ -0,0 +1,91 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hsm/api"
+	"hsm/internal/client"
+	"hsm/internal/handlers"
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+	"hsm/internal/sessionstore"
+)
+
+func TestLinkNotEnabled(t *testing.T) {
+	backend := hsmtest.New(t)
+	for _, multiUser := range []bool{false, true} {
+		h := newTestHandler(t, backend, multiUser)
+		if rec := do(t, h, http.MethodPost, "/api/v1/link", "alice"); rec.Code != http.StatusNotImplemented {
+			t.Fatalf("multiUser=%v: expected 501, got %d", multiUser, rec.Code)
+		}
+	}
+}
+
+func TestLinkLifecycle(t *testing.T) {
+	pool, own := hsmtest.New(t), hsmtest.New(t)
+	sessionPath := filepath.Join(t.TempDir(), "session.json")
+	pool.WriteSessionFile(t, sessionPath)
+	sessionService, err := services.NewSessionService(t.Context(), pool.Client(), sessionstore.NewFileStore(sessionPath))
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+	t.Cleanup(sessionService.Close)
+
+	dir := t.TempDir()
+	links := services.NewLinkService(func() *client.Client { return own.Client() }, func(subject string) (sessionstore.Store, error) {
+		return sessionstore.NewFileStore(filepath.Join(dir, subject+".json")), nil
+	})
+	t.Cleanup(links.Close)
+	userSessions := services.NewUserSessionService(services.NewSingleAccountPool(sessionService), services.WithLinks(links))
+	h := api.Handler(handlers.NewServer("test", sessionService, services.NewDownloadService(pool.Client()), handlers.WithUserSessionService(userSessions)))
+
+	decodeLink := func(body *json.Decoder) api.LinkStatus {
+		var status api.LinkStatus
+		if err := body.Decode(&status); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return status
+	}
+
+	rec := do(t, h, http.MethodPost, "/api/v1/link", "alice")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("link: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if status := decodeLink(json.NewDecoder(rec.Body)); status.Status != api.LinkStatusStatusPending || status.UserCode == nil {
+		t.Fatalf("unexpected link status %+v", status)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec = do(t, h, http.MethodGet, "/api/v1/link", "alice")
+		if status := decodeLink(json.NewDecoder(rec.Body)); status.Status == api.LinkStatusStatusLinked {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("link did not complete")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	rec = do(t, h, http.MethodPost, "/api/v1/session", "alice")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if session := decodeGameSession(t, rec); !own.HasGameSession(session.SessionToken) {
+		t.Fatal("expected the game session on the linked account")
+	}
+
+	if rec := do(t, h, http.MethodDelete, "/api/v1/link", "alice"); rec.Code != http.StatusOK {
+		t.Fatalf("unlink: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if own.GameSessionCount() != 0 {
+		t.Fatalf("expected the linked game session to be terminated, got %d", own.GameSessionCount())
+	}
+	if rec := do(t, h, http.MethodDelete, "/api/v1/link", "alice"); rec.Code != http.StatusNotFound {
+		t.Fatalf("unlink again: expected 404, got %d", rec.Code)
+	}
+}
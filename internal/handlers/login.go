@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"hsm/api"
+	"hsm/internal/services"
+	"hsm/internal/utils"
+)
+
+// accountLogin is the login state of a named account
+type accountLogin struct {
+	Name string
+	services.LoginStatus
+}
+
+// AdminGetLogin returns the login state of every account
+// (GET /admin/v1/login)
+func (s *Server) AdminGetLogin(w http.ResponseWriter, r *http.Request) {
+	response := api.LoginStatus{Accounts: []api.AccountLogin{}}
+	for _, login := range s.loginStatuses() {
+		account := api.AccountLogin{
+			Name:  login.Name,
+			State: api.AccountLoginState(login.State),
+		}
+		if login.UserCode != "" {
+			account.UserCode = &login.UserCode
+			account.VerificationUri = &login.VerificationURI
+		}
+		if login.VerificationURIComplete != "" {
+			account.VerificationUriComplete = &login.VerificationURIComplete
+		}
+		if !login.ExpiresAt.IsZero() {
+			account.ExpiresAt = &login.ExpiresAt
+		}
+		if login.Error != "" {
+			account.Error = &login.Error
+		}
+		response.Accounts = append(response.Accounts, account)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// loginStatuses returns the login state of every account of the pool
+func (s *Server) loginStatuses() []accountLogin {
+	var accounts []accountLogin
+	for _, member := range s.pool.Members() {
+		account := accountLogin{Name: member.Name}
+		switch login := s.logins[member.Name]; {
+		case login != nil:
+			account.LoginStatus = login.Status()
+		case member.Service.LoggedIn():
+			account.State = services.LoginLoggedIn
+		default:
+			account.State = services.LoginRequired
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts
+}
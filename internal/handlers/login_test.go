@@ -0,0 +1,48 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"hsm/api"
+	"hsm/internal/handlers"
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+	"hsm/internal/sessionstore"
+)
+
+func TestLoggedOutServer(t *testing.T) {
+	backend := hsmtest.New(t)
+	store := sessionstore.NewFileStore(filepath.Join(t.TempDir(), "session.json"))
+	sessionService, err := services.NewSessionService(t.Context(), backend.Client(), store, services.WithDeferredLogin())
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+	t.Cleanup(sessionService.Close)
+	h := api.Handler(handlers.NewServer("test", sessionService, services.NewDownloadService(sessionService.Client())))
+
+	for _, target := range []string{"/api/v1/session", "/game-session"} {
+		if rec := do(t, h, http.MethodPost, target, ""); rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("%s: expected 503, got %d: %s", target, rec.Code, rec.Body)
+		}
+	}
+	for _, target := range []string{"/api/v1/download", "/download", "/version"} {
+		if rec := do(t, h, http.MethodGet, target, ""); rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("%s: expected 503, got %d: %s", target, rec.Code, rec.Body)
+		}
+	}
+
+	rec := do(t, h, http.MethodGet, "/admin/v1/login", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var status api.LoginStatus
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(status.Accounts) != 1 || status.Accounts[0].State != api.AccountLoginStateNeedsLogin {
+		t.Fatalf("unexpected login status %+v", status)
+	}
+}
@@ -1,6 +1,10 @@
 package handlers
 
 import (
+	"net/http"
+
+	"hsm/internal/client"
+	"hsm/internal/middleware"
 	"hsm/internal/services"
 )
 
@@ -10,6 +14,12 @@ type Server struct {
 	sessionService     *services.SessionService
 	userSessionService *services.UserSessionService
 	downloadService    *services.DownloadService
+	pool               *services.AccountPool
+	accountClaim       string
+	instanceClaim      string
+	logins             map[string]*services.LoginService // pool account -> login
+	events             services.EventSinks
+	broker             *services.EventBroker
 }
 
 // ServerOption is a functional option for configuring the Server
@@ -22,6 +32,54 @@ func WithUserSessionService(userSessionService *services.UserSessionService) Ser
 	}
 }
 
+// WithAccountPool sets the accounts game sessions are spread across. By default
+// the pool of the UserSessionService is used, or in single-user mode a pool
+// holding only the session service passed to NewServer.
+func WithAccountPool(pool *services.AccountPool) ServerOption {
+	return func(s *Server) {
+		s.pool = pool
+	}
+}
+
+// WithAccountClaim sets the JWT claim naming the pool account of a subject
+func WithAccountClaim(claim string) ServerOption {
+	return func(s *Server) {
+		s.accountClaim = claim
+	}
+}
+
+// WithInstanceClaim sets the JWT claim naming the instance of a game session,
+// which takes precedence over the instance query parameter
+func WithInstanceClaim(claim string) ServerOption {
+	return func(s *Server) {
+		s.instanceClaim = claim
+	}
+}
+
+// WithLoginServices exposes the in-process logins of the pool accounts, keyed
+// by account name
+func WithLoginServices(logins map[string]*services.LoginService) ServerOption {
+	return func(s *Server) {
+		s.logins = logins
+	}
+}
+
+// WithEventSink publishes the lifecycle events of the game sessions created
+// in single-user mode to sink. In multi-user mode the UserSessionService
+// publishes them.
+func WithEventSink(sink services.EventSink) ServerOption {
+	return func(s *Server) {
+		s.events = append(s.events, sink)
+	}
+}
+
+// WithEventBroker enables the event stream, see StreamEvents
+func WithEventBroker(broker *services.EventBroker) ServerOption {
+	return func(s *Server) {
+		s.broker = broker
+	}
+}
+
 // NewServer creates a new Server that implements api.ServerInterface
 func NewServer(
 	version string,
@@ -38,6 +96,13 @@ func NewServer(
 	for _, opt := range opts {
 		opt(s)
 	}
+	switch {
+	case s.pool != nil:
+	case s.userSessionService != nil:
+		s.pool = s.userSessionService.Pool()
+	default:
+		s.pool = services.NewSingleAccountPool(sessionService)
+	}
 
 	return s
 }
@@ -45,3 +110,51 @@ func NewServer(
 func (s *Server) isMultiUser() bool {
 	return s.userSessionService != nil
 }
+
+// emit publishes an event of typ for a game session of the primary account in
+// single-user mode
+func (s *Server) emit(typ services.EventType, session *client.GameSession, err error) {
+	if s.isMultiUser() || len(s.events) == 0 {
+		return
+	}
+	account := s.pool.Members()[0].Name
+	s.events.Publish(services.NewEvent(typ, services.SessionKey{}, account, session, err))
+}
+
+// requireLogin writes a not_logged_in error unless the primary account is logged in
+func (s *Server) requireLogin(w http.ResponseWriter, action string, plain bool) bool {
+	if s.sessionService.LoggedIn() {
+		return true
+	}
+	if plain {
+		writePlainError(w, action, services.ErrNotLoggedIn)
+	} else {
+		writeError(w, action, services.ErrNotLoggedIn)
+	}
+	return false
+}
+
+// requestedAccount returns the pool account named by the caller's JWT, if any
+func (s *Server) requestedAccount(r *http.Request) string {
+	if s.accountClaim == "" {
+		return ""
+	}
+	account, _ := middleware.GetClaimFromContext(r.Context(), s.accountClaim)
+	return account
+}
+
+// sessionKey returns the key of the game session addressed by the request: the
+// JWT subject and the instance from the instance claim or parameter
+func (s *Server) sessionKey(r *http.Request, instance *string) (services.SessionKey, bool) {
+	subject, ok := middleware.GetSubjectFromContext(r.Context())
+	if !ok {
+		return services.SessionKey{}, false
+	}
+	key := services.SessionKey{Subject: subject}
+	if claimed, ok := middleware.GetClaimFromContext(r.Context(), s.instanceClaim); ok && s.instanceClaim != "" {
+		key.Instance = claimed
+	} else if instance != nil {
+		key.Instance = *instance
+	}
+	return key, true
+}
@@ -1,18 +1,41 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
 
 	"hsm/api"
 	"hsm/internal/client"
 	"hsm/internal/middleware"
+	"hsm/internal/services"
 	"hsm/internal/utils"
 )
 
 // GetSession returns the current session (multi-user only)
 // (GET /api/v1/session)
-func (s *Server) GetSession(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetSession(w http.ResponseWriter, r *http.Request, params api.GetSessionParams) {
+	if !s.isMultiUser() {
+		utils.WriteJSON(w, http.StatusNotImplemented, api.ErrorResponse{Error: "not available in single-user mode"})
+		return
+	}
+
+	key, ok := s.sessionKey(r, params.Instance)
+	if !ok {
+		utils.WriteJSON(w, http.StatusUnauthorized, api.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	session := s.userSessionService.GetSession(key)
+	if session == nil {
+		utils.WriteJSON(w, http.StatusNotFound, api.ErrorResponse{Error: "no session"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, s.toAPIUserGameSession(key, session))
+}
+
+// ListSessions returns the sessions of all instances of the user (multi-user only)
+// (GET /api/v1/sessions)
+func (s *Server) ListSessions(w http.ResponseWriter, r *http.Request) {
 	if !s.isMultiUser() {
 		utils.WriteJSON(w, http.StatusNotImplemented, api.ErrorResponse{Error: "not available in single-user mode"})
 		return
@@ -24,53 +47,83 @@ func (s *Server) GetSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session := s.userSessionService.GetSession(subject)
+	result := api.SessionList{Sessions: []api.GameSession{}}
+	for _, instance := range s.userSessionService.ListSessions(subject) {
+		key := services.SessionKey{Subject: subject, Instance: instance.Instance}
+		result.Sessions = append(result.Sessions, s.toAPIUserGameSession(key, instance.Session))
+	}
+	utils.WriteJSON(w, http.StatusOK, result)
+}
+
+// HeartbeatSession renews the lease of the current session (multi-user only)
+// (POST /api/v1/session/heartbeat)
+func (s *Server) HeartbeatSession(w http.ResponseWriter, r *http.Request, params api.HeartbeatSessionParams) {
+	if !s.isMultiUser() {
+		utils.WriteJSON(w, http.StatusNotImplemented, api.ErrorResponse{Error: "not available in single-user mode"})
+		return
+	}
+
+	key, ok := s.sessionKey(r, params.Instance)
+	if !ok {
+		utils.WriteJSON(w, http.StatusUnauthorized, api.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	session := s.userSessionService.Heartbeat(key)
 	if session == nil {
 		utils.WriteJSON(w, http.StatusNotFound, api.ErrorResponse{Error: "no session"})
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, toAPIGameSession(session))
+	utils.WriteJSON(w, http.StatusOK, s.toAPIUserGameSession(key, session))
 }
 
 // CreateSession creates a new session
 // (POST /api/v1/session)
-func (s *Server) CreateSession(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreateSession(w http.ResponseWriter, r *http.Request, params api.CreateSessionParams) {
 	var session *client.GameSession
 	var err error
+	var key services.SessionKey
+
+	profile := ""
+	if params.Profile != nil {
+		profile = *params.Profile
+	}
 
 	if s.isMultiUser() {
-		subject, ok := middleware.GetSubjectFromContext(r.Context())
+		var ok bool
+		key, ok = s.sessionKey(r, params.Instance)
 		if !ok {
 			utils.WriteJSON(w, http.StatusUnauthorized, api.ErrorResponse{Error: "unauthorized"})
 			return
 		}
-		session, err = s.userSessionService.GetOrCreateSession(subject)
+		session, err = s.userSessionService.GetOrCreateSession(r.Context(), key, profile, s.requestedAccount(r))
 	} else {
-		session, err = s.sessionService.CreateGameSession()
+		session, err = s.sessionService.CreateGameSessionForProfile(r.Context(), profile)
+		if err == nil {
+			s.emit(services.EventSessionCreated, session, nil)
+		}
 	}
 
 	if err != nil {
-		log.Printf("failed to create game session: %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		writeError(w, "create game session", err)
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, toAPIGameSession(session))
+	utils.WriteJSON(w, http.StatusOK, s.toAPIUserGameSession(key, session))
 }
 
 // DeleteSession deletes a session
 // (DELETE /api/v1/session)
 func (s *Server) DeleteSession(w http.ResponseWriter, r *http.Request, params api.DeleteSessionParams) {
 	if s.isMultiUser() {
-		subject, ok := middleware.GetSubjectFromContext(r.Context())
+		key, ok := s.sessionKey(r, params.Instance)
 		if !ok {
 			utils.WriteJSON(w, http.StatusUnauthorized, api.ErrorResponse{Error: "unauthorized"})
 			return
 		}
-		if err := s.userSessionService.DeleteSession(subject); err != nil {
-			log.Printf("failed to delete game session: %v", err)
-			utils.WriteJSON(w, http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		if err := s.userSessionService.DeleteSession(r.Context(), key); err != nil {
+			writeError(w, "delete game session", err)
 			return
 		}
 	} else {
@@ -78,11 +131,11 @@ func (s *Server) DeleteSession(w http.ResponseWriter, r *http.Request, params ap
 			utils.WriteJSON(w, http.StatusBadRequest, api.ErrorResponse{Error: "token required"})
 			return
 		}
-		if err := s.sessionService.DeleteGameSession(*params.Token); err != nil {
-			log.Printf("failed to delete game session: %v", err)
-			utils.WriteJSON(w, http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		if err := s.sessionService.DeleteGameSession(r.Context(), *params.Token); err != nil {
+			writeError(w, "delete game session", err)
 			return
 		}
+		s.emit(services.EventSessionTerminated, nil, nil)
 	}
 
 	utils.WriteJSON(w, http.StatusOK, api.MessageResponse{Message: "deleted"})
@@ -93,25 +146,31 @@ func (s *Server) DeleteSession(w http.ResponseWriter, r *http.Request, params ap
 func (s *Server) RefreshSession(w http.ResponseWriter, r *http.Request, params api.RefreshSessionParams) {
 	var session *client.GameSession
 	var err error
+	var key services.SessionKey
 
 	if s.isMultiUser() {
-		subject, ok := middleware.GetSubjectFromContext(r.Context())
+		var ok bool
+		key, ok = s.sessionKey(r, params.Instance)
 		if !ok {
 			utils.WriteJSON(w, http.StatusUnauthorized, api.ErrorResponse{Error: "unauthorized"})
 			return
 		}
-		session, err = s.userSessionService.RefreshSession(subject)
+		session, err = s.userSessionService.RefreshSession(r.Context(), key)
 	} else {
 		if params.Token == nil || *params.Token == "" {
 			utils.WriteJSON(w, http.StatusBadRequest, api.ErrorResponse{Error: "token required"})
 			return
 		}
-		session, err = s.sessionService.RefreshGameSession(*params.Token)
+		session, err = s.sessionService.RefreshGameSession(r.Context(), *params.Token)
+		if err != nil {
+			s.emit(services.EventSessionRefreshFailed, nil, err)
+		} else if session != nil {
+			s.emit(services.EventSessionRefreshed, session, nil)
+		}
 	}
 
 	if err != nil {
-		log.Printf("failed to refresh game session: %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		writeError(w, "refresh game session", err)
 		return
 	}
 
@@ -120,29 +179,36 @@ func (s *Server) RefreshSession(w http.ResponseWriter, r *http.Request, params a
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, toAPIGameSession(session))
+	utils.WriteJSON(w, http.StatusOK, s.toAPIUserGameSession(key, session))
 }
 
 // CreateGameSessionEnv creates a session and returns it in env format
 // (POST /game-session)
-func (s *Server) CreateGameSessionEnv(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreateGameSessionEnv(w http.ResponseWriter, r *http.Request, params api.CreateGameSessionEnvParams) {
 	var session *client.GameSession
 	var err error
 
+	profile := ""
+	if params.Profile != nil {
+		profile = *params.Profile
+	}
+
 	if s.isMultiUser() {
-		subject, ok := middleware.GetSubjectFromContext(r.Context())
+		key, ok := s.sessionKey(r, params.Instance)
 		if !ok {
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
-		session, err = s.userSessionService.GetOrCreateSession(subject)
+		session, err = s.userSessionService.GetOrCreateSession(r.Context(), key, profile, s.requestedAccount(r))
 	} else {
-		session, err = s.sessionService.CreateGameSession()
+		session, err = s.sessionService.CreateGameSessionForProfile(r.Context(), profile)
+		if err == nil {
+			s.emit(services.EventSessionCreated, session, nil)
+		}
 	}
 
 	if err != nil {
-		log.Printf("failed to create game session: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writePlainError(w, "create game session", err)
 		return
 	}
 
@@ -152,6 +218,27 @@ func (s *Server) CreateGameSessionEnv(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte("HYTALE_SERVER_IDENTITY_TOKEN=\"" + session.IdentityToken + "\"\n"))
 }
 
+// toAPIUserGameSession converts the game session of key to api.GameSession,
+// including its instance and its lease when leases are enabled
+func (s *Server) toAPIUserGameSession(key services.SessionKey, session *client.GameSession) api.GameSession {
+	result := toAPIGameSession(session)
+	if !s.isMultiUser() {
+		return result
+	}
+	if key.Instance != "" {
+		result.Instance = &key.Instance
+	}
+	if s.userSessionService.LeaseTTL() <= 0 {
+		return result
+	}
+	ttl := int(s.userSessionService.LeaseTTL().Seconds())
+	result.LeaseTtl = &ttl
+	if lease := s.userSessionService.LeaseExpiresAt(key); !lease.IsZero() {
+		result.LeaseExpiresAt = &lease
+	}
+	return result
+}
+
 // toAPIGameSession converts a client.GameSession to api.GameSession
 func toAPIGameSession(session *client.GameSession) api.GameSession {
 	return api.GameSession{
@@ -0,0 +1,300 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hsm/api"
+	"hsm/internal/handlers"
+	"hsm/internal/hsmtest"
+	"hsm/internal/middleware"
+	"hsm/internal/services"
+	"hsm/internal/sessionstore"
+)
+
+// newTestHandler wires the generated router to services backed by the fake upstream
+func newTestHandler(t *testing.T, backend *hsmtest.Backend, multiUser bool, userOpts ...services.UserSessionOption) http.Handler {
+	t.Helper()
+	sessionPath := filepath.Join(t.TempDir(), "session.json")
+	backend.WriteSessionFile(t, sessionPath)
+
+	c := backend.Client()
+	sessionService, err := services.NewSessionService(t.Context(), c, sessionstore.NewFileStore(sessionPath))
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+	t.Cleanup(sessionService.Close)
+
+	var opts []handlers.ServerOption
+	if multiUser {
+		opts = append(opts, handlers.WithUserSessionService(services.NewUserSessionService(services.NewSingleAccountPool(sessionService), userOpts...)))
+	}
+	server := handlers.NewServer("test", sessionService, services.NewDownloadService(c), opts...)
+	return api.Handler(server)
+}
+
+func do(t *testing.T, h http.Handler, method, target, subject string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	if subject != "" {
+		req = req.WithContext(context.WithValue(req.Context(), middleware.SubjectContextKey, subject))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeGameSession(t *testing.T, rec *httptest.ResponseRecorder) api.GameSession {
+	t.Helper()
+	var session api.GameSession
+	if err := json.NewDecoder(rec.Body).Decode(&session); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return session
+}
+
+func TestSingleUserSessionLifecycle(t *testing.T) {
+	backend := hsmtest.New(t)
+	h := newTestHandler(t, backend, false)
+
+	rec := do(t, h, http.MethodPost, "/api/v1/session", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	session := decodeGameSession(t, rec)
+
+	rec = do(t, h, http.MethodPost, "/api/v1/session/refresh?token="+session.SessionToken, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("refresh: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = do(t, h, http.MethodDelete, "/api/v1/session", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("delete without token: expected 400, got %d", rec.Code)
+	}
+
+	rec = do(t, h, http.MethodDelete, "/api/v1/session?token="+session.SessionToken, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if backend.HasGameSession(session.SessionToken) {
+		t.Fatal("expected upstream session to be terminated")
+	}
+
+	rec = do(t, h, http.MethodGet, "/api/v1/session", "")
+	if rec.Code != http.StatusNotImplemented {
+		t.Fatalf("get: expected 501 in single-user mode, got %d", rec.Code)
+	}
+}
+
+func TestMultiUserSessionLifecycle(t *testing.T) {
+	backend := hsmtest.New(t)
+	h := newTestHandler(t, backend, true)
+
+	if rec := do(t, h, http.MethodPost, "/api/v1/session", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("create without subject: expected 401, got %d", rec.Code)
+	}
+
+	rec := do(t, h, http.MethodPost, "/api/v1/session", "alice")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	created := decodeGameSession(t, rec)
+
+	rec = do(t, h, http.MethodGet, "/api/v1/session", "alice")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get: expected 200, got %d", rec.Code)
+	}
+	if got := decodeGameSession(t, rec); got.SessionToken != created.SessionToken {
+		t.Fatal("expected get to return the created session")
+	}
+
+	if rec := do(t, h, http.MethodGet, "/api/v1/session", "bob"); rec.Code != http.StatusNotFound {
+		t.Fatalf("get for other subject: expected 404, got %d", rec.Code)
+	}
+
+	if rec := do(t, h, http.MethodDelete, "/api/v1/session", "alice"); rec.Code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodPost, "/api/v1/session/refresh", "alice"); rec.Code != http.StatusNotFound {
+		t.Fatalf("refresh after delete: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestCreateGameSessionEnv(t *testing.T) {
+	backend := hsmtest.New(t)
+	h := newTestHandler(t, backend, false)
+
+	rec := do(t, h, http.MethodPost, "/game-session", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "HYTALE_SERVER_SESSION_TOKEN=") || !strings.Contains(body, "HYTALE_SERVER_IDENTITY_TOKEN=") {
+		t.Fatalf("unexpected env body: %s", body)
+	}
+}
+
+func TestCreateSessionUpstreamFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantCode int
+		wantErr  api.ErrorResponseCode
+	}{
+		{"unauthorized", http.StatusUnauthorized, `{"code":"unauthorized"}`, http.StatusUnauthorized, api.UpstreamUnauthorized},
+		{"not found", http.StatusNotFound, `{"code":"profile_not_found"}`, http.StatusNotFound, api.NotFound},
+		{"rate limited", http.StatusTooManyRequests, `{}`, http.StatusTooManyRequests, api.RateLimited},
+		{"session limit", http.StatusForbidden, `{"code":"session_limit_reached"}`, http.StatusTooManyRequests, api.SessionLimitReached},
+		{"unavailable", http.StatusBadGateway, `{"code":"bad_gateway"}`, http.StatusServiceUnavailable, api.UpstreamUnavailable},
+		{"other", http.StatusConflict, `{"code":"conflict"}`, http.StatusBadGateway, api.UpstreamError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := hsmtest.New(t)
+			h := newTestHandler(t, backend, false)
+
+			backend.FailNext(hsmtest.RouteGameSessionNew, tt.status, tt.body)
+			rec := do(t, h, http.MethodPost, "/api/v1/session", "")
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, rec.Code, rec.Body)
+			}
+
+			var resp api.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Code == nil || *resp.Code != tt.wantErr {
+				t.Fatalf("expected code %q, got %v", tt.wantErr, resp.Code)
+			}
+		})
+	}
+}
+
+func TestCreateSessionCancelledByClient(t *testing.T) {
+	backend := hsmtest.New(t)
+	h := newTestHandler(t, backend, false)
+	backend.SetLatency(10 * time.Second)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/session", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	start := time.Now()
+	h.ServeHTTP(rec, req)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected upstream call to be cancelled with the request, took %v", elapsed)
+	}
+	if rec.Code == http.StatusOK {
+		t.Fatal("expected cancelled request to fail")
+	}
+}
+
+func TestCreateSessionUnknownProfile(t *testing.T) {
+	backend := hsmtest.New(t)
+	h := newTestHandler(t, backend, false)
+
+	rec := do(t, h, http.MethodPost, "/api/v1/session?profile=nobody", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = do(t, h, http.MethodPost, "/api/v1/session?profile="+hsmtest.DefaultProfile.Username, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a known profile, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestHeartbeatSession(t *testing.T) {
+	backend := hsmtest.New(t)
+	h := newTestHandler(t, backend, true, services.WithLeaseTTL(time.Minute))
+
+	if rec := do(t, h, http.MethodPost, "/api/v1/session/heartbeat", "alice"); rec.Code != http.StatusNotFound {
+		t.Fatalf("heartbeat without session: expected 404, got %d", rec.Code)
+	}
+
+	rec := do(t, h, http.MethodPost, "/api/v1/session", "alice")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	created := decodeGameSession(t, rec)
+	if created.LeaseTtl == nil || *created.LeaseTtl != 60 || created.LeaseExpiresAt == nil {
+		t.Fatalf("expected the lease in the response, got %+v", created)
+	}
+
+	rec = do(t, h, http.MethodPost, "/api/v1/session/heartbeat", "alice")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("heartbeat: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	renewed := decodeGameSession(t, rec)
+	if renewed.SessionToken != created.SessionToken || renewed.LeaseExpiresAt == nil || renewed.LeaseExpiresAt.Before(*created.LeaseExpiresAt) {
+		t.Fatalf("expected the lease to be renewed, got %+v", renewed)
+	}
+}
+
+func TestHeartbeatSessionSingleUser(t *testing.T) {
+	backend := hsmtest.New(t)
+	h := newTestHandler(t, backend, false)
+
+	if rec := do(t, h, http.MethodPost, "/api/v1/session/heartbeat", ""); rec.Code != http.StatusNotImplemented {
+		t.Fatalf("expected 501 in single-user mode, got %d", rec.Code)
+	}
+}
+
+func TestMultiUserSessionInstances(t *testing.T) {
+	backend := hsmtest.New(t)
+	h := newTestHandler(t, backend, true)
+
+	rec := do(t, h, http.MethodPost, "/api/v1/session", "alice")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create default: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if session := decodeGameSession(t, rec); session.Instance != nil {
+		t.Fatalf("expected no instance on the default session, got %q", *session.Instance)
+	}
+
+	rec = do(t, h, http.MethodPost, "/api/v1/session?instance=lobby", "alice")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create lobby: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	lobby := decodeGameSession(t, rec)
+	if lobby.Instance == nil || *lobby.Instance != "lobby" {
+		t.Fatalf("expected instance lobby, got %+v", lobby)
+	}
+
+	rec = do(t, h, http.MethodGet, "/api/v1/sessions", "alice")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var list api.SessionList
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(list.Sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(list.Sessions))
+	}
+
+	if rec := do(t, h, http.MethodDelete, "/api/v1/session?instance=lobby", "alice"); rec.Code != http.StatusOK {
+		t.Fatalf("delete lobby: expected 200, got %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodGet, "/api/v1/session?instance=lobby", "alice"); rec.Code != http.StatusNotFound {
+		t.Fatalf("get lobby after delete: expected 404, got %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodGet, "/api/v1/session", "alice"); rec.Code != http.StatusOK {
+		t.Fatalf("get default: expected 200, got %d", rec.Code)
+	}
+
+	rec = do(t, h, http.MethodPost, "/api/v1/session?instance="+strings.Repeat("x", services.MaxInstanceLength+1), "alice")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("create with long instance: expected 400, got %d", rec.Code)
+	}
+}
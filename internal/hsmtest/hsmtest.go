@@ -0,0 +1,558 @@
+// Package hsmtest provides an in-process fake of the upstream Hytale services
+// (OAuth, account-data, game-assets and sessions) for end-to-end tests.
+package hsmtest
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"hsm/internal/client"
+	"hsm/internal/sessionstore"
+	"hsm/internal/utils"
+)
+
+// Routes served by the fake backend. They are used as keys for error
+// injection and call counting.
+const (
+	RouteDeviceAuth         = "POST /oauth2/device/auth"
+	RouteToken              = "POST /oauth2/token"
+	RouteRevoke             = "POST /oauth2/revoke"
+	RouteProfiles           = "GET /my-account/get-profiles"
+	RouteGameSessionNew     = "POST /game-session/new"
+	RouteGameSessionRefresh = "POST /game-session/refresh"
+	RouteGameSessionDelete  = "DELETE /game-session"
+	RouteGameAssets         = "GET /game-assets/{file...}"
+	RouteCDN                = "GET /cdn/{file...}"
+)
+
+// DefaultProfile is the profile every fake account owns unless overridden
+var DefaultProfile = client.Profile{
+	UUID:     "00000000-0000-0000-0000-000000000001",
+	Username: "hsmtest",
+}
+
+// Failure is an injected error response
+type Failure struct {
+	Status int
+	Body   string
+	Header http.Header
+}
+
+// Backend is a fake Hytale backend backed by an httptest.Server
+type Backend struct {
+	Server *httptest.Server
+
+	mu                    sync.Mutex
+	latency               time.Duration
+	accessTokenTTL        time.Duration
+	gameSessionTTL        time.Duration
+	pendingAuthorizations int
+	owner                 string
+	profiles              []client.Profile
+	versions              map[string]string
+
+	failures      map[string][]Failure
+	calls         map[string]int
+	accessTokens  map[string]time.Time
+	refreshTokens map[string]bool
+	deviceCodes   map[string]int
+	gameSessions  map[string]*client.GameSession
+}
+
+// New starts a fake backend and stops it when the test finishes
+func New(t testing.TB) *Backend {
+	t.Helper()
+
+	b := &Backend{
+		accessTokenTTL: time.Hour,
+		gameSessionTTL: time.Hour,
+		owner:          "owner-" + DefaultProfile.UUID,
+		profiles:       []client.Profile{DefaultProfile},
+		versions:       map[string]string{"release": "2026.01.01-release", "prerelease": "2026.01.02-prerelease"},
+		failures:       make(map[string][]Failure),
+		calls:          make(map[string]int),
+		accessTokens:   make(map[string]time.Time),
+		refreshTokens:  make(map[string]bool),
+		deviceCodes:    make(map[string]int),
+		gameSessions:   make(map[string]*client.GameSession),
+	}
+
+	mux := http.NewServeMux()
+	b.handle(mux, RouteDeviceAuth, b.deviceAuth)
+	b.handle(mux, RouteToken, b.token)
+	b.handle(mux, RouteRevoke, b.revoke)
+	b.handle(mux, RouteProfiles, b.getProfiles)
+	b.handle(mux, RouteGameSessionNew, b.newGameSession)
+	b.handle(mux, RouteGameSessionRefresh, b.refreshGameSession)
+	b.handle(mux, RouteGameSessionDelete, b.deleteGameSession)
+	b.handle(mux, RouteGameAssets, b.gameAssets)
+	b.handle(mux, RouteCDN, b.cdn)
+
+	b.Server = httptest.NewServer(mux)
+	t.Cleanup(b.Server.Close)
+
+	return b
+}
+
+// URL returns the base URL of the fake backend
+func (b *Backend) URL() string {
+	return b.Server.URL
+}
+
+// Endpoints returns client endpoints that all point at the fake backend
+func (b *Backend) Endpoints() client.Endpoints {
+	return client.Endpoints{
+		OAuth:       b.URL(),
+		AccountData: b.URL(),
+		GameAssets:  b.URL() + "/game-assets",
+		Sessions:    b.URL(),
+	}
+}
+
+// Client returns a new API client pointed at the fake backend. Retries are
+// disabled so injected failures surface directly; pass client.WithRetryPolicy
+// to enable them.
+func (b *Backend) Client(opts ...client.Option) *client.Client {
+	opts = append([]client.Option{
+		client.WithEndpoints(b.Endpoints()),
+		client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 1}),
+	}, opts...)
+	return client.New(opts...)
+}
+
+// SetLatency delays every response by d
+func (b *Backend) SetLatency(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.latency = d
+}
+
+// SetAccessTokenTTL sets the lifetime of newly issued access tokens
+func (b *Backend) SetAccessTokenTTL(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.accessTokenTTL = d
+}
+
+// SetGameSessionTTL sets the lifetime of newly created or refreshed game sessions
+func (b *Backend) SetGameSessionTTL(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.gameSessionTTL = d
+}
+
+// SetPendingAuthorizations sets how many token polls of a new device flow
+// answer authorization_pending before the device is authorized
+func (b *Backend) SetPendingAuthorizations(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pendingAuthorizations = n
+}
+
+// SetProfiles replaces the profiles owned by the fake account
+func (b *Backend) SetProfiles(profiles ...client.Profile) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.profiles = profiles
+}
+
+// SetVersion sets the version advertised for a patchline
+func (b *Backend) SetVersion(patchline, version string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.versions[patchline] = version
+}
+
+// FailNext makes the next request to route fail with the given status and body.
+// Multiple calls queue up failures in order.
+func (b *Backend) FailNext(route string, status int, body string) {
+	b.FailNextWith(route, Failure{Status: status, Body: body})
+}
+
+// FailNextWith queues an injected failure including response headers
+func (b *Backend) FailNextWith(route string, f Failure) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures[route] = append(b.failures[route], f)
+}
+
+// Calls returns how many requests have been made to route
+func (b *Backend) Calls(route string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls[route]
+}
+
+// GameSessionCount returns the number of live game sessions
+func (b *Backend) GameSessionCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.gameSessions)
+}
+
+// HasGameSession reports whether a game session token is live
+func (b *Backend) HasGameSession(sessionToken string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.gameSessions[sessionToken]
+	return ok
+}
+
+// ExpireAccessTokens invalidates all issued access tokens
+func (b *Backend) ExpireAccessTokens() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for token := range b.accessTokens {
+		b.accessTokens[token] = time.Now().Add(-time.Second)
+	}
+}
+
+// TokenActive reports whether an access or refresh token is still valid
+func (b *Backend) TokenActive(token string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if expiresAt, ok := b.accessTokens[token]; ok {
+		return time.Now().Before(expiresAt)
+	}
+	return b.refreshTokens[token]
+}
+
+// IssueSession returns a fresh OAuth session as if the device flow had completed
+func (b *Backend) IssueSession() *client.Session {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.issueSessionLocked()
+}
+
+// WriteSessionFile writes a fresh OAuth session to path
+func (b *Backend) WriteSessionFile(t testing.TB, path string) *client.Session {
+	t.Helper()
+	session := b.IssueSession()
+	if err := sessionstore.NewFileStore(path).Save(t.Context(), session); err != nil {
+		t.Fatalf("failed to write session file: %v", err)
+	}
+	return session
+}
+
+// BuildArchive returns the zip served for a version
+func BuildArchive(version string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct{ name, content string }{
+		{"Server/HytaleServer.jar", "fake server " + version},
+		{"Assets.zip", "fake assets " + version},
+	}
+	for _, f := range files {
+		w, _ := zw.Create(f.name)
+		_, _ = w.Write([]byte(f.content))
+	}
+	_ = zw.Close()
+	return buf.Bytes()
+}
+
+func (b *Backend) handle(mux *http.ServeMux, route string, h http.HandlerFunc) {
+	mux.HandleFunc(route, func(w http.ResponseWriter, r *http.Request) {
+		b.mu.Lock()
+		b.calls[route]++
+		latency := b.latency
+		var failure *Failure
+		if queue := b.failures[route]; len(queue) > 0 {
+			failure = &queue[0]
+			b.failures[route] = queue[1:]
+		}
+		b.mu.Unlock()
+
+		if latency > 0 {
+			// Consume the body first so the server notices client disconnects while waiting
+			body, _ := io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			select {
+			case <-time.After(latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if failure != nil {
+			for k, v := range failure.Header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(failure.Status)
+			_, _ = w.Write([]byte(failure.Body))
+			return
+		}
+
+		h(w, r)
+	})
+}
+
+func (b *Backend) deviceAuth(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	deviceCode := randomToken("dc")
+	userCode := strings.ToUpper(randomToken("")[:8])
+	b.deviceCodes[deviceCode] = b.pendingAuthorizations
+
+	utils.WriteJSON(w, http.StatusOK, client.DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                userCode,
+		VerificationURI:         b.Server.URL + "/device",
+		VerificationURIComplete: b.Server.URL + "/device?user_code=" + userCode,
+		ExpiresIn:               600,
+		Interval:                1,
+	})
+}
+
+func (b *Backend) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch r.PostForm.Get("grant_type") {
+	case "urn:ietf:params:oauth:grant-type:device_code":
+		deviceCode := r.PostForm.Get("device_code")
+		pending, ok := b.deviceCodes[deviceCode]
+		if !ok {
+			writeOAuthError(w, http.StatusBadRequest, "expired_token", "unknown or expired device code")
+			return
+		}
+		if pending > 0 {
+			b.deviceCodes[deviceCode] = pending - 1
+			writeOAuthError(w, http.StatusBadRequest, "authorization_pending", "user has not authorized the device yet")
+			return
+		}
+		delete(b.deviceCodes, deviceCode)
+		utils.WriteJSON(w, http.StatusOK, b.issueSessionLocked())
+	case "refresh_token":
+		refreshToken := r.PostForm.Get("refresh_token")
+		if !b.refreshTokens[refreshToken] {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "refresh token is invalid or expired")
+			return
+		}
+		delete(b.refreshTokens, refreshToken)
+		utils.WriteJSON(w, http.StatusOK, b.issueSessionLocked())
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "unsupported grant type")
+	}
+}
+
+// revoke implements RFC 7009: unknown tokens are ignored
+func (b *Backend) revoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "missing token")
+		return
+	}
+	delete(b.accessTokens, token)
+	delete(b.refreshTokens, token)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (b *Backend) getProfiles(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.authorizedLocked(r) {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "invalid access token")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, client.ProfilesResponse{
+		Owner:    b.owner,
+		Profiles: b.profiles,
+	})
+}
+
+func (b *Backend) newGameSession(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		UUID string `json:"uuid"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.authorizedLocked(r) {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "invalid access token")
+		return
+	}
+	if !b.hasProfileLocked(payload.UUID) {
+		writeError(w, http.StatusNotFound, "profile_not_found", "unknown profile "+payload.UUID)
+		return
+	}
+
+	session := &client.GameSession{
+		SessionToken:  randomToken("st"),
+		IdentityToken: randomToken("it"),
+		ExpiresAt:     time.Now().Add(b.gameSessionTTL).UTC(),
+	}
+	b.gameSessions[session.SessionToken] = session
+
+	utils.WriteJSON(w, http.StatusOK, session)
+}
+
+func (b *Backend) refreshGameSession(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	session, ok := b.gameSessions[bearerToken(r)]
+	if !ok || time.Now().After(session.ExpiresAt) {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "invalid game session")
+		return
+	}
+
+	refreshed := &client.GameSession{
+		SessionToken:  session.SessionToken,
+		IdentityToken: randomToken("it"),
+		ExpiresAt:     time.Now().Add(b.gameSessionTTL).UTC(),
+	}
+	b.gameSessions[session.SessionToken] = refreshed
+
+	utils.WriteJSON(w, http.StatusOK, refreshed)
+}
+
+func (b *Backend) deleteGameSession(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	token := bearerToken(r)
+	if _, ok := b.gameSessions[token]; !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "invalid game session")
+		return
+	}
+	delete(b.gameSessions, token)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (b *Backend) gameAssets(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.authorizedLocked(r) {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "invalid access token")
+		return
+	}
+
+	file := r.PathValue("file")
+	utils.WriteJSON(w, http.StatusOK, client.SignedURLResponse{
+		URL: fmt.Sprintf("%s/cdn/%s?signature=%s", b.Server.URL, file, randomToken("")),
+	})
+}
+
+func (b *Backend) cdn(w http.ResponseWriter, r *http.Request) {
+	file := r.PathValue("file")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if patchline, ok := strings.CutPrefix(file, "version/"); ok {
+		patchline = strings.TrimSuffix(patchline, ".json")
+		version, ok := b.versions[patchline]
+		if !ok {
+			writeError(w, http.StatusNotFound, "not_found", "unknown patchline "+patchline)
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, map[string]string{
+			"download_url": "builds/" + version + ".zip",
+			"version":      version,
+		})
+		return
+	}
+
+	if build, ok := strings.CutPrefix(file, "builds/"); ok {
+		w.Header().Set("Content-Type", "application/zip")
+		_, _ = w.Write(BuildArchive(strings.TrimSuffix(build, ".zip")))
+		return
+	}
+
+	writeError(w, http.StatusNotFound, "not_found", "unknown file "+file)
+}
+
+func (b *Backend) issueSessionLocked() *client.Session {
+	accessToken := randomToken("at")
+	refreshToken := randomToken("rt")
+	b.accessTokens[accessToken] = time.Now().Add(b.accessTokenTTL)
+	b.refreshTokens[refreshToken] = true
+
+	return &client.Session{
+		Token:        accessToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "bearer",
+		Scope:        "openid offline auth:server",
+		ExpiresIn:    int(b.accessTokenTTL.Seconds()),
+		ExpiresAt:    time.Now().Add(b.accessTokenTTL),
+		CreatedAt:    time.Now(),
+	}
+}
+
+func (b *Backend) authorizedLocked(r *http.Request) bool {
+	expiresAt, ok := b.accessTokens[bearerToken(r)]
+	return ok && time.Now().Before(expiresAt)
+}
+
+func (b *Backend) hasProfileLocked(uuid string) bool {
+	for _, p := range b.profiles {
+		if p.UUID == uuid {
+			return true
+		}
+	}
+	return false
+}
+
+func bearerToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	utils.WriteJSON(w, status, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	utils.WriteJSON(w, status, map[string]string{
+		"code":    code,
+		"message": message,
+	})
+}
+
+func randomToken(prefix string) string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	if prefix == "" {
+		return hex.EncodeToString(buf)
+	}
+	return prefix + "_" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AdminAuth protects all paths below pathPrefix with a static bearer token.
+// With an empty token admin paths are only reachable if allowWithoutToken is
+// set, which is meant for single-user deployments without JWT validation.
+func AdminAuth(pathPrefix string, token string, allowWithoutToken bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, pathPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if token == "" {
+				if allowWithoutToken {
+					next.ServeHTTP(w, r)
+					return
+				}
+				http.Error(w, `{"error":"admin API disabled, configure an admin token"}`, http.StatusForbidden)
+				return
+			}
+
+			auth := r.Header.Get("Authorization")
+			presented, ok := strings.CutPrefix(auth, "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				http.Error(w, `{"error":"invalid admin token"}`, http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -14,7 +14,10 @@ import (
 
 type contextKey string
 
-const SubjectContextKey contextKey = "jwt_subject"
+const (
+	SubjectContextKey contextKey = "jwt_subject"
+	ClaimsContextKey  contextKey = "jwt_claims"
+)
 
 // bearerTokenTransport wraps an http.RoundTripper to add a bearer token to requests
 type bearerTokenTransport struct {
@@ -111,8 +114,9 @@ func JWTAuth(jwksURL string, caCertFile string, jwtTokenFile string) func(http.H
 				return
 			}
 
-			// Add subject to context
+			// Add subject and claims to context
 			ctx := context.WithValue(r.Context(), SubjectContextKey, subject)
+			ctx = context.WithValue(ctx, ClaimsContextKey, claims)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -124,7 +128,18 @@ func GetSubjectFromContext(ctx context.Context) (string, bool) {
 	return subject, ok
 }
 
-// JWTAuthWithPublicPaths creates JWT authentication middleware that skips auth for specified paths
+// GetClaimFromContext extracts a string claim of the JWT from the request context
+func GetClaimFromContext(ctx context.Context, name string) (string, bool) {
+	claims, ok := ctx.Value(ClaimsContextKey).(jwt.MapClaims)
+	if !ok {
+		return "", false
+	}
+	value, ok := claims[name].(string)
+	return value, ok
+}
+
+// JWTAuthWithPublicPaths creates JWT authentication middleware that skips auth for specified paths.
+// Paths ending in a slash match every path below them.
 func JWTAuthWithPublicPaths(jwksURL string, caCertFile string, jwtTokenFile string, publicPaths []string) func(http.Handler) http.Handler {
 	jwtMiddleware := JWTAuth(jwksURL, caCertFile, jwtTokenFile)
 
@@ -132,7 +147,7 @@ func JWTAuthWithPublicPaths(jwksURL string, caCertFile string, jwtTokenFile stri
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Check if the path is public
 			for _, path := range publicPaths {
-				if r.URL.Path == path {
+				if r.URL.Path == path || (strings.HasSuffix(path, "/") && strings.HasPrefix(r.URL.Path, path)) {
 					next.ServeHTTP(w, r)
 					return
 				}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"net/http"
+	"strings"
 
 	"hsm/api"
 	"hsm/internal/handlers"
@@ -11,26 +12,64 @@ import (
 
 const version = "1.0.0"
 
+// adminPathPrefix is protected by the admin token instead of JWT authentication
+const adminPathPrefix = "/admin/"
+
 // SetupRoutes configures all HTTP routes using oapi-codegen generated handlers
-func SetupRoutes(sessionService *services.SessionService, downloadService *services.DownloadService, jwksEndpoint string, jwksCACert string, jwksJWTToken string) http.Handler {
+// userSessionService is nil in single-user mode, logins holds the in-process
+// login of every pool account. events receives the session events of
+// single-user mode and broker serves the event stream, both may be nil.
+func SetupRoutes(config Config, pool *services.AccountPool, userSessionService *services.UserSessionService, logins map[string]*services.LoginService, downloadService *services.DownloadService, events services.EventSink, broker *services.EventBroker) http.Handler {
 	// Create server with appropriate configuration
-	var opts []handlers.ServerOption
-	if jwksEndpoint != "" {
+	opts := []handlers.ServerOption{handlers.WithAccountPool(pool), handlers.WithLoginServices(logins)}
+	if events != nil {
+		opts = append(opts, handlers.WithEventSink(events))
+	}
+	if broker != nil {
+		opts = append(opts, handlers.WithEventBroker(broker))
+	}
+	if userSessionService != nil {
 		// Multi-user mode: use UserSessionService for subject tracking
-		userSessionService := services.NewUserSessionService(sessionService)
-		opts = append(opts, handlers.WithUserSessionService(userSessionService))
+		opts = append(opts, handlers.WithUserSessionService(userSessionService), handlers.WithAccountClaim(config.PoolClaim), handlers.WithInstanceClaim(config.InstanceClaim))
 	}
 
-	server := handlers.NewServer(version, sessionService, downloadService, opts...)
+	// Single-user mode only uses the primary account
+	server := handlers.NewServer(version, pool.Primary(), downloadService, opts...)
 
 	// Create the base handler with all routes
 	baseHandler := api.HandlerWithOptions(server, api.StdHTTPServerOptions{})
 
 	// Apply JWT authentication middleware if JWKS endpoint is configured
 	var handler = baseHandler
-	if jwksEndpoint != "" {
-		handler = middleware.JWTAuthWithPublicPaths(jwksEndpoint, jwksCACert, jwksJWTToken, []string{"/health"})(handler)
+	if config.JWKSEndpoint != "" {
+		handler = middleware.JWTAuthWithPublicPaths(config.JWKSEndpoint, config.JWKSCACert, config.JWKSJWTToken, []string{"/health", adminPathPrefix})(handler)
 	}
 
+	// Without an admin token the admin API is only open when nothing else is authenticated either
+	handler = middleware.AdminAuth(adminPathPrefix, config.AdminToken, config.JWKSEndpoint == "")(handler)
+
 	return middleware.Logging(handler)
 }
+
+// PublicRoutes hides the admin API of handler, for when it is served on a
+// separate listener
+func PublicRoutes(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, adminPathPrefix) {
+			http.NotFound(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// AdminRoutes serves only the admin API and the health check of handler
+func AdminRoutes(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, adminPathPrefix) && r.URL.Path != "/health" {
+			http.NotFound(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -1,41 +1,249 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"hsm/internal/client"
 	"hsm/internal/services"
+	"hsm/internal/sessionstore"
 )
 
+// shutdownTimeout bounds how long in-flight requests may take after a shutdown signal
+const shutdownTimeout = 15 * time.Second
+
 // Config holds server configuration
 type Config struct {
-	Port         string
+	Port string
+	// AdminPort serves the admin API on a separate listener when set, the
+	// admin API is then no longer reachable on Port
+	AdminPort    string
 	JWKSEndpoint string
 	JWKSCACert   string
 	JWKSJWTToken string
-	SessionPath  string
+	SessionStore sessionstore.Store
+	Endpoints    client.Endpoints
+	RetryPolicy  client.RetryPolicy
+	Profile      string
+	AdminToken   string
+	// Pool lists the accounts game sessions are spread across in multi-user
+	// mode. When empty, SessionStore and Profile form the only account.
+	Pool         []PoolAccount
+	PoolStrategy services.PoolStrategy
+	// PoolClaim is the JWT claim naming the account for services.StrategyClaim
+	PoolClaim string
+	// LinkStore opens the store of a subject's own login. Linking accounts
+	// is disabled when nil.
+	LinkStore services.LinkStoreFunc
+	// StateStore persists the game sessions tracked in multi-user mode so
+	// they survive a restart. Tracked sessions are kept in memory only when nil.
+	StateStore services.StateStore
+	// GameSessionRefreshMargin is how long before they expire tracked game
+	// sessions are refreshed in the background
+	GameSessionRefreshMargin      time.Duration
+	GameSessionRefreshConcurrency int
+	// LeaseTTL is how long a game session survives without a heartbeat,
+	// leases are disabled when zero
+	LeaseTTL time.Duration
+	// MaxSessionsPerSubject limits the instances a subject may run, unlimited when zero
+	MaxSessionsPerSubject int
+	// InstanceClaim is the JWT claim naming the instance of a game session
+	InstanceClaim string
+	// Webhooks are the URLs session lifecycle events are posted to, signed
+	// with WebhookSecret
+	Webhooks      []string
+	WebhookSecret string
+	// WebhookOutbox keeps undelivered events across restarts, in memory only when empty
+	WebhookOutbox string
+	// VersionCheckInterval is how often new versions are looked for to notify the
+	// event stream, disabled when zero
+	VersionCheckInterval time.Duration
+}
+
+// PoolAccount is an account of the pool
+type PoolAccount struct {
+	Name         string
+	SessionStore sessionstore.Store
+	Profile      string
 }
 
-// Start initializes and starts the HTTP server
+// Start initializes and starts the HTTP server. It returns after SIGINT/SIGTERM
+// once in-flight requests have completed or been cancelled.
 func Start(config Config) error {
-	c := client.New()
-	sessionService, err := services.NewSessionService(c, config.SessionPath)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pool, err := newAccountPool(ctx, config)
 	if err != nil {
-		log.Fatalf("failed to create session service: %v", err)
+		return err
+	}
+	defer func() {
+		for _, member := range pool.Members() {
+			member.Service.Close()
+		}
+	}()
+	downloadService := services.NewDownloadService(pool.Primary().Client())
+
+	// Accounts without a usable session log in with the device flow in-process
+	logins := make(map[string]*services.LoginService)
+	for _, member := range pool.Members() {
+		login := services.NewLoginService(member.Service)
+		logins[member.Name] = login
+		go login.Run(ctx)
+		if !member.Service.LoggedIn() {
+			log.Printf("Account %s needs a login, the verification URL is logged below and returned by GET /admin/v1/login", member.Name)
+		}
+	}
+
+	var links *services.LinkService
+	if config.LinkStore != nil && config.JWKSEndpoint != "" {
+		links = services.NewLinkService(func() *client.Client {
+			return client.New(client.WithEndpoints(config.Endpoints), client.WithRetryPolicy(config.RetryPolicy))
+		}, config.LinkStore)
+		defer links.Close()
 	}
-	downloadService := services.NewDownloadService(c)
 
-	handler := SetupRoutes(sessionService, downloadService, config.JWKSEndpoint, config.JWKSCACert, config.JWKSJWTToken)
+	// Events are streamed to /api/v1/events and posted to the configured webhooks
+	broker := services.NewEventBroker(services.DefaultEventBacklog)
+	events := services.EventSinks{broker}
+	if len(config.Webhooks) > 0 {
+		webhooks, err := services.NewWebhookDispatcher(config.Webhooks, config.WebhookSecret, services.WithWebhookOutbox(config.WebhookOutbox))
+		if err != nil {
+			return err
+		}
+		go webhooks.Run(ctx)
+		events = append(events, webhooks)
+		log.Printf("Posting session events to %d webhooks", len(config.Webhooks))
+	}
+	if config.VersionCheckInterval > 0 {
+		// Webhooks are about game sessions, new versions only go to the event stream
+		watcher := services.NewVersionWatcher(downloadService, broker, services.PatchlineRelease, services.PatchlinePrerelease)
+		go watcher.Run(ctx, config.VersionCheckInterval)
+	}
+
+	// Multi-user mode tracks the game session of every subject
+	var userSessionService *services.UserSessionService
+	if config.JWKSEndpoint != "" {
+		userOpts := []services.UserSessionOption{
+			services.WithRefreshMargin(config.GameSessionRefreshMargin),
+			services.WithRefreshConcurrency(config.GameSessionRefreshConcurrency),
+			services.WithLeaseTTL(config.LeaseTTL),
+			services.WithMaxSessionsPerSubject(config.MaxSessionsPerSubject),
+		}
+		if links != nil {
+			userOpts = append(userOpts, services.WithLinks(links))
+		}
+		if config.StateStore != nil {
+			userOpts = append(userOpts, services.WithStateStore(config.StateStore))
+		}
+		userOpts = append(userOpts, services.WithEventSink(events))
+		userSessionService = services.NewUserSessionService(pool, userOpts...)
+		if err := userSessionService.Restore(ctx); err != nil {
+			return fmt.Errorf("failed to restore tracked game sessions: %w", err)
+		}
+		go userSessionService.KeepSessionsFresh(ctx)
+		go userSessionService.KeepLeases(ctx)
+	}
+
+	handler := SetupRoutes(config, pool, userSessionService, logins, downloadService, events, broker)
 
 	if config.JWKSEndpoint != "" {
 		log.Printf("Multi-user mode enabled with JWKS endpoint: %s", config.JWKSEndpoint)
+		if len(config.Pool) > 0 {
+			log.Printf("Spreading game sessions across %d accounts (%s)", len(config.Pool), pool.Strategy())
+		}
 	} else {
 		log.Println("Single-user mode (no JWT validation)")
 	}
 
-	addr := ":" + config.Port
-	log.Printf("Starting server on %s", addr)
+	// Request contexts derive from ctx so upstream calls are cancelled on shutdown
+	servers := []*http.Server{{
+		Addr:        ":" + config.Port,
+		Handler:     handler,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}}
+	if config.AdminPort != "" {
+		servers[0].Handler = PublicRoutes(handler)
+		servers = append(servers, &http.Server{
+			Addr:        ":" + config.AdminPort,
+			Handler:     AdminRoutes(handler),
+			BaseContext: func(net.Listener) context.Context { return ctx },
+		})
+	}
 
-	return http.ListenAndServe(addr, handler)
+	errCh := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() {
+			log.Printf("Starting server on %s", srv.Addr)
+			errCh <- srv.ListenAndServe()
+		}()
+	}
+
+	// A listener failing to start stops the others as well
+	var serveErr error
+	received := 0
+	select {
+	case serveErr = <-errCh:
+		received++
+	case <-ctx.Done():
+		log.Println("Shutting down server...")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil && serveErr == nil {
+			serveErr = err
+		}
+	}
+	for ; received < len(servers); received++ {
+		if err := <-errCh; !errors.Is(err, http.ErrServerClosed) && serveErr == nil {
+			serveErr = err
+		}
+	}
+	return serveErr
+}
+
+// newAccountPool loads the session of every account in config. Each account
+// gets its own client because the client holds the account's access token.
+func newAccountPool(ctx context.Context, config Config) (*services.AccountPool, error) {
+	accounts := config.Pool
+	if len(accounts) == 0 {
+		accounts = []PoolAccount{{Name: "default", SessionStore: config.SessionStore, Profile: config.Profile}}
+	}
+	strategy := config.PoolStrategy
+	if strategy == "" {
+		strategy = services.StrategyLeastLoaded
+	}
+
+	var members []services.PoolMember
+	closeAll := func() {
+		for _, member := range members {
+			member.Service.Close()
+		}
+	}
+	for _, account := range accounts {
+		c := client.New(client.WithEndpoints(config.Endpoints), client.WithRetryPolicy(config.RetryPolicy))
+		sessionService, err := services.NewSessionService(ctx, c, account.SessionStore, services.WithProfile(account.Profile), services.WithDeferredLogin())
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to create session service for account %s: %w", account.Name, err)
+		}
+		members = append(members, services.PoolMember{Name: account.Name, Service: sessionService})
+	}
+
+	pool, err := services.NewAccountPool(strategy, members...)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	return pool, nil
 }
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"hsm/internal/client"
+	"hsm/internal/sessionstore"
+)
+
+// Bootstrap creates the session in store from refreshToken, e.g. one handed to
+// a CI job or a fresh container, unless store already holds a session. The
+// token is exchanged immediately, so the stored session carries rotated tokens.
+// It reports whether a session was created.
+func Bootstrap(ctx context.Context, c *client.Client, store sessionstore.Store, refreshToken string) (bool, error) {
+	unlock, err := sessionstore.Lock(ctx, store)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	_, err = store.Load(ctx)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, sessionstore.ErrNotFound) {
+		return false, err
+	}
+
+	session, err := c.RefreshAccessToken(ctx, refreshToken)
+	if err != nil {
+		return false, fmt.Errorf("failed to exchange refresh token: %w", err)
+	}
+	if session.RefreshToken == "" {
+		session.RefreshToken = refreshToken
+	}
+	if err := store.Save(ctx, session); err != nil {
+		return false, fmt.Errorf("failed to save session: %w", err)
+	}
+	return true, nil
+}
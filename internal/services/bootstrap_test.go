@@ -0,0 +1,48 @@
+package services_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"hsm/internal/client"
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+	"hsm/internal/sessionstore"
+)
+
+func TestBootstrap(t *testing.T) {
+	backend := hsmtest.New(t)
+	store := sessionstore.NewFileStore(filepath.Join(t.TempDir(), "session.json"))
+	issued := backend.IssueSession()
+
+	created, err := services.Bootstrap(t.Context(), backend.Client(), store, issued.RefreshToken)
+	if err != nil || !created {
+		t.Fatalf("Bootstrap: created=%v err=%v", created, err)
+	}
+	session, err := store.Load(t.Context())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if session.RefreshToken == issued.RefreshToken || !backend.TokenActive(session.Token) {
+		t.Fatal("expected the refresh token to be exchanged for a fresh session")
+	}
+
+	// The used token is not exchanged again once the store holds a session
+	created, err = services.Bootstrap(t.Context(), backend.Client(), store, issued.RefreshToken)
+	if err != nil || created {
+		t.Fatalf("second Bootstrap: created=%v err=%v", created, err)
+	}
+}
+
+func TestBootstrapRejectedToken(t *testing.T) {
+	backend := hsmtest.New(t)
+	store := sessionstore.NewFileStore(filepath.Join(t.TempDir(), "session.json"))
+
+	if _, err := services.Bootstrap(t.Context(), backend.Client(), store, "unknown"); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+	if _, err := store.Load(t.Context()); !errors.Is(err, sessionstore.ErrNotFound) {
+		t.Fatalf("expected no session to be saved, got %v", err)
+	}
+}
@@ -10,34 +10,96 @@ import (
 	"hsm/internal/client"
 )
 
+const (
+	// DefaultPollInterval is used when the server does not specify a polling interval
+	DefaultPollInterval = 5 * time.Second
+	// SlowDownIncrement is added to the polling interval on every slow_down response (RFC 8628 section 3.5)
+	SlowDownIncrement = 5 * time.Second
+)
+
 // DeviceFlowService handles OAuth2 device flow business logic
 type DeviceFlowService struct {
-	client *client.Client
+	client      *client.Client
+	maxRestarts int
+	prompt      func(*client.DeviceAuthorizationResponse)
+	slowDown    time.Duration
+}
+
+// DeviceFlowOption is a functional option for configuring the DeviceFlowService
+type DeviceFlowOption func(*DeviceFlowService)
+
+// WithRestartOnExpiry restarts the flow with a new device code up to maxRestarts
+// times when the user did not authorize the device before the code expired
+func WithRestartOnExpiry(maxRestarts int) DeviceFlowOption {
+	return func(d *DeviceFlowService) {
+		d.maxRestarts = maxRestarts
+	}
+}
+
+// WithPrompt sets the callback that presents the verification URL and user code
+func WithPrompt(prompt func(*client.DeviceAuthorizationResponse)) DeviceFlowOption {
+	return func(d *DeviceFlowService) {
+		d.prompt = prompt
+	}
 }
 
 // NewDeviceFlowService creates a new DeviceFlowService with a client
-func NewDeviceFlowService(client *client.Client) *DeviceFlowService {
-	return &DeviceFlowService{
-		client: client,
+func NewDeviceFlowService(client *client.Client, opts ...DeviceFlowOption) *DeviceFlowService {
+	d := &DeviceFlowService{
+		client:   client,
+		prompt:   logPrompt,
+		slowDown: SlowDownIncrement,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// logPrompt is the default prompt, it logs the verification URL and user code
+func logPrompt(deviceAuth *client.DeviceAuthorizationResponse) {
+	if deviceAuth.VerificationURIComplete != "" {
+		log.Printf("Please visit %s to authorize the device", deviceAuth.VerificationURIComplete)
+		return
 	}
+	log.Printf("Please visit %s and enter the code %s to authorize the device", deviceAuth.VerificationURI, deviceAuth.UserCode)
 }
 
 // Flow orchestrates the complete OAuth2 device flow:
 // 1. Initiates device authorization
-// 2. Polls for token exchange until authorized or expired
-// Returns the session with tokens when complete
+// 2. Polls for token exchange until authorized, denied or expired
+// 3. Optionally restarts with a new device code on expiry
+// Returns the session with tokens when complete. Failures match client.ErrAccessDenied
+// or client.ErrExpiredToken with errors.Is.
 func (d *DeviceFlowService) Flow(ctx context.Context) (*client.Session, error) {
+	for restarts := 0; ; restarts++ {
+		session, err := d.flowOnce(ctx)
+		if err == nil {
+			return session, nil
+		}
+
+		if errors.Is(err, client.ErrExpiredToken) && restarts < d.maxRestarts && ctx.Err() == nil {
+			log.Println("Device code expired, restarting device flow...")
+			continue
+		}
+
+		return nil, err
+	}
+}
+
+func (d *DeviceFlowService) flowOnce(ctx context.Context) (*client.Session, error) {
 	// Step 1: Initiate device authorization
-	deviceAuth, err := d.client.CreateDeviceFlow()
+	deviceAuth, err := d.client.CreateDeviceFlow(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initiate device flow: %w", err)
 	}
 
 	// Step 2: Poll for token exchange
-	// Default interval is 5 seconds if not specified
 	pollInterval := time.Duration(deviceAuth.Interval) * time.Second
 	if deviceAuth.Interval == 0 {
-		pollInterval = 5 * time.Second
+		pollInterval = DefaultPollInterval
 	}
 
 	// Create a context with timeout based on device authorization expiration
@@ -48,11 +110,15 @@ func (d *DeviceFlowService) Flow(ctx context.Context) (*client.Session, error) {
 		defer cancel()
 	}
 
-	log.Printf("Please visit %s to authorize the device", deviceAuth.VerificationURIComplete)
+	d.prompt(deviceAuth)
 
 	// Poll for token with the polling logic in the service
 	session, err := d.pollForToken(pollCtx, deviceAuth.DeviceCode, pollInterval)
 	if err != nil {
+		// The local deadline is the device code lifetime, report it like the server would
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			err = client.ErrExpiredToken
+		}
 		return nil, fmt.Errorf("failed to poll for token: %w", err)
 	}
 
@@ -61,10 +127,10 @@ func (d *DeviceFlowService) Flow(ctx context.Context) (*client.Session, error) {
 
 // pollForToken implements the polling logic for device flow token exchange.
 // It calls the client's ExchangeDeviceCodeForToken method repeatedly until
-// the user authorizes or the context is cancelled.
+// the user authorizes, the flow fails or the context is cancelled.
 func (d *DeviceFlowService) pollForToken(ctx context.Context, deviceCode string, interval time.Duration) (*client.Session, error) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
 
 	// Make initial request immediately, then poll at intervals
 	for {
@@ -75,17 +141,27 @@ func (d *DeviceFlowService) pollForToken(ctx context.Context, deviceCode string,
 		}
 
 		// Check for specific device flow errors
-		if errors.Is(err, client.ErrAuthorizationPending) {
-			// Wait for next poll interval
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-ticker.C:
-				continue
-			}
+		switch {
+		case errors.Is(err, client.ErrAuthorizationPending):
+			// Keep polling at the current interval
+		case errors.Is(err, client.ErrSlowDown):
+			interval += d.slowDown
+			log.Printf("Authorization server asked to slow down, polling every %v", interval)
+		case errors.Is(err, client.ErrExpiredToken):
+			return nil, client.ErrExpiredToken
+		case errors.Is(err, client.ErrAccessDenied):
+			return nil, client.ErrAccessDenied
+		default:
+			// For any other error, return it
+			return nil, err
 		}
 
-		// For any other error, return it
-		return nil, err
+		// Wait for next poll interval
+		timer.Reset(interval)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
 	}
 }
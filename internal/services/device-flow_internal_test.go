@@ -0,0 +1,29 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"hsm/internal/hsmtest"
+)
+
+func TestDeviceFlowSlowDownIncreasesInterval(t *testing.T) {
+	backend := hsmtest.New(t)
+	backend.FailNext(hsmtest.RouteToken, 400, `{"error":"slow_down"}`)
+
+	d := NewDeviceFlowService(backend.Client())
+	d.slowDown = 500 * time.Millisecond
+
+	start := time.Now()
+	if _, err := d.Flow(t.Context()); err != nil {
+		t.Fatalf("Flow: %v", err)
+	}
+
+	// The fake advertises a 1s interval, slow_down must extend it
+	if elapsed := time.Since(start); elapsed < 1500*time.Millisecond {
+		t.Fatalf("expected the polling interval to grow after slow_down, finished after %v", elapsed)
+	}
+	if got := backend.Calls(hsmtest.RouteToken); got != 2 {
+		t.Fatalf("expected 2 token polls, got %d", got)
+	}
+}
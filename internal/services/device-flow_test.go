@@ -0,0 +1,84 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"hsm/internal/client"
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+)
+
+func TestDeviceFlowPollsUntilAuthorized(t *testing.T) {
+	backend := hsmtest.New(t)
+	backend.SetPendingAuthorizations(1)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+
+	session, err := services.NewDeviceFlowService(backend.Client()).Flow(ctx)
+	if err != nil {
+		t.Fatalf("Flow: %v", err)
+	}
+	if session.AccessToken == "" || session.RefreshToken == "" {
+		t.Fatalf("expected tokens, got %+v", session)
+	}
+	if session.Token != session.AccessToken {
+		t.Fatal("expected Token to mirror AccessToken")
+	}
+	if got := backend.Calls(hsmtest.RouteToken); got != 2 {
+		t.Fatalf("expected 2 token polls, got %d", got)
+	}
+}
+
+func TestDeviceFlowAccessDenied(t *testing.T) {
+	backend := hsmtest.New(t)
+	backend.FailNext(hsmtest.RouteToken, 400, `{"error":"access_denied"}`)
+
+	_, err := services.NewDeviceFlowService(backend.Client()).Flow(t.Context())
+	if !errors.Is(err, client.ErrAccessDenied) {
+		t.Fatalf("expected ErrAccessDenied, got %v", err)
+	}
+}
+
+func TestDeviceFlowExpired(t *testing.T) {
+	backend := hsmtest.New(t)
+	backend.FailNext(hsmtest.RouteToken, 400, `{"error":"expired_token"}`)
+
+	_, err := services.NewDeviceFlowService(backend.Client()).Flow(t.Context())
+	if !errors.Is(err, client.ErrExpiredToken) {
+		t.Fatalf("expected ErrExpiredToken, got %v", err)
+	}
+}
+
+func TestDeviceFlowRestartsOnExpiry(t *testing.T) {
+	backend := hsmtest.New(t)
+	backend.FailNext(hsmtest.RouteToken, 400, `{"error":"expired_token"}`)
+
+	var prompts int
+	svc := services.NewDeviceFlowService(backend.Client(),
+		services.WithRestartOnExpiry(1),
+		services.WithPrompt(func(*client.DeviceAuthorizationResponse) { prompts++ }),
+	)
+
+	if _, err := svc.Flow(t.Context()); err != nil {
+		t.Fatalf("Flow: %v", err)
+	}
+	if got := backend.Calls(hsmtest.RouteDeviceAuth); got != 2 {
+		t.Fatalf("expected the flow to restart once, got %d device authorizations", got)
+	}
+	if prompts != 2 {
+		t.Fatalf("expected the user to be prompted twice, got %d", prompts)
+	}
+}
+
+func TestDeviceFlowFailsOnUnexpectedError(t *testing.T) {
+	backend := hsmtest.New(t)
+	backend.FailNext(hsmtest.RouteToken, 400, `{"error":"invalid_client"}`)
+
+	if _, err := services.NewDeviceFlowService(backend.Client()).Flow(t.Context()); err == nil {
+		t.Fatal("expected error for unexpected OAuth error")
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -27,35 +28,64 @@ func NewDownloadService(c *client.Client) *DownloadService {
 	}
 }
 
+// versionInfo describes the latest version of a patchline
+type versionInfo struct {
+	DownloadURL string `json:"download_url"`
+	Version     string `json:"version"`
+}
+
 // GetDownloadURL fetches the signed download URL for a patchline
-func (s *DownloadService) GetDownloadURL(patchline string) (string, string, error) {
-	resultFileInfoUrl, err := s.client.GetSignedURL(fmt.Sprintf("version/%s.json", patchline))
+func (s *DownloadService) GetDownloadURL(ctx context.Context, patchline string) (string, string, error) {
+	resultFileInfo, err := s.versionInfo(ctx, patchline)
 	if err != nil {
 		return "", "", err
 	}
 
-	resp, err := http.Get(resultFileInfoUrl.URL)
+	resultDownloadURL, err := s.client.GetSignedURL(ctx, resultFileInfo.DownloadURL)
 	if err != nil {
 		return "", "", err
 	}
-	defer func() { _ = resp.Body.Close() }()
+	return resultDownloadURL.URL, resultFileInfo.Version, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+// GetVersion fetches the latest version of a patchline
+func (s *DownloadService) GetVersion(ctx context.Context, patchline string) (string, error) {
+	info, err := s.versionInfo(ctx, patchline)
 	if err != nil {
-		return "", "", err
+		return "", err
 	}
+	return info.Version, nil
+}
 
-	var resultFileInfo struct {
-		DownloadURL string `json:"download_url"`
-		Version     string `json:"version"`
+func (s *DownloadService) versionInfo(ctx context.Context, patchline string) (*versionInfo, error) {
+	resultFileInfoUrl, err := s.client.GetSignedURL(ctx, fmt.Sprintf("version/%s.json", patchline))
+	if err != nil {
+		return nil, err
 	}
-	if err := json.Unmarshal(body, &resultFileInfo); err != nil {
-		return "", "", err
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, resultFileInfoUrl.URL, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	resultDownloadURL, err := s.client.GetSignedURL(resultFileInfo.DownloadURL)
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
-	return resultDownloadURL.URL, resultFileInfo.Version, nil
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch version info for patchline %s: unexpected status %d", patchline, resp.StatusCode)
+	}
+
+	var resultFileInfo versionInfo
+	if err := json.Unmarshal(body, &resultFileInfo); err != nil {
+		return nil, err
+	}
+	return &resultFileInfo, nil
 }
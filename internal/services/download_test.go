@@ -0,0 +1,44 @@
+package services_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+)
+
+func TestDownloadServiceGetDownloadURL(t *testing.T) {
+	backend := hsmtest.New(t)
+	backend.SetVersion(services.PatchlinePrerelease, "2026.02.03-test")
+	svc := services.NewDownloadService(newSessionService(t, backend).Client())
+
+	url, version, err := svc.GetDownloadURL(t.Context(), services.PatchlinePrerelease)
+	if err != nil {
+		t.Fatalf("GetDownloadURL: %v", err)
+	}
+	if version != "2026.02.03-test" {
+		t.Fatalf("unexpected version %q", version)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+
+	if string(body) != string(hsmtest.BuildArchive(version)) {
+		t.Fatal("downloaded archive does not match the advertised version")
+	}
+}
+
+func TestDownloadServiceUnknownPatchline(t *testing.T) {
+	backend := hsmtest.New(t)
+	svc := services.NewDownloadService(newSessionService(t, backend).Client())
+
+	if _, _, err := svc.GetDownloadURL(t.Context(), "nightly"); err == nil {
+		t.Fatal("expected error for unknown patchline")
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultEventBacklog is how many recent events an EventBroker keeps for
+// streams resuming after a reconnect
+const DefaultEventBacklog = 1024
+
+// eventBuffer is how many events a subscriber may fall behind before it is dropped
+const eventBuffer = 64
+
+// StreamEvent is an event with its id in the stream of an EventBroker
+type StreamEvent struct {
+	ID string
+	Event
+	seq uint64
+}
+
+// EventBroker fans events out to the subscribed event streams and keeps the
+// recent ones so that streams can resume after a reconnect. Stream ids start
+// with the time the broker was created, ids of an earlier run resume from the
+// oldest kept event.
+type EventBroker struct {
+	epoch   int64
+	seq     uint64
+	backlog []StreamEvent
+	size    int
+	subs    map[*eventSubscription]struct{}
+	mu      sync.Mutex
+}
+
+type eventSubscription struct {
+	subject string // receives the events of all subjects when empty
+	ch      chan StreamEvent
+}
+
+// wants reports whether the subscriber receives event. Global events have no subject.
+func (s *eventSubscription) wants(event Event) bool {
+	return s.subject == "" || event.Subject == "" || event.Subject == s.subject
+}
+
+// NewEventBroker creates an EventBroker keeping the last backlog events,
+// DefaultEventBacklog if backlog is not positive
+func NewEventBroker(backlog int) *EventBroker {
+	if backlog <= 0 {
+		backlog = DefaultEventBacklog
+	}
+	return &EventBroker{
+		epoch: time.Now().UnixMilli(),
+		size:  backlog,
+		subs:  make(map[*eventSubscription]struct{}),
+	}
+}
+
+// Publish sends event to every interested subscriber. Subscribers that fell
+// too far behind are dropped, their streams end and resume after a reconnect.
+func (b *EventBroker) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	streamEvent := StreamEvent{ID: fmt.Sprintf("%d-%d", b.epoch, b.seq), Event: event, seq: b.seq}
+	b.backlog = append(b.backlog, streamEvent)
+	if len(b.backlog) > b.size {
+		b.backlog = slices.Delete(b.backlog, 0, len(b.backlog)-b.size)
+	}
+
+	for sub := range b.subs {
+		if !sub.wants(event) {
+			continue
+		}
+		select {
+		case sub.ch <- streamEvent:
+		default:
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Subscribe returns the kept events for subject after lastID, none if lastID
+// is empty, and a channel receiving the following ones. An empty subject
+// subscribes to the events of all subjects. The channel is closed when the
+// subscriber falls behind or cancel is called.
+func (b *EventBroker) Subscribe(subject, lastID string) (missed []StreamEvent, events <-chan StreamEvent, cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &eventSubscription{subject: subject, ch: make(chan StreamEvent, eventBuffer)}
+	b.subs[sub] = struct{}{}
+
+	if lastID != "" {
+		var epoch int64
+		var seq uint64
+		if _, err := fmt.Sscanf(lastID, "%d-%d", &epoch, &seq); err != nil || epoch != b.epoch {
+			seq = 0
+		}
+		for _, event := range b.backlog {
+			if event.seq > seq && sub.wants(event.Event) {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	return missed, sub.ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[sub]; ok {
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package services_test
+
+import (
+	"testing"
+
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+)
+
+func receive(t *testing.T, events <-chan services.StreamEvent) services.StreamEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	default:
+		t.Fatal("expected an event")
+		return services.StreamEvent{}
+	}
+}
+
+func TestEventBrokerFiltersBySubject(t *testing.T) {
+	broker := services.NewEventBroker(0)
+	_, alice, cancel := broker.Subscribe("alice", "")
+	defer cancel()
+	_, all, cancelAll := broker.Subscribe("", "")
+	defer cancelAll()
+
+	broker.Publish(services.NewEvent(services.EventSessionRefreshed, services.SessionKey{Subject: "bob"}, "", nil, nil))
+	broker.Publish(services.NewEvent(services.EventSessionRefreshed, services.SessionKey{Subject: "alice"}, "", nil, nil))
+	broker.Publish(services.NewEvent(services.EventVersionAvailable, services.SessionKey{}, "", nil, nil))
+
+	if event := receive(t, alice); event.Subject != "alice" {
+		t.Fatalf("expected alice's event, got %+v", event)
+	}
+	if event := receive(t, alice); event.Type != services.EventVersionAvailable {
+		t.Fatalf("expected the global event, got %+v", event)
+	}
+	if len(alice) != 0 {
+		t.Fatal("expected no events of other subjects")
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected all 3 events without a subject, got %d", len(all))
+	}
+}
+
+func TestEventBrokerResumes(t *testing.T) {
+	broker := services.NewEventBroker(2)
+	for _, subject := range []string{"alice", "alice", "alice"} {
+		broker.Publish(services.NewEvent(services.EventSessionRefreshed, services.SessionKey{Subject: subject}, "", nil, nil))
+	}
+
+	missed, _, cancel := broker.Subscribe("alice", "")
+	cancel()
+	if len(missed) != 0 {
+		t.Fatalf("expected no events without a last id, got %d", len(missed))
+	}
+
+	// Ids of an earlier run resume from the oldest kept event
+	missed, _, cancel = broker.Subscribe("alice", "1-1")
+	cancel()
+	if len(missed) != 2 {
+		t.Fatalf("expected the 2 kept events, got %d", len(missed))
+	}
+
+	resumed, _, cancel := broker.Subscribe("alice", missed[0].ID)
+	cancel()
+	if len(resumed) != 1 || resumed[0].ID != missed[1].ID {
+		t.Fatalf("expected to resume after %s, got %+v", missed[0].ID, resumed)
+	}
+}
+
+func TestEventBrokerDropsSlowSubscribers(t *testing.T) {
+	broker := services.NewEventBroker(0)
+	_, events, cancel := broker.Subscribe("alice", "")
+	defer cancel()
+
+	for range 100 {
+		broker.Publish(services.NewEvent(services.EventSessionRefreshed, services.SessionKey{Subject: "alice"}, "", nil, nil))
+	}
+	for range events {
+	}
+	// The channel is closed, the stream resumes with its last id
+}
+
+func TestVersionWatcher(t *testing.T) {
+	backend := hsmtest.New(t)
+	recorder := &eventRecorder{}
+	watcher := services.NewVersionWatcher(services.NewDownloadService(newSessionService(t, backend).Client()), recorder, services.PatchlineRelease)
+
+	watcher.Check(t.Context())
+	watcher.Check(t.Context())
+	if got := recorder.types(); len(got) != 0 {
+		t.Fatalf("expected no events for known versions, got %v", got)
+	}
+
+	backend.SetVersion(services.PatchlineRelease, "2026.03.01-release")
+	watcher.Check(t.Context())
+	if got := recorder.types(); len(got) != 1 || got[0] != services.EventVersionAvailable {
+		t.Fatalf("expected a version event, got %v", got)
+	}
+	if event := recorder.events[0]; event.Patchline != services.PatchlineRelease || event.Version != "2026.03.01-release" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
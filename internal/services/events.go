@@ -0,0 +1,89 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"hsm/internal/client"
+)
+
+// EventType names a game session lifecycle event or a global event
+type EventType string
+
+const (
+	EventSessionCreated       EventType = "session.created"
+	EventSessionRefreshed     EventType = "session.refreshed"
+	EventSessionRefreshFailed EventType = "session.refresh_failed"
+	// EventSessionExpiring is sent when less than half of the lease TTL of a
+	// session is left without a heartbeat, ExpiresAt is the end of the lease.
+	// Without leases it is sent when a background refresh of a session failed
+	// and ExpiresAt is the end of the game session.
+	EventSessionExpiring EventType = "session.expiring"
+	// EventSessionExpired is sent when a session expired, the upstream no
+	// longer knows it or its lease ran out
+	EventSessionExpired EventType = "session.expired"
+	// EventSessionTerminated is sent when a session was terminated on request,
+	// replaced by one for another profile or account, or ended by a logout
+	EventSessionTerminated EventType = "session.terminated"
+	// EventVersionAvailable is streamed to every subscriber when a new version
+	// appears on a patchline, it is not posted to webhooks
+	EventVersionAvailable EventType = "version.available"
+)
+
+// Event is a lifecycle event of a game session or a global event, which has
+// no subject. It never carries the tokens of the session.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      EventType `json:"event"`
+	Subject   string    `json:"subject,omitempty"` // empty in single-user mode
+	Instance  string    `json:"instance,omitempty"`
+	Account   string    `json:"account,omitempty"` // pool account, empty for linked accounts
+	Time      time.Time `json:"timestamp"`
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+	Error     string    `json:"error,omitempty"`
+	Patchline string    `json:"patchline,omitempty"`
+	Version   string    `json:"version,omitempty"`
+}
+
+// NewEvent returns an event of typ for the session of key. session and err
+// are optional.
+func NewEvent(typ EventType, key SessionKey, account string, session *client.GameSession, err error) Event {
+	event := Event{
+		ID:       newEventID(),
+		Type:     typ,
+		Subject:  key.Subject,
+		Instance: key.Instance,
+		Account:  account,
+		Time:     time.Now().UTC(),
+	}
+	if session != nil {
+		event.ExpiresAt = session.ExpiresAt
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	return event
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// EventSink receives game session events. Publish must not wait for the
+// delivery of the event.
+type EventSink interface {
+	Publish(event Event)
+}
+
+// EventSinks publishes every event to all of its sinks
+type EventSinks []EventSink
+
+// Publish publishes event to every sink
+func (sinks EventSinks) Publish(event Event) {
+	for _, sink := range sinks {
+		sink.Publish(event)
+	}
+}
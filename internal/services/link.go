@@ -0,0 +1,377 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"hsm/internal/client"
+	"hsm/internal/sessionstore"
+)
+
+// LinkState is the state of a subject's own Hytale account link
+type LinkState string
+
+const (
+	// LinkNone means the subject uses the accounts of the pool
+	LinkNone LinkState = "none"
+	// LinkPending means a device flow is waiting for the subject to authorize it
+	LinkPending LinkState = "pending"
+	// LinkLinked means game sessions for the subject use the subject's own account
+	LinkLinked LinkState = "linked"
+	// LinkFailed means the last device flow was denied, expired or failed
+	LinkFailed LinkState = "failed"
+)
+
+// ErrNotLinked is returned when unlinking a subject without a linked account
+var ErrNotLinked = errors.New("no linked account")
+
+// errLinkExpired marks a link whose login can no longer be refreshed
+var errLinkExpired = errors.New("linked login expired, link the account again")
+
+// Link describes a subject's account link
+type Link struct {
+	State                   LinkState
+	UserCode                string
+	VerificationURI         string
+	VerificationURIComplete string
+	ExpiresAt               time.Time // when the pending device code expires
+	Owner                   string
+	Profile                 client.Profile
+	Error                   string
+}
+
+// LinkStoreFunc opens the session store holding a subject's own login
+type LinkStoreFunc func(subject string) (sessionstore.Store, error)
+
+// LinkService lets JWT subjects log in with their own Hytale account. It runs
+// the device flow on their behalf and keeps a SessionService per linked subject.
+type LinkService struct {
+	newClient func() *client.Client
+	openStore LinkStoreFunc
+	links     map[string]*link
+	loads     *sessionLocks // per subject, held while a saved login is loaded
+	mu        sync.Mutex
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+type link struct {
+	state      LinkState
+	deviceAuth *client.DeviceAuthorizationResponse
+	expiresAt  time.Time
+	err        error
+	service    *SessionService
+	cancel     context.CancelFunc
+}
+
+// NewLinkService creates a LinkService. newClient creates the client for a
+// subject's account and openStore opens the store its login is saved to.
+func NewLinkService(newClient func() *client.Client, openStore LinkStoreFunc) *LinkService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &LinkService{
+		newClient: newClient,
+		openStore: openStore,
+		links:     make(map[string]*link),
+		loads:     newSessionLocks(),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Close stops pending device flows and the linked SessionServices
+func (l *LinkService) Close() {
+	l.cancel()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, entry := range l.links {
+		if entry.service != nil {
+			entry.service.Close()
+		}
+	}
+}
+
+// Start begins a device flow for subject and returns the verification URL and
+// user code once the authorization server issued them. A pending flow that has
+// not expired is returned as is. When the subject authorizes the device, the
+// login is saved and later game sessions for subject use it.
+func (l *LinkService) Start(ctx context.Context, subject string) (Link, error) {
+	l.mu.Lock()
+	if entry, ok := l.links[subject]; ok && entry.state == LinkPending && time.Now().Before(entry.expiresAt) {
+		defer l.mu.Unlock()
+		return entry.toLink(), nil
+	}
+	l.mu.Unlock()
+
+	prompted := make(chan Link, 1)
+	failed := make(chan error, 1)
+	flowCtx, cancel := context.WithCancel(l.ctx)
+	c := l.newClient()
+	deviceFlow := NewDeviceFlowService(c, WithPrompt(func(deviceAuth *client.DeviceAuthorizationResponse) {
+		// Registered before polling starts, so a quick authorization cannot be overwritten
+		prompted <- l.pending(subject, deviceAuth, cancel)
+	}))
+
+	go func() {
+		defer cancel()
+		session, err := deviceFlow.Flow(flowCtx)
+		if err == nil {
+			err = l.complete(flowCtx, subject, c, session)
+		}
+		if err != nil {
+			failed <- err
+			l.fail(flowCtx, subject, err)
+		}
+	}()
+
+	select {
+	case pending := <-prompted:
+		return pending, nil
+	case err := <-failed:
+		return Link{}, err
+	case <-ctx.Done():
+		cancel()
+		return Link{}, ctx.Err()
+	}
+}
+
+// pending records the device flow of subject that waits for authorization
+func (l *LinkService) pending(subject string, deviceAuth *client.DeviceAuthorizationResponse, cancel context.CancelFunc) Link {
+	entry := &link{state: LinkPending, deviceAuth: deviceAuth, cancel: cancel}
+	if deviceAuth.ExpiresIn > 0 {
+		entry.expiresAt = time.Now().Add(time.Duration(deviceAuth.ExpiresIn) * time.Second)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if previous, ok := l.links[subject]; ok {
+		if previous.cancel != nil {
+			previous.cancel()
+		}
+		// A linked account stays in use until the new login completes
+		entry.service = previous.service
+	}
+	l.links[subject] = entry
+	return entry.toLink()
+}
+
+// complete saves the login of subject and switches its game sessions to it
+func (l *LinkService) complete(ctx context.Context, subject string, c *client.Client, session *client.Session) error {
+	store, err := l.openStore(subject)
+	if err != nil {
+		return err
+	}
+	if err := store.Save(ctx, session); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	service, err := NewSessionService(l.ctx, c, store)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if previous, ok := l.links[subject]; ok && previous.service != nil {
+		previous.service.Close()
+	}
+	l.links[subject] = &link{state: LinkLinked, service: service}
+	log.Printf("Subject %s linked Hytale account %s", subject, service.Owner())
+	return nil
+}
+
+// fail records a failed device flow unless it was superseded by a newer one.
+// An account linked before stays linked and in use.
+func (l *LinkService) fail(ctx context.Context, subject string, err error) {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if entry, ok := l.links[subject]; ok && entry.state == LinkPending {
+		entry.state = LinkFailed
+		if entry.service != nil {
+			entry.state = LinkLinked
+		}
+		entry.err = err
+		entry.deviceAuth = nil
+		entry.expiresAt = time.Time{}
+	}
+}
+
+// Status returns the link of subject
+func (l *LinkService) Status(ctx context.Context, subject string) (Link, error) {
+	if _, err := l.Service(ctx, subject); err != nil {
+		return Link{}, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.links[subject]
+	if !ok {
+		return Link{State: LinkNone}, nil
+	}
+	return entry.toLink(), nil
+}
+
+// Service returns the SessionService of subject's own account, loading a login
+// saved by an earlier run. It returns nil if subject has not linked an account.
+// A login that can no longer be refreshed, on load or later, is removed and
+// the link is marked failed, so subject uses the pool until it links its
+// account again.
+func (l *LinkService) Service(ctx context.Context, subject string) (*SessionService, error) {
+	if entry, ok := l.entry(subject); ok {
+		return l.live(ctx, subject, entry), nil
+	}
+
+	// Loading does upstream and store I/O, only requests of the same subject wait for it
+	unlock, err := l.loads.lock(ctx, SessionKey{Subject: subject})
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if entry, ok := l.entry(subject); ok {
+		return entry.service, nil
+	}
+
+	store, err := l.openStore(subject)
+	if err != nil {
+		return nil, err
+	}
+	loaded := &link{state: LinkLinked}
+	loaded.service, err = NewSessionService(l.ctx, l.newClient(), store)
+	switch {
+	case errors.Is(err, sessionstore.ErrNotFound):
+		// Remember that subject uses the pool so later calls skip the store
+		loaded = &link{state: LinkNone}
+	case NeedsLogin(err):
+		log.Printf("Linked account of %s needs a new login, removing it: %v", subject, err)
+		l.removeLogin(ctx, subject, store)
+		loaded = &link{state: LinkFailed, err: fmt.Errorf("%w: %w", errLinkExpired, err)}
+	case err != nil:
+		return nil, fmt.Errorf("failed to load linked account: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if entry, ok := l.links[subject]; ok {
+		// A device flow completed in the meantime
+		if loaded.service != nil {
+			loaded.service.Close()
+		}
+		return entry.service, nil
+	}
+	l.links[subject] = loaded
+	return loaded.service, nil
+}
+
+// live returns the SessionService of entry unless it dropped its login, e.g.
+// because the refresh token was rejected. Then the service is closed, the login
+// removed and nil returned, so subject uses the pool.
+func (l *LinkService) live(ctx context.Context, subject string, entry *link) *SessionService {
+	l.mu.Lock()
+	service := entry.service
+	l.mu.Unlock()
+	// LoggedIn waits for a running login, l.mu must not be held meanwhile
+	if service == nil || service.LoggedIn() {
+		return service
+	}
+
+	l.mu.Lock()
+	if current, ok := l.links[subject]; current != entry || entry.service != service {
+		// Replaced or unlinked in the meantime
+		l.mu.Unlock()
+		if !ok {
+			return nil
+		}
+		return l.live(ctx, subject, current)
+	}
+	entry.service = nil
+	pending := entry.state == LinkPending
+	if !pending {
+		entry.state = LinkFailed
+		entry.err = errLinkExpired
+	}
+	l.mu.Unlock()
+
+	log.Printf("Linked account of %s needs a new login, removing it", subject)
+	service.Close()
+	// A pending device flow saves its login over the old one
+	if !pending {
+		if store, err := l.openStore(subject); err == nil {
+			l.removeLogin(ctx, subject, store)
+		} else {
+			log.Printf("Failed to remove linked account of %s: %v", subject, err)
+		}
+	}
+	return nil
+}
+
+// removeLogin deletes the saved login of subject, which cannot be used anymore
+func (l *LinkService) removeLogin(ctx context.Context, subject string, store sessionstore.Store) {
+	if err := store.Delete(ctx); err != nil && !errors.Is(err, sessionstore.ErrNotFound) {
+		log.Printf("Failed to remove linked account of %s: %v", subject, err)
+	}
+}
+
+// entry returns the link of subject, if it is known
+func (l *LinkService) entry(subject string) (*link, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.links[subject]
+	return entry, ok
+}
+
+// Unlink cancels a pending device flow of subject, logs its own account out and
+// removes the saved login. Game sessions for subject use the pool again.
+func (l *LinkService) Unlink(ctx context.Context, subject string) (*LogoutResult, error) {
+	service, err := l.Service(ctx, subject)
+	if err != nil {
+		// Remove a login that cannot be loaded anyway
+		log.Printf("Removing linked account of %s that failed to load: %v", subject, err)
+	}
+
+	l.mu.Lock()
+	entry, ok := l.links[subject]
+	delete(l.links, subject)
+	l.mu.Unlock()
+
+	if ok && entry.cancel != nil {
+		entry.cancel()
+	}
+	if service == nil {
+		store, err := l.openStore(subject)
+		if err != nil {
+			return nil, err
+		}
+		if err := store.Delete(ctx); err != nil {
+			return nil, err
+		}
+		if !ok || entry.state == LinkNone {
+			return nil, ErrNotLinked
+		}
+		return &LogoutResult{Removed: true}, nil
+	}
+
+	defer service.Close()
+	// The subject cannot retry a failed revocation, its login is removed either way
+	return service.Logout(ctx, true)
+}
+
+func (e *link) toLink() Link {
+	result := Link{State: e.state, ExpiresAt: e.expiresAt}
+	if e.deviceAuth != nil {
+		result.UserCode = e.deviceAuth.UserCode
+		result.VerificationURI = e.deviceAuth.VerificationURI
+		result.VerificationURIComplete = e.deviceAuth.VerificationURIComplete
+	}
+	if e.err != nil {
+		result.Error = e.err.Error()
+	}
+	if e.service != nil && e.state != LinkPending && e.state != LinkFailed {
+		result.Owner = e.service.Owner()
+		result.Profile = e.service.Profile()
+	}
+	return result
+}
@@ -0,0 +1,258 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hsm/internal/client"
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+	"hsm/internal/sessionstore"
+)
+
+func newLinkService(t *testing.T, backend *hsmtest.Backend, dir string) *services.LinkService {
+	t.Helper()
+	links := services.NewLinkService(func() *client.Client { return backend.Client() }, func(subject string) (sessionstore.Store, error) {
+		return sessionstore.NewFileStore(filepath.Join(dir, subject+".json")), nil
+	})
+	t.Cleanup(links.Close)
+	return links
+}
+
+// waitForLink polls the link of subject until it left the pending state
+func waitForLink(t *testing.T, links *services.LinkService, subject string) services.Link {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		link, err := links.Status(t.Context(), subject)
+		if err != nil {
+			t.Fatalf("Status: %v", err)
+		}
+		if link.State != services.LinkPending {
+			return link
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("link is still pending")
+	return services.Link{}
+}
+
+func TestLinkedAccountServesSubject(t *testing.T) {
+	pool, own := hsmtest.New(t), hsmtest.New(t)
+	links := newLinkService(t, own, t.TempDir())
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(newSessionService(t, pool)), services.WithLinks(links))
+
+	own.SetPendingAuthorizations(1)
+	pending, err := links.Start(t.Context(), "alice")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if pending.State != services.LinkPending || pending.UserCode == "" || pending.VerificationURI == "" {
+		t.Fatalf("unexpected pending link %+v", pending)
+	}
+	if link := waitForLink(t, links, "alice"); link.State != services.LinkLinked || link.Owner == "" {
+		t.Fatalf("unexpected link %+v", link)
+	}
+
+	alice, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	if !own.HasGameSession(alice.SessionToken) {
+		t.Fatal("expected alice's session on the linked account")
+	}
+	bob, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "bob"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	if !pool.HasGameSession(bob.SessionToken) {
+		t.Fatal("expected bob's session on the pool")
+	}
+
+	result, err := svc.Unlink(t.Context(), "alice")
+	if err != nil {
+		t.Fatalf("Unlink: %v", err)
+	}
+	if result.TerminatedGameSessions != 1 || !result.Revoked || !result.Removed {
+		t.Fatalf("unexpected unlink result %+v", result)
+	}
+	if own.HasGameSession(alice.SessionToken) {
+		t.Fatal("expected the linked game session to be terminated")
+	}
+
+	alice, err = svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	if !pool.HasGameSession(alice.SessionToken) {
+		t.Fatal("expected alice back on the pool after unlinking")
+	}
+	if _, err := svc.Unlink(t.Context(), "alice"); !errors.Is(err, services.ErrNotLinked) {
+		t.Fatalf("expected ErrNotLinked, got %v", err)
+	}
+}
+
+func TestLinkedAccountSurvivesRestart(t *testing.T) {
+	own := hsmtest.New(t)
+	dir := t.TempDir()
+
+	links := newLinkService(t, own, dir)
+	if _, err := links.Start(t.Context(), "alice"); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitForLink(t, links, "alice")
+	links.Close()
+
+	restarted := newLinkService(t, own, dir)
+	link, err := restarted.Status(t.Context(), "alice")
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if link.State != services.LinkLinked {
+		t.Fatalf("expected the saved link to be loaded, got %+v", link)
+	}
+	if link, _ := restarted.Status(t.Context(), "bob"); link.State != services.LinkNone {
+		t.Fatalf("expected bob without link, got %+v", link)
+	}
+}
+
+func TestLinkDenied(t *testing.T) {
+	own := hsmtest.New(t)
+	links := newLinkService(t, own, t.TempDir())
+
+	own.SetPendingAuthorizations(1)
+	own.FailNext(hsmtest.RouteToken, 400, `{"error":"access_denied"}`)
+	if _, err := links.Start(t.Context(), "alice"); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	link := waitForLink(t, links, "alice")
+	if link.State != services.LinkFailed || link.Error == "" {
+		t.Fatalf("expected a failed link, got %+v", link)
+	}
+}
+
+func TestLinkedAccountWithDeadLoginFallsBackToPool(t *testing.T) {
+	pool, own := hsmtest.New(t), hsmtest.New(t)
+	dir := t.TempDir()
+	dead := &client.Session{Token: "revoked", RefreshToken: "revoked", ExpiresAt: time.Now().Add(-time.Hour)}
+	if err := sessionstore.NewFileStore(filepath.Join(dir, "alice.json")).Save(t.Context(), dead); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	links := newLinkService(t, own, dir)
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(newSessionService(t, pool)), services.WithLinks(links))
+
+	if _, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", ""); err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	if pool.GameSessionCount() != 1 {
+		t.Fatal("expected the session on the pool")
+	}
+	link, err := links.Status(t.Context(), "alice")
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if link.State != services.LinkFailed || link.Error == "" {
+		t.Fatalf("expected a failed link, got %+v", link)
+	}
+	if _, err := sessionstore.NewFileStore(filepath.Join(dir, "alice.json")).Load(t.Context()); !errors.Is(err, sessionstore.ErrNotFound) {
+		t.Fatalf("expected the dead login to be removed, got %v", err)
+	}
+}
+
+// blockingStore blocks loading until release is closed
+type blockingStore struct {
+	sessionstore.Store
+	release chan struct{}
+}
+
+func (s *blockingStore) Load(ctx context.Context) (*client.Session, error) {
+	<-s.release
+	return s.Store.Load(ctx)
+}
+
+func TestLinkServiceLoadsSubjectsInParallel(t *testing.T) {
+	own := hsmtest.New(t)
+	dir := t.TempDir()
+	release := make(chan struct{})
+	defer close(release)
+	links := services.NewLinkService(func() *client.Client { return own.Client() }, func(subject string) (sessionstore.Store, error) {
+		store := sessionstore.NewFileStore(filepath.Join(dir, subject+".json"))
+		if subject == "slow" {
+			return &blockingStore{Store: store, release: release}, nil
+		}
+		return store, nil
+	})
+	t.Cleanup(links.Close)
+
+	go func() { _, _ = links.Service(context.Background(), "slow") }()
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := links.Service(t.Context(), "fast"); err != nil {
+			t.Errorf("Service: %v", err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("loading one subject blocked another")
+	}
+}
+
+func TestLinkedAccountThatLostItsLoginFallsBackToPool(t *testing.T) {
+	pool, own := hsmtest.New(t), hsmtest.New(t)
+	dir := t.TempDir()
+	links := newLinkService(t, own, dir)
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(newSessionService(t, pool)), services.WithLinks(links))
+
+	if _, err := links.Start(t.Context(), "alice"); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitForLink(t, links, "alice")
+	linked, err := links.Service(t.Context(), "alice")
+	if err != nil || linked == nil {
+		t.Fatalf("Service: %v", err)
+	}
+	// The linked login is dropped at runtime, like after a rejected refresh
+	if _, err := linked.Logout(t.Context(), true); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+
+	if _, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", ""); err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	if pool.GameSessionCount() != 1 {
+		t.Fatal("expected the session on the pool")
+	}
+	if link, _ := links.Status(t.Context(), "alice"); link.State != services.LinkFailed || link.Error == "" {
+		t.Fatalf("expected a failed link, got %+v", link)
+	}
+}
+
+func TestFailedRelinkKeepsLinkedAccount(t *testing.T) {
+	own := hsmtest.New(t)
+	links := newLinkService(t, own, t.TempDir())
+
+	if _, err := links.Start(t.Context(), "alice"); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitForLink(t, links, "alice")
+
+	own.SetPendingAuthorizations(1)
+	own.FailNext(hsmtest.RouteToken, 400, `{"error":"access_denied"}`)
+	if _, err := links.Start(t.Context(), "alice"); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	link := waitForLink(t, links, "alice")
+	if link.State != services.LinkLinked || link.Owner == "" || link.Error == "" {
+		t.Fatalf("expected the earlier link to stay with the error of the new one, got %+v", link)
+	}
+	if service, err := links.Service(t.Context(), "alice"); err != nil || service == nil {
+		t.Fatalf("expected the linked account to stay in use, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"hsm/internal/client"
+)
+
+// LoginRetryDelay is how long the login loop waits after a failed device flow
+const LoginRetryDelay = 10 * time.Second
+
+// LoginState is the login state of a SessionService
+type LoginState string
+
+const (
+	// LoginLoggedIn means the service holds a session
+	LoginLoggedIn LoginState = "logged_in"
+	// LoginRequired means the service has no session and no device code was issued yet
+	LoginRequired LoginState = "needs_login"
+	// LoginPending means a device code waits for authorization
+	LoginPending LoginState = "pending"
+)
+
+// LoginStatus describes the login state of a SessionService
+type LoginStatus struct {
+	State                   LoginState
+	UserCode                string
+	VerificationURI         string
+	VerificationURIComplete string
+	ExpiresAt               time.Time
+	Error                   string
+}
+
+// LoginService logs a SessionService in with the device flow whenever it has
+// no session, so a server can start without one and recover from a refresh
+// token that was revoked or expired
+type LoginService struct {
+	session    *SessionService
+	retryDelay time.Duration
+	deviceAuth *client.DeviceAuthorizationResponse
+	expiresAt  time.Time
+	lastErr    error
+	mu         sync.RWMutex
+}
+
+// NewLoginService creates a LoginService for session
+func NewLoginService(session *SessionService) *LoginService {
+	return &LoginService{
+		session:    session,
+		retryDelay: LoginRetryDelay,
+	}
+}
+
+// Run logs the session in whenever it needs a login until ctx is done
+func (l *LoginService) Run(ctx context.Context) {
+	for {
+		if !l.session.LoggedIn() {
+			l.login(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-l.session.LoginRequired():
+		}
+	}
+}
+
+// login runs the device flow until the session is logged in or ctx is done
+func (l *LoginService) login(ctx context.Context) {
+	for ctx.Err() == nil && !l.session.LoggedIn() {
+		// Keep a valid code available until someone logs in
+		deviceFlow := NewDeviceFlowService(l.session.Client(), WithRestartOnExpiry(math.MaxInt), WithPrompt(l.prompt))
+		session, err := deviceFlow.Flow(ctx)
+		if err == nil {
+			err = l.session.Login(ctx, session)
+		}
+
+		l.mu.Lock()
+		l.deviceAuth = nil
+		l.expiresAt = time.Time{}
+		l.lastErr = err
+		l.mu.Unlock()
+
+		if err == nil {
+			log.Println("Logged in")
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Login failed, retrying in %v: %v", l.retryDelay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(l.retryDelay):
+		}
+	}
+}
+
+// prompt records the device code and logs it like the CLI login
+func (l *LoginService) prompt(deviceAuth *client.DeviceAuthorizationResponse) {
+	l.mu.Lock()
+	l.deviceAuth = deviceAuth
+	l.expiresAt = time.Time{}
+	if deviceAuth.ExpiresIn > 0 {
+		l.expiresAt = time.Now().Add(time.Duration(deviceAuth.ExpiresIn) * time.Second)
+	}
+	l.mu.Unlock()
+	logPrompt(deviceAuth)
+}
+
+// Status returns the login state
+func (l *LoginService) Status() LoginStatus {
+	if l.session.LoggedIn() {
+		return LoginStatus{State: LoginLoggedIn}
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	status := LoginStatus{State: LoginRequired}
+	if l.lastErr != nil {
+		status.Error = l.lastErr.Error()
+	}
+	if l.deviceAuth != nil {
+		status.State = LoginPending
+		status.UserCode = l.deviceAuth.UserCode
+		status.VerificationURI = l.deviceAuth.VerificationURI
+		status.VerificationURIComplete = l.deviceAuth.VerificationURIComplete
+		status.ExpiresAt = l.expiresAt
+	}
+	return status
+}
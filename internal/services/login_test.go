@@ -0,0 +1,96 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hsm/internal/client"
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+	"hsm/internal/sessionstore"
+)
+
+func TestSessionServiceDeferredLoginWithoutSession(t *testing.T) {
+	backend := hsmtest.New(t)
+	store := sessionstore.NewFileStore(filepath.Join(t.TempDir(), "session.json"))
+
+	if _, err := services.NewSessionService(t.Context(), backend.Client(), store); !errors.Is(err, sessionstore.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound without deferred login, got %v", err)
+	}
+
+	svc, err := services.NewSessionService(t.Context(), backend.Client(), store, services.WithDeferredLogin())
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+	defer svc.Close()
+
+	if svc.LoggedIn() {
+		t.Fatal("expected the service to start logged out")
+	}
+	if _, err := svc.CreateGameSession(t.Context()); !errors.Is(err, services.ErrNotLoggedIn) {
+		t.Fatalf("expected ErrNotLoggedIn, got %v", err)
+	}
+}
+
+func TestSessionServiceDeferredLoginWithDeadRefreshToken(t *testing.T) {
+	backend := hsmtest.New(t)
+	sessionPath := filepath.Join(t.TempDir(), "session.json")
+	dead := &client.Session{Token: "revoked", RefreshToken: "revoked", ExpiresAt: time.Now().Add(-time.Hour)}
+	if err := sessionstore.NewFileStore(sessionPath).Save(t.Context(), dead); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	svc, err := services.NewSessionService(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath), services.WithDeferredLogin())
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+	defer svc.Close()
+
+	if svc.LoggedIn() {
+		t.Fatal("expected the service to start logged out")
+	}
+}
+
+func TestLoginServiceLogsIn(t *testing.T) {
+	backend := hsmtest.New(t)
+	store := sessionstore.NewFileStore(filepath.Join(t.TempDir(), "session.json"))
+	svc, err := services.NewSessionService(t.Context(), backend.Client(), store, services.WithDeferredLogin())
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+	defer svc.Close()
+
+	backend.SetPendingAuthorizations(1)
+	login := services.NewLoginService(svc)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	go login.Run(ctx)
+
+	var sawPending bool
+	deadline := time.Now().Add(5 * time.Second)
+	for !svc.LoggedIn() {
+		if time.Now().After(deadline) {
+			t.Fatal("login did not complete")
+		}
+		if status := login.Status(); status.State == services.LoginPending && status.UserCode != "" {
+			sawPending = true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !sawPending {
+		t.Fatal("expected the device code to be exposed while pending")
+	}
+	if login.Status().State != services.LoginLoggedIn {
+		t.Fatalf("expected logged_in, got %+v", login.Status())
+	}
+
+	if _, err := store.Load(t.Context()); err != nil {
+		t.Fatalf("expected the session to be saved: %v", err)
+	}
+	if _, err := svc.CreateGameSession(t.Context()); err != nil {
+		t.Fatalf("CreateGameSession: %v", err)
+	}
+}
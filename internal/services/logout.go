@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"hsm/internal/client"
+	"hsm/internal/sessionstore"
+)
+
+// LogoutResult summarizes what a logout cleaned up
+type LogoutResult struct {
+	TerminatedGameSessions int  // game sessions terminated upstream
+	Revoked                bool // whether the OAuth tokens were revoked
+	Removed                bool // whether the stored session was deleted
+}
+
+// Logout ends the login held by store without loading it into a SessionService,
+// so it also works for sessions that can no longer be refreshed.
+// See SessionService.Logout for the meaning of force.
+func Logout(ctx context.Context, c *client.Client, store sessionstore.Store, force bool) (*LogoutResult, error) {
+	unlock, err := sessionstore.Lock(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	session, err := store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return logout(ctx, c, session, store, force)
+}
+
+func logout(ctx context.Context, c *client.Client, session *client.Session, store sessionstore.Store, force bool) (*LogoutResult, error) {
+	result := &LogoutResult{}
+
+	// Game sessions are authenticated by their own token, terminate them while
+	// they can still be attributed to this login. Failures are not fatal, the
+	// sessions expire on their own.
+	now := time.Now()
+	for _, tracked := range session.GameSessions {
+		if tracked.Expired(now) {
+			continue
+		}
+		err := c.TerminateGameSession(ctx, tracked.Token)
+		switch {
+		case err == nil:
+			result.TerminatedGameSessions++
+		case errors.Is(err, client.ErrUnauthorized), errors.Is(err, client.ErrNotFound):
+			// Already expired or terminated
+		default:
+			log.Printf("Failed to terminate game session: %v", err)
+		}
+	}
+
+	// Revoking the refresh token first also invalidates derived access tokens on
+	// most servers, the access token is revoked explicitly for the others
+	var revokeErrs []error
+	if session.RefreshToken != "" {
+		if err := c.RevokeToken(ctx, session.RefreshToken, client.TokenTypeHintRefreshToken); err != nil {
+			revokeErrs = append(revokeErrs, fmt.Errorf("failed to revoke refresh token: %w", err))
+		}
+	}
+	if session.AccessToken != "" {
+		if err := c.RevokeToken(ctx, session.AccessToken, client.TokenTypeHintAccessToken); err != nil {
+			revokeErrs = append(revokeErrs, fmt.Errorf("failed to revoke access token: %w", err))
+		}
+	}
+	revokeErr := errors.Join(revokeErrs...)
+	result.Revoked = revokeErr == nil
+
+	// Keep the session around so the logout can be retried, unless forced
+	if revokeErr != nil && !force {
+		return result, revokeErr
+	}
+
+	if err := store.Delete(ctx); err != nil {
+		return result, errors.Join(revokeErr, err)
+	}
+	result.Removed = true
+
+	return result, revokeErr
+}
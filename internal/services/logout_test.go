@@ -0,0 +1,114 @@
+package services_test
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+	"hsm/internal/sessionstore"
+)
+
+func TestLogoutRevokesTokensAndTerminatesGameSessions(t *testing.T) {
+	backend := hsmtest.New(t)
+	sessionPath := filepath.Join(t.TempDir(), "session.json")
+	backend.WriteSessionFile(t, sessionPath)
+
+	svc, err := services.NewSessionService(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath))
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+	defer svc.Close()
+
+	kept, err := svc.CreateGameSession(t.Context())
+	if err != nil {
+		t.Fatalf("CreateGameSession: %v", err)
+	}
+	deleted, err := svc.CreateGameSession(t.Context())
+	if err != nil {
+		t.Fatalf("CreateGameSession: %v", err)
+	}
+	if err := svc.DeleteGameSession(t.Context(), deleted.SessionToken); err != nil {
+		t.Fatalf("DeleteGameSession: %v", err)
+	}
+
+	// Closing saves the tracked game sessions
+	svc.Close()
+	saved, err := sessionstore.NewFileStore(sessionPath).Load(t.Context())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(saved.GameSessions) != 1 || saved.GameSessions[0].Token != kept.SessionToken {
+		t.Fatalf("expected only the live game session to be tracked, got %v", saved.GameSessions)
+	}
+
+	// A separate process logs out using the session file alone
+	result, err := services.Logout(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath), false)
+	if err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if result.TerminatedGameSessions != 1 || !result.Revoked || !result.Removed {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if backend.HasGameSession(kept.SessionToken) {
+		t.Fatal("expected tracked game session to be terminated")
+	}
+	if backend.TokenActive(saved.AccessToken) || backend.TokenActive(saved.RefreshToken) {
+		t.Fatal("expected tokens to be revoked")
+	}
+	if _, err := os.Stat(sessionPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected session file to be removed, got %v", err)
+	}
+}
+
+func TestLogoutKeepsSessionWhenRevocationFails(t *testing.T) {
+	backend := hsmtest.New(t)
+	sessionPath := filepath.Join(t.TempDir(), "session.json")
+	backend.WriteSessionFile(t, sessionPath)
+
+	backend.FailNext(hsmtest.RouteRevoke, http.StatusBadGateway, `{"code":"bad_gateway","message":"down"}`)
+	result, err := services.Logout(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath), false)
+	if err == nil {
+		t.Fatal("expected revocation error")
+	}
+	if result.Removed {
+		t.Fatal("expected session to be kept")
+	}
+	if _, err := os.Stat(sessionPath); err != nil {
+		t.Fatalf("expected session file to remain: %v", err)
+	}
+
+	backend.FailNext(hsmtest.RouteRevoke, http.StatusBadGateway, `{"code":"bad_gateway","message":"down"}`)
+	result, err = services.Logout(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath), true)
+	if err == nil {
+		t.Fatal("expected forced logout to still report the revocation error")
+	}
+	if !result.Removed {
+		t.Fatal("expected forced logout to remove the session")
+	}
+}
+
+func TestSessionServiceLogout(t *testing.T) {
+	backend := hsmtest.New(t)
+	svc := newSessionService(t, backend)
+
+	if _, err := svc.CreateGameSession(t.Context()); err != nil {
+		t.Fatalf("CreateGameSession: %v", err)
+	}
+	if _, err := svc.Logout(t.Context(), false); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if backend.GameSessionCount() != 0 {
+		t.Fatalf("expected no game sessions, got %d", backend.GameSessionCount())
+	}
+
+	if _, err := svc.CreateGameSession(t.Context()); !errors.Is(err, services.ErrNotLoggedIn) {
+		t.Fatalf("expected ErrNotLoggedIn, got %v", err)
+	}
+	if _, err := svc.Logout(t.Context(), false); !errors.Is(err, services.ErrNotLoggedIn) {
+		t.Fatalf("expected ErrNotLoggedIn on second logout, got %v", err)
+	}
+}
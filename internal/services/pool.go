@@ -0,0 +1,287 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"hsm/internal/client"
+)
+
+// PoolStrategy selects the account that serves a subject
+type PoolStrategy string
+
+const (
+	// StrategyLeastLoaded picks the account with the fewest active game sessions
+	StrategyLeastLoaded PoolStrategy = "least-loaded"
+	// StrategySticky hashes the subject so it keeps landing on the same account
+	StrategySticky PoolStrategy = "sticky"
+	// StrategyClaim uses the account named by the caller (e.g. a JWT claim) and
+	// falls back to least-loaded for callers that do not name one
+	StrategyClaim PoolStrategy = "claim"
+)
+
+// PoolCooldown is how long an account is skipped after it failed to create a game session
+const PoolCooldown = 30 * time.Second
+
+var (
+	// ErrPoolExhausted is returned when no account in the pool could create a game session
+	ErrPoolExhausted = errors.New("no account in the pool could create a game session")
+	// ErrUnknownAccount is returned when the caller names an account that is not in the pool
+	ErrUnknownAccount = errors.New("unknown pool account")
+)
+
+// ParsePoolStrategy validates a strategy name
+func ParsePoolStrategy(name string) (PoolStrategy, error) {
+	switch s := PoolStrategy(name); s {
+	case StrategyLeastLoaded, StrategySticky, StrategyClaim:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown pool strategy %q (expected %s, %s or %s)", name, StrategyLeastLoaded, StrategySticky, StrategyClaim)
+}
+
+// PoolMember is a named account in the pool
+type PoolMember struct {
+	Name    string
+	Service *SessionService
+}
+
+// PoolAccountState is a snapshot of an account in the pool
+type PoolAccountState struct {
+	Name           string
+	Owner          string
+	Profile        client.Profile
+	LoggedIn       bool
+	ActiveSessions int
+	CooldownUntil  time.Time // zero if the account is available
+	LastError      string
+}
+
+// AccountPool spreads game sessions across several Hytale accounts so a single
+// account's session limit does not cap the whole deployment
+type AccountPool struct {
+	strategy PoolStrategy
+	members  []*poolMember
+	mu       sync.Mutex
+}
+
+type poolMember struct {
+	name          string
+	service       *SessionService
+	active        int
+	cooldownUntil time.Time
+	lastError     string
+}
+
+// NewAccountPool creates a pool of members using strategy. The first member is
+// the primary account, used wherever a single account is needed.
+func NewAccountPool(strategy PoolStrategy, members ...PoolMember) (*AccountPool, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("account pool needs at least one account")
+	}
+	p := &AccountPool{strategy: strategy}
+	for _, m := range members {
+		if slices.ContainsFunc(p.members, func(existing *poolMember) bool { return existing.name == m.Name }) {
+			return nil, fmt.Errorf("duplicate pool account %q", m.Name)
+		}
+		p.members = append(p.members, &poolMember{name: m.Name, service: m.Service})
+	}
+	return p, nil
+}
+
+// NewSingleAccountPool wraps one SessionService in a pool
+func NewSingleAccountPool(sessionService *SessionService) *AccountPool {
+	pool, _ := NewAccountPool(StrategyLeastLoaded, PoolMember{Name: "default", Service: sessionService})
+	return pool
+}
+
+// Strategy returns the strategy of the pool
+func (p *AccountPool) Strategy() PoolStrategy {
+	return p.strategy
+}
+
+// Primary returns the SessionService of the first account
+func (p *AccountPool) Primary() *SessionService {
+	return p.members[0].service
+}
+
+// Members returns the accounts of the pool
+func (p *AccountPool) Members() []PoolMember {
+	members := make([]PoolMember, len(p.members))
+	for i, m := range p.members {
+		members[i] = PoolMember{Name: m.name, Service: m.service}
+	}
+	return members
+}
+
+// Service returns the SessionService of the account with name
+func (p *AccountPool) Service(name string) (*SessionService, error) {
+	for _, m := range p.members {
+		if m.name == name {
+			return m.service, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownAccount, name)
+}
+
+// CreateGameSession creates a game session for subject on an account chosen by
+// the pool strategy. account names the account for StrategyClaim and is ignored
+// otherwise. profile selects the game profile (UUID or username) on that
+// account. When an account fails, e.g. because it hit its session limit, the
+// next candidate is tried and the failed one is skipped for PoolCooldown.
+// It returns the session and the name of the account that created it.
+func (p *AccountPool) CreateGameSession(ctx context.Context, subject, account, profile string) (*client.GameSession, string, error) {
+	candidates, err := p.candidates(subject, account)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var lastErr error
+	for _, m := range candidates {
+		session, err := m.service.CreateGameSessionForProfile(ctx, profile)
+		if err == nil {
+			p.mu.Lock()
+			m.active++
+			m.cooldownUntil = time.Time{}
+			m.lastError = ""
+			p.mu.Unlock()
+			return session, m.name, nil
+		}
+		if ctx.Err() != nil {
+			return nil, "", err
+		}
+
+		lastErr = err
+		if errors.Is(err, ErrProfileNotFound) {
+			// The profile may exist on another account, this one is healthy
+			continue
+		}
+
+		log.Printf("Pool account %s failed to create a game session, trying the next one: %v", m.name, err)
+		p.mu.Lock()
+		m.cooldownUntil = time.Now().Add(PoolCooldown)
+		m.lastError = err.Error()
+		p.mu.Unlock()
+	}
+
+	if len(candidates) == 1 {
+		return nil, "", lastErr
+	}
+	return nil, "", fmt.Errorf("%w: %w", ErrPoolExhausted, lastErr)
+}
+
+// Retain records a game session on the account that was created earlier, e.g.
+// one restored after a restart
+func (p *AccountPool) Retain(account string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, m := range p.members {
+		if m.name == account {
+			m.active++
+		}
+	}
+}
+
+// Release records that a game session created on the account has ended
+func (p *AccountPool) Release(account string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, m := range p.members {
+		if m.name == account && m.active > 0 {
+			m.active--
+			// A freed slot makes an account that hit its limit worth trying again
+			m.cooldownUntil = time.Time{}
+		}
+	}
+}
+
+// ResetCounts forgets all active game sessions, e.g. after logout
+func (p *AccountPool) ResetCounts() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, m := range p.members {
+		m.active = 0
+	}
+}
+
+// State returns a snapshot of all accounts in the pool
+func (p *AccountPool) State() []PoolAccountState {
+	p.mu.Lock()
+	states := make([]PoolAccountState, len(p.members))
+	for i, m := range p.members {
+		states[i] = PoolAccountState{
+			Name:           m.name,
+			ActiveSessions: m.active,
+			LastError:      m.lastError,
+		}
+		if time.Now().Before(m.cooldownUntil) {
+			states[i].CooldownUntil = m.cooldownUntil
+		}
+	}
+	p.mu.Unlock()
+
+	// The services wait for a running login, the pool must stay usable meanwhile
+	for i, m := range p.members {
+		states[i].Owner = m.service.Owner()
+		states[i].Profile = m.service.Profile()
+		states[i].LoggedIn = m.service.LoggedIn()
+	}
+	return states
+}
+
+// candidates orders the accounts to try for subject. Accounts in cooldown go
+// last so they are still used when every account is failing.
+func (p *AccountPool) candidates(subject, account string) ([]*poolMember, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.strategy == StrategyClaim && account != "" {
+		for _, m := range p.members {
+			if m.name == account {
+				return []*poolMember{m}, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAccount, account)
+	}
+
+	ordered := slices.Clone(p.members)
+	switch p.strategy {
+	case StrategySticky:
+		// Rendezvous hashing keeps most subjects on their account when accounts are added or removed
+		slices.SortStableFunc(ordered, func(a, b *poolMember) int {
+			return cmp.Compare(rendezvousScore(subject, b.name), rendezvousScore(subject, a.name))
+		})
+	default:
+		slices.SortStableFunc(ordered, func(a, b *poolMember) int {
+			return cmp.Compare(a.active, b.active)
+		})
+	}
+
+	now := time.Now()
+	slices.SortStableFunc(ordered, func(a, b *poolMember) int {
+		aCooling, bCooling := now.Before(a.cooldownUntil), now.Before(b.cooldownUntil)
+		switch {
+		case aCooling == bCooling:
+			return 0
+		case aCooling:
+			return 1
+		default:
+			return -1
+		}
+	})
+	return ordered, nil
+}
+
+func rendezvousScore(subject, account string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(subject))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(account))
+	return h.Sum64()
+}
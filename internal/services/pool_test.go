@@ -0,0 +1,157 @@
+package services_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+)
+
+// newPool creates a pool with one account per backend, named a, b, ...
+func newPool(t *testing.T, strategy services.PoolStrategy, backends ...*hsmtest.Backend) *services.AccountPool {
+	t.Helper()
+	var members []services.PoolMember
+	for i, backend := range backends {
+		members = append(members, services.PoolMember{Name: string(rune('a' + i)), Service: newSessionService(t, backend)})
+	}
+	pool, err := services.NewAccountPool(strategy, members...)
+	if err != nil {
+		t.Fatalf("NewAccountPool: %v", err)
+	}
+	return pool
+}
+
+func TestAccountPoolLeastLoadedSpreadsSessions(t *testing.T) {
+	a, b := hsmtest.New(t), hsmtest.New(t)
+	svc := services.NewUserSessionService(newPool(t, services.StrategyLeastLoaded, a, b))
+
+	for _, subject := range []string{"alice", "bob", "carol", "dave"} {
+		if _, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: subject}, "", ""); err != nil {
+			t.Fatalf("GetOrCreateSession(%s): %v", subject, err)
+		}
+	}
+
+	if a.GameSessionCount() != 2 || b.GameSessionCount() != 2 {
+		t.Fatalf("expected 2 sessions per account, got %d and %d", a.GameSessionCount(), b.GameSessionCount())
+	}
+	for _, state := range svc.Pool().State() {
+		if state.ActiveSessions != 2 {
+			t.Fatalf("account %s: expected 2 active sessions, got %d", state.Name, state.ActiveSessions)
+		}
+	}
+
+	if err := svc.DeleteSession(t.Context(), services.SessionKey{Subject: "alice"}); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	total := 0
+	for _, state := range svc.Pool().State() {
+		total += state.ActiveSessions
+	}
+	if total != 3 {
+		t.Fatalf("expected 3 active sessions after delete, got %d", total)
+	}
+}
+
+func TestAccountPoolFailsOverOnSessionLimit(t *testing.T) {
+	a, b := hsmtest.New(t), hsmtest.New(t)
+	pool := newPool(t, services.StrategyLeastLoaded, a, b)
+	svc := services.NewUserSessionService(pool)
+
+	a.FailNext(hsmtest.RouteGameSessionNew, http.StatusForbidden, `{"code":"session_limit_reached"}`)
+	session, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	if !b.HasGameSession(session.SessionToken) {
+		t.Fatal("expected the session on the second account")
+	}
+
+	state := pool.State()
+	if state[0].CooldownUntil.IsZero() || state[0].LastError == "" {
+		t.Fatalf("expected the first account in cooldown, got %+v", state[0])
+	}
+
+	// The account in cooldown is skipped although it is less loaded
+	if _, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "bob"}, "", ""); err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	if b.GameSessionCount() != 2 {
+		t.Fatalf("expected both sessions on the second account, got %d", b.GameSessionCount())
+	}
+}
+
+func TestAccountPoolExhausted(t *testing.T) {
+	a, b := hsmtest.New(t), hsmtest.New(t)
+	svc := services.NewUserSessionService(newPool(t, services.StrategyLeastLoaded, a, b))
+
+	a.FailNext(hsmtest.RouteGameSessionNew, http.StatusForbidden, `{"code":"session_limit_reached"}`)
+	b.FailNext(hsmtest.RouteGameSessionNew, http.StatusForbidden, `{"code":"session_limit_reached"}`)
+	_, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+	if !errors.Is(err, services.ErrPoolExhausted) {
+		t.Fatalf("expected ErrPoolExhausted, got %v", err)
+	}
+}
+
+func TestAccountPoolStickyKeepsSubjectOnAccount(t *testing.T) {
+	a, b := hsmtest.New(t), hsmtest.New(t)
+	svc := services.NewUserSessionService(newPool(t, services.StrategySticky, a, b))
+
+	first, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	backend := a
+	if b.HasGameSession(first.SessionToken) {
+		backend = b
+	}
+
+	for range 3 {
+		if err := svc.DeleteSession(t.Context(), services.SessionKey{Subject: "alice"}); err != nil {
+			t.Fatalf("DeleteSession: %v", err)
+		}
+		session, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+		if err != nil {
+			t.Fatalf("GetOrCreateSession: %v", err)
+		}
+		if !backend.HasGameSession(session.SessionToken) {
+			t.Fatal("expected the subject to stay on its account")
+		}
+	}
+}
+
+func TestAccountPoolClaimSelectsAccount(t *testing.T) {
+	a, b := hsmtest.New(t), hsmtest.New(t)
+	svc := services.NewUserSessionService(newPool(t, services.StrategyClaim, a, b))
+
+	session, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "b")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	if !b.HasGameSession(session.SessionToken) {
+		t.Fatal("expected the session on the claimed account")
+	}
+
+	// Claiming another account moves the subject
+	moved, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "a")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	if !a.HasGameSession(moved.SessionToken) || b.HasGameSession(session.SessionToken) {
+		t.Fatal("expected the session to move to the claimed account")
+	}
+
+	if _, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "bob"}, "", "unknown"); !errors.Is(err, services.ErrUnknownAccount) {
+		t.Fatalf("expected ErrUnknownAccount, got %v", err)
+	}
+}
+
+func TestParsePoolStrategy(t *testing.T) {
+	if _, err := services.ParsePoolStrategy("sticky"); err != nil {
+		t.Fatalf("ParsePoolStrategy: %v", err)
+	}
+	if _, err := services.ParsePoolStrategy("random"); err == nil {
+		t.Fatal("expected an error for an unknown strategy")
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"hsm/internal/client"
+)
+
+// ErrProfileNotFound is returned when a profile selector matches no profile of the account
+var ErrProfileNotFound = errors.New("profile not found")
+
+// ResolveProfile returns the profile matching selector, which may be a profile UUID
+// or a username (case-insensitive). An empty selector returns the first profile.
+func ResolveProfile(profiles []client.Profile, selector string) (client.Profile, error) {
+	if len(profiles) == 0 {
+		return client.Profile{}, fmt.Errorf("no profiles found")
+	}
+	if selector == "" {
+		return profiles[0], nil
+	}
+
+	for _, p := range profiles {
+		if strings.EqualFold(p.UUID, selector) {
+			return p, nil
+		}
+	}
+	for _, p := range profiles {
+		if strings.EqualFold(p.Username, selector) {
+			return p, nil
+		}
+	}
+
+	names := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		names = append(names, p.Username)
+	}
+	return client.Profile{}, fmt.Errorf("%w: %q (available: %s)", ErrProfileNotFound, selector, strings.Join(names, ", "))
+}
@@ -0,0 +1,98 @@
+package services_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"hsm/internal/client"
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+	"hsm/internal/sessionstore"
+)
+
+var secondProfile = client.Profile{UUID: "00000000-0000-0000-0000-000000000002", Username: "Builder"}
+
+func TestResolveProfile(t *testing.T) {
+	profiles := []client.Profile{hsmtest.DefaultProfile, secondProfile}
+
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"", hsmtest.DefaultProfile.UUID},
+		{secondProfile.UUID, secondProfile.UUID},
+		{"builder", secondProfile.UUID},
+	}
+	for _, tt := range tests {
+		got, err := services.ResolveProfile(profiles, tt.selector)
+		if err != nil {
+			t.Fatalf("ResolveProfile(%q): %v", tt.selector, err)
+		}
+		if got.UUID != tt.want {
+			t.Fatalf("ResolveProfile(%q) = %s, want %s", tt.selector, got.UUID, tt.want)
+		}
+	}
+
+	if _, err := services.ResolveProfile(profiles, "nobody"); !errors.Is(err, services.ErrProfileNotFound) {
+		t.Fatalf("expected ErrProfileNotFound, got %v", err)
+	}
+}
+
+func TestSessionServiceProfileSelection(t *testing.T) {
+	backend := hsmtest.New(t)
+	backend.SetProfiles(hsmtest.DefaultProfile, secondProfile)
+	sessionPath := filepath.Join(t.TempDir(), "session.json")
+
+	// The profile persisted at login is used by default
+	session := backend.IssueSession()
+	session.Profile = secondProfile.UUID
+	if err := sessionstore.NewFileStore(sessionPath).Save(t.Context(), session); err != nil {
+		t.Fatal(err)
+	}
+
+	svc, err := services.NewSessionService(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath))
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+	defer svc.Close()
+	if svc.Profile().UUID != secondProfile.UUID {
+		t.Fatalf("expected persisted profile, got %+v", svc.Profile())
+	}
+
+	// An explicit selector overrides it
+	override, err := services.NewSessionService(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath), services.WithProfile(hsmtest.DefaultProfile.Username))
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+	defer override.Close()
+	if override.Profile().UUID != hsmtest.DefaultProfile.UUID {
+		t.Fatalf("expected selected profile, got %+v", override.Profile())
+	}
+
+	if _, err := services.NewSessionService(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath), services.WithProfile("nobody")); !errors.Is(err, services.ErrProfileNotFound) {
+		t.Fatalf("expected ErrProfileNotFound, got %v", err)
+	}
+}
+
+func TestUserSessionServiceSwitchesProfile(t *testing.T) {
+	backend := hsmtest.New(t)
+	backend.SetProfiles(hsmtest.DefaultProfile, secondProfile)
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(newSessionService(t, backend)))
+
+	first, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	second, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, secondProfile.Username, "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+
+	if first.SessionToken == second.SessionToken {
+		t.Fatal("expected a new session for a different profile")
+	}
+	if backend.HasGameSession(first.SessionToken) {
+		t.Fatal("expected the session of the previous profile to be terminated")
+	}
+}
@@ -2,10 +2,12 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"hsm/internal/client"
-	"hsm/internal/utils"
+	"hsm/internal/sessionstore"
 	"log"
+	"slices"
 	"sync"
 	"time"
 )
@@ -13,83 +15,252 @@ import (
 const (
 	RefreshThreshold     = 5 * time.Minute
 	RefreshCheckInterval = 1 * time.Minute
+
+	// gameSessionSaveDelay collects the game session changes of a burst into
+	// one write of the store
+	gameSessionSaveDelay = time.Second
+	// maxTrackedGameSessions bounds the game sessions kept for logout, the
+	// oldest are dropped first
+	maxTrackedGameSessions = 1000
+)
+
+var (
+	// ErrNotLoggedIn is returned by operations that need an OAuth session after logout
+	ErrNotLoggedIn = errors.New("not logged in")
+	// ErrSessionExpired is returned when the stored session expired and cannot be refreshed
+	ErrSessionExpired = errors.New("session expired and no refresh token available")
 )
 
+// NeedsLogin reports whether err means the stored session is missing or cannot
+// be used anymore, so only a new login helps
+func NeedsLogin(err error) bool {
+	return errors.Is(err, ErrNotLoggedIn) ||
+		errors.Is(err, ErrSessionExpired) ||
+		errors.Is(err, sessionstore.ErrNotFound) ||
+		errors.Is(err, client.ErrUnauthorized)
+}
+
 // SessionService is a stateless service that wraps the Hytale API client.
 // It handles OAuth token management but does not track game sessions.
 type SessionService struct {
-	client      *client.Client
-	profileId   string
-	sessionPath string
-	session     *client.Session
-	mu          sync.RWMutex
-	cancel      context.CancelFunc
+	client          *client.Client
+	profileSelector string
+	profile         client.Profile
+	profiles        []client.Profile
+	owner           string
+	store           sessionstore.Store
+	session         *client.Session
+	deferredLogin   bool
+	loginRequired   chan struct{}
+	mu              sync.RWMutex
+	cancel          context.CancelFunc
+
+	// Game session changes not saved to the store yet, guarded by trackMu
+	trackAdded   map[string]time.Time // token -> expiry
+	trackRemoved map[string]bool
+	trackMu      sync.Mutex
+	tracked      chan struct{}
 }
 
-func NewSessionService(c *client.Client, sessionPath string) (*SessionService, error) {
-	svc := &SessionService{
-		client:      c,
-		sessionPath: sessionPath,
+// SessionOption is a functional option for configuring the SessionService
+type SessionOption func(*SessionService)
+
+// WithProfile selects the game profile (UUID or username) used for game sessions,
+// overriding the profile stored in the session file
+func WithProfile(selector string) SessionOption {
+	return func(s *SessionService) {
+		s.profileSelector = selector
 	}
+}
 
-	if err := svc.loadAndRefreshSession(); err != nil {
-		return nil, err
+// WithDeferredLogin starts the service logged out instead of failing when the
+// store holds no usable session. Login installs a session later.
+func WithDeferredLogin() SessionOption {
+	return func(s *SessionService) {
+		s.deferredLogin = true
 	}
+}
 
-	c.WithToken(svc.session.Token)
+// NewSessionService loads the session from store, refreshing it if needed,
+// and keeps it fresh in the background until Close is called
+func NewSessionService(ctx context.Context, c *client.Client, store sessionstore.Store, opts ...SessionOption) (*SessionService, error) {
+	svc := &SessionService{
+		client:        c,
+		store:         store,
+		loginRequired: make(chan struct{}, 1),
+		trackAdded:    make(map[string]time.Time),
+		trackRemoved:  make(map[string]bool),
+		tracked:       make(chan struct{}, 1),
+	}
 
-	profiles, err := c.GetProfiles()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get profiles: %w", err)
+	for _, opt := range opts {
+		opt(svc)
 	}
-	if len(profiles.Profiles) == 0 {
-		return nil, fmt.Errorf("no profiles found")
+
+	err := svc.loadAndRefreshSession(ctx)
+	if err == nil {
+		err = svc.loadProfiles(ctx)
+	}
+	if err != nil {
+		if !svc.deferredLogin || !NeedsLogin(err) {
+			return nil, err
+		}
+		log.Printf("No usable session in %s, login required: %v", store, err)
+		svc.dropSession()
 	}
-	svc.profileId = profiles.Profiles[0].UUID
 
-	ctx, cancel := context.WithCancel(context.Background())
+	refreshCtx, cancel := context.WithCancel(context.Background())
 	svc.cancel = cancel
-	go svc.keepSessionFresh(ctx)
+	go svc.keepSessionFresh(refreshCtx)
 
 	return svc, nil
 }
 
-func (s *SessionService) loadAndRefreshSession() error {
+// loadProfiles fetches the profiles of the account and selects the default one
+func (s *SessionService) loadProfiles(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.loadProfilesLocked(ctx, s.session)
+}
 
-	session, err := utils.ReadSessionFromFile(s.sessionPath)
+func (s *SessionService) loadProfilesLocked(ctx context.Context, session *client.Session) error {
+	profiles, err := s.client.GetProfiles(ctx)
 	if err != nil {
+		return fmt.Errorf("failed to get profiles: %w", err)
+	}
+
+	// An explicit selector wins over the profile persisted with the session
+	selector := s.profileSelector
+	if selector == "" {
+		selector = session.Profile
+	}
+	profile, err := ResolveProfile(profiles.Profiles, selector)
+	if err != nil {
+		return err
+	}
+	s.profile = profile
+	s.profiles = profiles.Profiles
+	s.owner = profiles.Owner
+	return nil
+}
+
+// Login installs session, obtained by a new login such as the device flow, and
+// saves it to the store
+func (s *SessionService) Login(ctx context.Context, session *client.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.client.WithToken(session.Token)
+	if err := s.loadProfilesLocked(ctx, session); err != nil {
+		s.client.WithToken(tokenOf(s.session))
 		return err
 	}
+
+	unlock, err := sessionstore.Lock(ctx, s.store)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := s.store.Save(ctx, session); errors.Is(err, sessionstore.ErrReadOnly) {
+		log.Printf("Session store %s is read-only, keeping the new session in memory", s.store)
+	} else if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	s.session = session
+	return nil
+}
+
+// LoginRequired is signalled whenever the service drops its session, e.g. on
+// logout or when the refresh token was rejected
+func (s *SessionService) LoginRequired() <-chan struct{} {
+	return s.loginRequired
+}
+
+// dropSession forgets the session and signals LoginRequired
+func (s *SessionService) dropSession() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropSessionLocked()
+}
+
+func (s *SessionService) dropSessionLocked() {
+	s.session = nil
+	s.client.WithToken("")
+	select {
+	case s.loginRequired <- struct{}{}:
+	default:
+	}
+}
+
+func tokenOf(session *client.Session) string {
+	if session == nil {
+		return ""
+	}
+	return session.Token
+}
+
+// loadAndRefreshSession loads the session from the store and refreshes it if it
+// is about to expire. The store is locked meanwhile, so when several processes
+// share it only one of them refreshes and the others adopt the rotated tokens
+// instead of refreshing with an already used refresh token.
+func (s *SessionService) loadAndRefreshSession(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := sessionstore.Lock(ctx, s.store)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	session, err := s.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	// Read-only stores keep returning the original session, prefer the
+	// refreshed one held in memory
+	if s.session != nil && s.session.ExpiresAt.After(session.ExpiresAt) {
+		session = s.session
+	}
 	if session.Token == "" {
 		return fmt.Errorf("invalid session: missing token")
 	}
 
-	if session.NeedsRefresh(RefreshThreshold) {
+	switch {
+	case session.NeedsRefresh(RefreshThreshold):
 		if session.RefreshToken == "" {
-			return fmt.Errorf("session expired and no refresh token available")
+			return ErrSessionExpired
 		}
-		newSession, err := s.client.RefreshAccessToken(session.RefreshToken)
+		newSession, err := s.client.RefreshAccessToken(ctx, session.RefreshToken)
 		if err != nil {
 			return fmt.Errorf("failed to refresh token: %w", err)
 		}
 		if newSession.RefreshToken == "" {
 			newSession.RefreshToken = session.RefreshToken
 		}
-		if err := utils.SaveSessionToFile(s.sessionPath, newSession); err != nil {
+		newSession.Profile = session.Profile
+		newSession.GameSessions = session.GameSessions
+		if err := s.store.Save(ctx, newSession); errors.Is(err, sessionstore.ErrReadOnly) {
+			log.Printf("Session store %s is read-only, keeping the refreshed session in memory", s.store)
+		} else if err != nil {
 			return fmt.Errorf("failed to save session: %w", err)
 		}
 		session = newSession
+		log.Println("Session refreshed")
+	case s.session == nil:
+		log.Println("Session loaded")
+	case session.Token != s.session.Token:
+		log.Println("Session was refreshed by another process, reloaded it")
 	}
 
 	s.session = session
 	s.client.WithToken(session.Token)
-
-	log.Println("Session loaded and refreshed")
 	return nil
 }
 
+// keepSessionFresh re-reads the store periodically, picking up tokens rotated by
+// other processes and refreshing the session before it expires
 func (s *SessionService) keepSessionFresh(ctx context.Context) {
 	ticker := time.NewTicker(RefreshCheckInterval)
 	defer ticker.Stop()
@@ -99,24 +270,39 @@ func (s *SessionService) keepSessionFresh(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			s.mu.RLock()
-			needsRefresh := s.session != nil && s.session.NeedsRefresh(RefreshThreshold)
-			s.mu.RUnlock()
-
-			if needsRefresh {
-				log.Println("Refreshing OAuth session...")
-				if err := s.loadAndRefreshSession(); err != nil {
-					log.Printf("Failed to refresh session: %v", err)
-				}
+			s.checkSession(ctx)
+		case <-s.tracked:
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(gameSessionSaveDelay):
 			}
+			s.saveGameSessions(ctx)
 		}
 	}
 }
 
+// checkSession reloads and refreshes the session of a logged in service. When
+// only a new login helps, e.g. the refresh token was rejected, the session is
+// dropped and LoginRequired is signalled.
+func (s *SessionService) checkSession(ctx context.Context) {
+	if !s.LoggedIn() {
+		return
+	}
+	if err := s.loadAndRefreshSession(ctx); err != nil {
+		log.Printf("Failed to refresh session: %v", err)
+		if NeedsLogin(err) {
+			s.dropSession()
+		}
+	}
+}
+
+// Close stops the background refresh and saves the pending game session changes
 func (s *SessionService) Close() {
 	if s.cancel != nil {
 		s.cancel()
 	}
+	s.saveGameSessions(context.Background())
 }
 
 // Client returns the underlying API client
@@ -124,17 +310,214 @@ func (s *SessionService) Client() *client.Client {
 	return s.client
 }
 
-// CreateGameSession creates a new game session via the API
-func (s *SessionService) CreateGameSession() (*client.GameSession, error) {
-	return s.client.CreateGameSession(s.profileId)
+// Owner returns the account owner reported by the profiles endpoint
+func (s *SessionService) Owner() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.owner
+}
+
+// Profile returns the profile used for game sessions by default
+func (s *SessionService) Profile() client.Profile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.profile
+}
+
+// Profiles returns all profiles of the account
+func (s *SessionService) Profiles() []client.Profile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.profiles
+}
+
+// ResolveProfile returns the account profile matching selector (UUID or username).
+// An empty selector returns the default profile.
+func (s *SessionService) ResolveProfile(selector string) (client.Profile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if selector == "" {
+		return s.profile, nil
+	}
+	return ResolveProfile(s.profiles, selector)
+}
+
+// CreateGameSession creates a new game session for the default profile via the API
+func (s *SessionService) CreateGameSession(ctx context.Context) (*client.GameSession, error) {
+	return s.CreateGameSessionForProfile(ctx, "")
+}
+
+// CreateGameSessionForProfile creates a new game session for the profile matching
+// selector (UUID or username). An empty selector uses the default profile.
+func (s *SessionService) CreateGameSessionForProfile(ctx context.Context, selector string) (*client.GameSession, error) {
+	if !s.LoggedIn() {
+		return nil, ErrNotLoggedIn
+	}
+	profile, err := s.ResolveProfile(selector)
+	if err != nil {
+		return nil, err
+	}
+	gameSession, err := s.client.CreateGameSession(ctx, profile.UUID)
+	if err != nil {
+		return nil, err
+	}
+	s.trackGameSession(gameSession, "")
+	return gameSession, nil
 }
 
 // DeleteGameSession terminates a game session via the API
-func (s *SessionService) DeleteGameSession(sessionToken string) error {
-	return s.client.TerminateGameSession(sessionToken)
+func (s *SessionService) DeleteGameSession(ctx context.Context, sessionToken string) error {
+	if !s.LoggedIn() {
+		return ErrNotLoggedIn
+	}
+	if err := s.client.TerminateGameSession(ctx, sessionToken); err != nil {
+		return err
+	}
+	s.trackGameSession(nil, sessionToken)
+	return nil
 }
 
 // RefreshGameSession refreshes a game session via the API
-func (s *SessionService) RefreshGameSession(sessionToken string) (*client.GameSession, error) {
-	return s.client.RefreshGameSession(sessionToken)
+func (s *SessionService) RefreshGameSession(ctx context.Context, sessionToken string) (*client.GameSession, error) {
+	if !s.LoggedIn() {
+		return nil, ErrNotLoggedIn
+	}
+	gameSession, err := s.client.RefreshGameSession(ctx, sessionToken)
+	if err != nil {
+		return nil, err
+	}
+	removed := ""
+	if gameSession.SessionToken != sessionToken {
+		removed = sessionToken
+	}
+	s.trackGameSession(gameSession, removed)
+	return gameSession, nil
+}
+
+// LoggedIn reports whether the service holds an OAuth session
+func (s *SessionService) LoggedIn() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.session != nil
+}
+
+// trackGameSession records an added or refreshed and forgets a removed game
+// session, so logout can terminate them. The changes are saved to the stored
+// session in the background, nil and empty arguments are ignored.
+func (s *SessionService) trackGameSession(added *client.GameSession, removed string) {
+	s.trackMu.Lock()
+	if removed != "" {
+		delete(s.trackAdded, removed)
+		s.trackRemoved[removed] = true
+	}
+	if added != nil {
+		delete(s.trackRemoved, added.SessionToken)
+		s.trackAdded[added.SessionToken] = added.ExpiresAt
+	}
+	s.trackMu.Unlock()
+
+	select {
+	case s.tracked <- struct{}{}:
+	default:
+	}
+}
+
+// saveGameSessions merges the pending game session changes into the stored session
+func (s *SessionService) saveGameSessions(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saveGameSessionsLocked(ctx)
+}
+
+func (s *SessionService) saveGameSessionsLocked(ctx context.Context) {
+	s.trackMu.Lock()
+	added, removed := s.trackAdded, s.trackRemoved
+	s.trackAdded, s.trackRemoved = make(map[string]time.Time), make(map[string]bool)
+	s.trackMu.Unlock()
+
+	if s.session == nil || len(added) == 0 && len(removed) == 0 {
+		return
+	}
+
+	unlock, err := sessionstore.Lock(ctx, s.store)
+	if err != nil {
+		log.Printf("Failed to track game sessions: %v", err)
+		return
+	}
+	defer unlock()
+
+	// Update the stored session rather than overwriting it with ours, another
+	// process may have rotated the tokens or tracked its own game sessions
+	session := s.session
+	if stored, err := s.store.Load(ctx); err == nil && !s.session.ExpiresAt.After(stored.ExpiresAt) {
+		session = stored
+	}
+	session.GameSessions = mergeGameSessions(session.GameSessions, added, removed, time.Now())
+
+	if session.Token != s.session.Token {
+		s.client.WithToken(session.Token)
+	}
+	s.session = session
+	if err := s.store.Save(ctx, session); err != nil && !errors.Is(err, sessionstore.ErrReadOnly) {
+		log.Printf("Failed to save tracked game sessions: %v", err)
+	}
+}
+
+// mergeGameSessions applies added and removed game sessions to tracked and
+// drops the ones that expired before now
+func mergeGameSessions(tracked []client.TrackedGameSession, added map[string]time.Time, removed map[string]bool, now time.Time) []client.TrackedGameSession {
+	merged := make([]client.TrackedGameSession, 0, len(tracked)+len(added))
+	known := make(map[string]bool, len(tracked))
+	for _, t := range tracked {
+		if removed[t.Token] {
+			continue
+		}
+		if expiresAt, ok := added[t.Token]; ok {
+			t.ExpiresAt = expiresAt
+		}
+		known[t.Token] = true
+		merged = append(merged, t)
+	}
+	for token, expiresAt := range added {
+		if !known[token] {
+			merged = append(merged, client.TrackedGameSession{Token: token, ExpiresAt: expiresAt})
+		}
+	}
+
+	merged = slices.DeleteFunc(merged, func(t client.TrackedGameSession) bool {
+		return t.Expired(now)
+	})
+	if len(merged) > maxTrackedGameSessions {
+		merged = merged[len(merged)-maxTrackedGameSessions:]
+	}
+	return merged
+}
+
+// Logout terminates the game sessions created with this login, revokes the OAuth
+// tokens and deletes the stored session. Afterwards operations that need the OAuth
+// session fail with ErrNotLoggedIn. If revocation fails the session is kept
+// unless force is set.
+func (s *SessionService) Logout(ctx context.Context, force bool) (*LogoutResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.session == nil {
+		return nil, ErrNotLoggedIn
+	}
+	// Terminate the game sessions created since the last save as well
+	s.saveGameSessionsLocked(ctx)
+
+	unlock, err := sessionstore.Lock(ctx, s.store)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	result, err := logout(ctx, s.client, s.session, s.store, force)
+	if err != nil && !force {
+		return result, err
+	}
+
+	s.dropSessionLocked()
+	return result, err
 }
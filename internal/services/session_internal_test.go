@@ -0,0 +1,36 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hsm/internal/hsmtest"
+	"hsm/internal/sessionstore"
+)
+
+func TestCheckSessionDropsRejectedRefreshToken(t *testing.T) {
+	backend := hsmtest.New(t)
+	// Every issued token is within the refresh threshold
+	backend.SetAccessTokenTTL(time.Minute)
+	sessionPath := filepath.Join(t.TempDir(), "session.json")
+	backend.WriteSessionFile(t, sessionPath)
+
+	svc, err := NewSessionService(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath))
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+	defer svc.Close()
+
+	backend.FailNext(hsmtest.RouteToken, 400, `{"error":"invalid_grant"}`)
+	svc.checkSession(t.Context())
+
+	select {
+	case <-svc.LoginRequired():
+	case <-time.After(time.Second):
+		t.Fatal("expected LoginRequired after the refresh token was rejected")
+	}
+	if svc.LoggedIn() {
+		t.Fatal("expected the session to be dropped")
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// sessionLocks serializes the operations on each tracked game session while
+// operations on different sessions run in parallel. Locks exist only while
+// they are held or waited for.
+type sessionLocks struct {
+	mu    sync.Mutex
+	locks map[SessionKey]*sessionLock
+}
+
+type sessionLock struct {
+	ch   chan struct{} // holds a token while locked
+	refs int           // holders and waiters
+}
+
+func newSessionLocks() *sessionLocks {
+	return &sessionLocks{locks: make(map[SessionKey]*sessionLock)}
+}
+
+// lock locks key, waiting until it is free or ctx is done, and returns
+// the function that unlocks it
+func (l *sessionLocks) lock(ctx context.Context, key SessionKey) (func(), error) {
+	lock := l.acquire(key)
+	select {
+	case lock.ch <- struct{}{}:
+		return func() { l.unlock(key, lock) }, nil
+	case <-ctx.Done():
+		l.release(key, lock)
+		return nil, ctx.Err()
+	}
+}
+
+// tryLock locks key if it is free
+func (l *sessionLocks) tryLock(key SessionKey) (func(), bool) {
+	lock := l.acquire(key)
+	select {
+	case lock.ch <- struct{}{}:
+		return func() { l.unlock(key, lock) }, true
+	default:
+		l.release(key, lock)
+		return nil, false
+	}
+}
+
+func (l *sessionLocks) acquire(key SessionKey) *sessionLock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &sessionLock{ch: make(chan struct{}, 1)}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	return lock
+}
+
+func (l *sessionLocks) unlock(key SessionKey, lock *sessionLock) {
+	<-lock.ch
+	l.release(key, lock)
+}
+
+func (l *sessionLocks) release(key SessionKey, lock *sessionLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if lock.refs--; lock.refs == 0 {
+		delete(l.locks, key)
+	}
+}
@@ -0,0 +1,266 @@
+package services_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+	"hsm/internal/sessionstore"
+)
+
+func newSessionService(t *testing.T, backend *hsmtest.Backend) *services.SessionService {
+	t.Helper()
+	sessionPath := filepath.Join(t.TempDir(), "session.json")
+	backend.WriteSessionFile(t, sessionPath)
+
+	svc, err := services.NewSessionService(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath))
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+	t.Cleanup(svc.Close)
+	return svc
+}
+
+func TestSessionServiceRefreshesExpiredToken(t *testing.T) {
+	backend := hsmtest.New(t)
+	sessionPath := filepath.Join(t.TempDir(), "session.json")
+
+	backend.SetAccessTokenTTL(time.Minute) // below RefreshThreshold
+	original := backend.WriteSessionFile(t, sessionPath)
+	backend.SetAccessTokenTTL(time.Hour)
+
+	svc, err := services.NewSessionService(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath))
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+	defer svc.Close()
+
+	if got := backend.Calls(hsmtest.RouteToken); got != 1 {
+		t.Fatalf("expected 1 token refresh, got %d", got)
+	}
+
+	saved, err := sessionstore.NewFileStore(sessionPath).Load(t.Context())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if saved.RefreshToken == original.RefreshToken {
+		t.Fatal("expected rotated refresh token to be persisted")
+	}
+	if saved.NeedsRefresh(services.RefreshThreshold) {
+		t.Fatal("expected persisted session to be fresh")
+	}
+}
+
+func TestSessionServiceMissingFile(t *testing.T) {
+	backend := hsmtest.New(t)
+
+	_, err := services.NewSessionService(t.Context(), backend.Client(), sessionstore.NewFileStore(filepath.Join(t.TempDir(), "missing.json")))
+	if err == nil {
+		t.Fatal("expected error for missing session file")
+	}
+}
+
+func TestSessionServiceDeadRefreshToken(t *testing.T) {
+	backend := hsmtest.New(t)
+	sessionPath := filepath.Join(t.TempDir(), "session.json")
+
+	backend.SetAccessTokenTTL(time.Minute)
+	backend.WriteSessionFile(t, sessionPath)
+	backend.FailNext(hsmtest.RouteToken, 400, `{"error":"invalid_grant"}`)
+
+	if _, err := services.NewSessionService(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath)); err == nil {
+		t.Fatal("expected error when refresh token is rejected")
+	}
+}
+
+func TestSessionServiceGameSessionLifecycle(t *testing.T) {
+	backend := hsmtest.New(t)
+	svc := newSessionService(t, backend)
+
+	session, err := svc.CreateGameSession(t.Context())
+	if err != nil {
+		t.Fatalf("CreateGameSession: %v", err)
+	}
+	if session.SessionToken == "" || session.IdentityToken == "" {
+		t.Fatalf("expected tokens, got %+v", session)
+	}
+
+	refreshed, err := svc.RefreshGameSession(t.Context(), session.SessionToken)
+	if err != nil {
+		t.Fatalf("RefreshGameSession: %v", err)
+	}
+	if refreshed.IdentityToken == session.IdentityToken {
+		t.Fatal("expected new identity token after refresh")
+	}
+
+	if err := svc.DeleteGameSession(t.Context(), session.SessionToken); err != nil {
+		t.Fatalf("DeleteGameSession: %v", err)
+	}
+	if backend.GameSessionCount() != 0 {
+		t.Fatalf("expected no live game sessions, got %d", backend.GameSessionCount())
+	}
+}
+
+func TestSessionServiceUpstreamError(t *testing.T) {
+	backend := hsmtest.New(t)
+	svc := newSessionService(t, backend)
+
+	backend.FailNext(hsmtest.RouteGameSessionNew, 502, `{"code":"bad_gateway"}`)
+	if _, err := svc.CreateGameSession(t.Context()); err == nil {
+		t.Fatal("expected error from injected failure")
+	}
+}
+
+func TestSessionServiceReadOnlyStore(t *testing.T) {
+	backend := hsmtest.New(t)
+
+	backend.SetAccessTokenTTL(time.Minute) // below RefreshThreshold
+	session := backend.IssueSession()
+	backend.SetAccessTokenTTL(time.Hour)
+	data, err := json.Marshal(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HSM_TEST_SESSION", string(data))
+
+	svc, err := services.NewSessionService(t.Context(), backend.Client(), sessionstore.NewEnvStore("HSM_TEST_SESSION"))
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+	defer svc.Close()
+
+	if got := backend.Calls(hsmtest.RouteToken); got != 1 {
+		t.Fatalf("expected 1 token refresh, got %d", got)
+	}
+	if _, err := svc.CreateGameSession(t.Context()); err != nil {
+		t.Fatalf("CreateGameSession with refreshed in-memory token: %v", err)
+	}
+}
+
+func TestSessionServicesSharingAStoreRefreshOnce(t *testing.T) {
+	backend := hsmtest.New(t)
+	sessionPath := filepath.Join(t.TempDir(), "session.json")
+
+	backend.SetAccessTokenTTL(time.Minute) // below RefreshThreshold
+	backend.WriteSessionFile(t, sessionPath)
+	backend.SetAccessTokenTTL(time.Hour)
+
+	// Like `hsm serve` and `hsm start` starting at the same time on one session file
+	const processes = 4
+	tokens := make([]string, processes)
+	errs := make([]error, processes)
+	var wg sync.WaitGroup
+	for i := range processes {
+		wg.Go(func() {
+			svc, err := services.NewSessionService(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath))
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			if _, err := svc.CreateGameSession(t.Context()); err != nil {
+				svc.Close()
+				errs[i] = err
+				return
+			}
+			svc.Close()
+			saved, err := sessionstore.NewFileStore(sessionPath).Load(t.Context())
+			if err == nil {
+				tokens[i] = saved.RefreshToken
+			}
+			errs[i] = err
+		})
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("process %d: %v", i, err)
+		}
+	}
+	if got := backend.Calls(hsmtest.RouteToken); got != 1 {
+		t.Fatalf("expected exactly 1 token refresh, got %d", got)
+	}
+
+	saved, err := sessionstore.NewFileStore(sessionPath).Load(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !backend.TokenActive(saved.RefreshToken) {
+		t.Fatal("expected the stored refresh token to still be valid")
+	}
+	if len(saved.GameSessions) != processes {
+		t.Fatalf("expected every process to track its game session, got %d", len(saved.GameSessions))
+	}
+}
+
+func TestSessionServiceTracksGameSessionsConcurrently(t *testing.T) {
+	backend := hsmtest.New(t)
+	sessionPath := filepath.Join(t.TempDir(), "session.json")
+	backend.WriteSessionFile(t, sessionPath)
+	svc, err := services.NewSessionService(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath))
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+
+	const sessions = 8
+	var wg sync.WaitGroup
+	for range sessions {
+		wg.Go(func() {
+			gameSession, err := svc.CreateGameSession(t.Context())
+			if err == nil {
+				_, err = svc.RefreshGameSession(t.Context(), gameSession.SessionToken)
+			}
+			if err != nil {
+				t.Errorf("game session: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	svc.Close()
+
+	saved, err := sessionstore.NewFileStore(sessionPath).Load(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.GameSessions) != sessions {
+		t.Fatalf("expected %d tracked game sessions, got %d", sessions, len(saved.GameSessions))
+	}
+}
+
+func TestSessionServicePrunesExpiredGameSessions(t *testing.T) {
+	backend := hsmtest.New(t)
+	sessionPath := filepath.Join(t.TempDir(), "session.json")
+	backend.WriteSessionFile(t, sessionPath)
+	svc, err := services.NewSessionService(t.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath))
+	if err != nil {
+		t.Fatalf("NewSessionService: %v", err)
+	}
+	defer svc.Close()
+
+	backend.SetGameSessionTTL(10 * time.Millisecond)
+	if _, err := svc.CreateGameSession(t.Context()); err != nil {
+		t.Fatalf("CreateGameSession: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	backend.SetGameSessionTTL(time.Hour)
+	live, err := svc.CreateGameSession(t.Context())
+	if err != nil {
+		t.Fatalf("CreateGameSession: %v", err)
+	}
+
+	// Logout only terminates the game session that is still alive
+	result, err := svc.Logout(t.Context(), false)
+	if err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if result.TerminatedGameSessions != 1 || backend.HasGameSession(live.SessionToken) {
+		t.Fatalf("expected the live game session to be terminated, got %+v", result)
+	}
+	if got := backend.Calls(hsmtest.RouteGameSessionDelete); got != 1 {
+		t.Fatalf("expected the expired game session to be skipped, got %d terminate calls", got)
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"hsm/internal/client"
+	"hsm/internal/utils"
+)
+
+// TrackedSession is a game session of a subject as persisted across restarts
+type TrackedSession struct {
+	Subject     string             `json:"subject"`
+	Instance    string             `json:"instance,omitempty"`
+	Session     client.GameSession `json:"session"`
+	Profile     string             `json:"profile"`
+	Account     string             `json:"account,omitempty"`
+	Linked      bool               `json:"linked,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt,omitzero"`
+	RefreshedAt time.Time          `json:"refreshedAt,omitzero"`
+}
+
+// Key returns the key of the session
+func (t TrackedSession) Key() SessionKey {
+	return SessionKey{Subject: t.Subject, Instance: t.Instance}
+}
+
+// StateStore persists the game sessions tracked by UserSessionService and the
+// subjects blocked from creating new ones
+type StateStore interface {
+	Load(ctx context.Context) ([]TrackedSession, error)
+	Put(ctx context.Context, session TrackedSession) error
+	Delete(ctx context.Context, key SessionKey) error
+	LoadBlocked(ctx context.Context) ([]string, error)
+	SetBlocked(ctx context.Context, subject string, blocked bool) error
+}
+
+// FileStateStore keeps the tracked game sessions in a JSON file that is
+// rewritten atomically on every change
+type FileStateStore struct {
+	path     string
+	sessions map[SessionKey]TrackedSession
+	blocked  map[string]bool
+	mu       sync.Mutex
+}
+
+// stateFile is the content of the file of a FileStateStore. Files written
+// before subjects could be blocked hold only the array of sessions.
+type stateFile struct {
+	Sessions []TrackedSession `json:"sessions"`
+	Blocked  []string         `json:"blocked,omitempty"`
+}
+
+// NewFileStateStore creates a FileStateStore at path
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{path: path}
+}
+
+// Load returns the persisted sessions, none if the file does not exist yet
+func (f *FileStateStore) Load(ctx context.Context) ([]TrackedSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.loadLocked(); err != nil {
+		return nil, err
+	}
+	return f.sortedLocked(), nil
+}
+
+// Put persists session, replacing the one with the same key
+func (f *FileStateStore) Put(ctx context.Context, session TrackedSession) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.loadLocked(); err != nil {
+		return err
+	}
+	f.sessions[session.Key()] = session
+	return f.writeLocked()
+}
+
+// Delete removes the session of key
+func (f *FileStateStore) Delete(ctx context.Context, key SessionKey) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.loadLocked(); err != nil {
+		return err
+	}
+	if _, ok := f.sessions[key]; !ok {
+		return nil
+	}
+	delete(f.sessions, key)
+	return f.writeLocked()
+}
+
+// LoadBlocked returns the blocked subjects in order
+func (f *FileStateStore) LoadBlocked(ctx context.Context) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.loadLocked(); err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(f.blocked)), nil
+}
+
+// SetBlocked blocks or unblocks subject
+func (f *FileStateStore) SetBlocked(ctx context.Context, subject string, blocked bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.loadLocked(); err != nil {
+		return err
+	}
+	if f.blocked[subject] == blocked {
+		return nil
+	}
+	if blocked {
+		f.blocked[subject] = true
+	} else {
+		delete(f.blocked, subject)
+	}
+	return f.writeLocked()
+}
+
+func (f *FileStateStore) loadLocked() error {
+	if f.sessions != nil {
+		return nil
+	}
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		f.sessions = make(map[SessionKey]TrackedSession)
+		f.blocked = make(map[string]bool)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var state stateFile
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		err = json.Unmarshal(data, &state.Sessions)
+	} else {
+		err = json.Unmarshal(data, &state)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse state file %s: %w", f.path, err)
+	}
+	f.sessions = make(map[SessionKey]TrackedSession, len(state.Sessions))
+	for _, session := range state.Sessions {
+		f.sessions[session.Key()] = session
+	}
+	f.blocked = make(map[string]bool, len(state.Blocked))
+	for _, subject := range state.Blocked {
+		f.blocked[subject] = true
+	}
+	return nil
+}
+
+func (f *FileStateStore) writeLocked() error {
+	state := stateFile{Sessions: f.sortedLocked(), Blocked: slices.Sorted(maps.Keys(f.blocked))}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	return utils.WriteSecretFile(f.path, data)
+}
+
+func (f *FileStateStore) sortedLocked() []TrackedSession {
+	return slices.SortedFunc(maps.Values(f.sessions), func(a, b TrackedSession) int {
+		return cmp.Or(cmp.Compare(a.Subject, b.Subject), cmp.Compare(a.Instance, b.Instance))
+	})
+}
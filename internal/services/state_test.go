@@ -0,0 +1,95 @@
+package services_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+)
+
+func TestUserSessionServiceRestoresSessions(t *testing.T) {
+	backend := hsmtest.New(t)
+	pool := services.NewSingleAccountPool(newSessionService(t, backend))
+	statePath := filepath.Join(t.TempDir(), "game-sessions.json")
+
+	before := services.NewUserSessionService(pool, services.WithStateStore(services.NewFileStateStore(statePath)))
+	alice, err := before.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	bob, err := before.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "bob"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	// bob's session ends upstream while HSM is down
+	if err := pool.Primary().Client().TerminateGameSession(t.Context(), bob.SessionToken); err != nil {
+		t.Fatalf("TerminateGameSession: %v", err)
+	}
+	pool.ResetCounts()
+
+	after := services.NewUserSessionService(pool, services.WithStateStore(services.NewFileStateStore(statePath)))
+	if err := after.Restore(t.Context()); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	if after.GetSession(services.SessionKey{Subject: "bob"}) != nil {
+		t.Fatal("expected the terminated session to be discarded")
+	}
+	restored, err := after.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	if restored.SessionToken != alice.SessionToken {
+		t.Fatal("expected the restored session to be reused")
+	}
+	if got := backend.Calls(hsmtest.RouteGameSessionNew); got != 2 {
+		t.Fatalf("expected no duplicate upstream session, got %d creates", got)
+	}
+	if got := pool.State()[0].ActiveSessions; got != 1 {
+		t.Fatalf("expected 1 active session after restore, got %d", got)
+	}
+
+	tracked, err := services.NewFileStateStore(statePath).Load(t.Context())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(tracked) != 1 || tracked[0].Subject != "alice" {
+		t.Fatalf("expected only alice to stay persisted, got %+v", tracked)
+	}
+
+	if err := after.DeleteSession(t.Context(), services.SessionKey{Subject: "alice"}); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if tracked, _ := services.NewFileStateStore(statePath).Load(t.Context()); len(tracked) != 0 {
+		t.Fatalf("expected the deleted session to be removed, got %+v", tracked)
+	}
+}
+
+func TestFileStateStoreReadsSessionArray(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "game-sessions.json")
+	if err := os.WriteFile(statePath, []byte(`[{"subject":"alice","session":{"sessionToken":"st"},"profile":"p"}]`), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	store := services.NewFileStateStore(statePath)
+	tracked, err := store.Load(t.Context())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(tracked) != 1 || tracked[0].Subject != "alice" || tracked[0].Session.SessionToken != "st" {
+		t.Fatalf("unexpected sessions %+v", tracked)
+	}
+	if err := store.SetBlocked(t.Context(), "bob", true); err != nil {
+		t.Fatalf("SetBlocked: %v", err)
+	}
+
+	reopened := services.NewFileStateStore(statePath)
+	if tracked, err := reopened.Load(t.Context()); err != nil || len(tracked) != 1 {
+		t.Fatalf("expected the session to be kept, got %+v, %v", tracked, err)
+	}
+	if blocked, err := reopened.LoadBlocked(t.Context()); err != nil || len(blocked) != 1 || blocked[0] != "bob" {
+		t.Fatalf("expected bob to be blocked, got %v, %v", blocked, err)
+	}
+}
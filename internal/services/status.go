@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"hsm/internal/client"
+	"hsm/internal/sessionstore"
+	"hsm/internal/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Status describes the login and install state of this machine
+type Status struct {
+	SessionStore string         `json:"session_store"`
+	LoggedIn     bool           `json:"logged_in"`
+	Token        *TokenStatus   `json:"token,omitempty"`
+	Account      *AccountStatus `json:"account,omitempty"`
+	Install      InstallStatus  `json:"install"`
+	// Errors lists the checks that could not be completed
+	Errors []string `json:"errors,omitempty"`
+}
+
+// TokenStatus describes the stored OAuth access token
+type TokenStatus struct {
+	Format          string    `json:"format"` // "jwt" or "opaque"
+	Subject         string    `json:"subject,omitempty"`
+	Issuer          string    `json:"issuer,omitempty"`
+	Scope           string    `json:"scope,omitempty"`
+	ExpiresAt       time.Time `json:"expires_at,omitzero"`
+	Expired         bool      `json:"expired"`
+	HasRefreshToken bool      `json:"has_refresh_token"`
+	// Accepted reports whether the upstream accepted the token
+	Accepted bool `json:"accepted"`
+}
+
+// AccountStatus describes the account the token belongs to
+type AccountStatus struct {
+	Owner           string           `json:"owner"`
+	Profiles        []client.Profile `json:"profiles"`
+	SelectedProfile string           `json:"selected_profile,omitempty"` // UUID
+}
+
+// InstallStatus describes the installed game files
+type InstallStatus struct {
+	VersionFile      string `json:"version_file"`
+	InstalledVersion string `json:"installed_version,omitempty"`
+	Patchline        string `json:"patchline"`
+	LatestVersion    string `json:"latest_version,omitempty"`
+	UpdateAvailable  bool   `json:"update_available"`
+}
+
+// StatusOptions selects what GetStatus inspects
+type StatusOptions struct {
+	SessionStore    sessionstore.Store
+	VersionFile     string
+	Patchline       string
+	ProfileSelector string
+}
+
+// GetStatus inspects the stored session, the account and the installed version.
+// It never modifies the session: an expired access token is reported instead of
+// refreshed. Checks that fail are recorded in Status.Errors.
+func GetStatus(ctx context.Context, c *client.Client, opts StatusOptions) *Status {
+	status := &Status{
+		SessionStore: opts.SessionStore.String(),
+		Install: InstallStatus{
+			VersionFile:      opts.VersionFile,
+			InstalledVersion: utils.GetVersion(opts.VersionFile),
+			Patchline:        opts.Patchline,
+		},
+	}
+	fail := func(format string, args ...any) {
+		status.Errors = append(status.Errors, fmt.Sprintf(format, args...))
+	}
+
+	session, err := opts.SessionStore.Load(ctx)
+	if err != nil {
+		fail("not logged in: %v", err)
+		return status
+	}
+	if session.Token == "" {
+		fail("invalid session: missing token")
+		return status
+	}
+	status.LoggedIn = true
+	status.Token = inspectToken(session)
+
+	if status.Token.Expired {
+		if session.RefreshToken != "" {
+			fail("access token expired, it is refreshed on the next command that uses it")
+		} else {
+			fail("access token expired and no refresh token available, run 'hsm login'")
+		}
+		return status
+	}
+
+	c.WithToken(session.Token)
+	profiles, err := c.GetProfiles(ctx)
+	if err != nil {
+		fail("failed to get profiles: %v", err)
+		return status
+	}
+	status.Token.Accepted = true
+	status.Account = &AccountStatus{Owner: profiles.Owner, Profiles: profiles.Profiles}
+
+	selector := opts.ProfileSelector
+	if selector == "" {
+		selector = session.Profile
+	}
+	if profile, err := ResolveProfile(profiles.Profiles, selector); err != nil {
+		fail("%v", err)
+	} else {
+		status.Account.SelectedProfile = profile.UUID
+	}
+
+	_, latest, err := NewDownloadService(c).GetDownloadURL(ctx, opts.Patchline)
+	if err != nil {
+		fail("failed to get latest version: %v", err)
+		return status
+	}
+	status.Install.LatestVersion = latest
+	status.Install.UpdateAvailable = status.Install.InstalledVersion != latest
+
+	return status
+}
+
+// inspectToken decodes the access token without verifying its signature, HSM
+// does not know the issuer's keys. Opaque tokens fall back to the session metadata.
+func inspectToken(session *client.Session) *TokenStatus {
+	token := &TokenStatus{
+		Format:          "opaque",
+		Scope:           session.Scope,
+		ExpiresAt:       session.ExpiresAt,
+		HasRefreshToken: session.RefreshToken != "",
+	}
+
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(session.Token, claims); err == nil {
+		token.Format = "jwt"
+		token.Subject, _ = claims.GetSubject()
+		token.Issuer, _ = claims.GetIssuer()
+		if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+			token.ExpiresAt = exp.Time
+		}
+		if scope, ok := claims["scope"].(string); ok && scope != "" {
+			token.Scope = scope
+		}
+	}
+
+	token.Expired = !token.ExpiresAt.IsZero() && time.Now().After(token.ExpiresAt)
+	return token
+}
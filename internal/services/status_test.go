@@ -0,0 +1,82 @@
+package services_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hsm/internal/client"
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+	"hsm/internal/sessionstore"
+	"hsm/internal/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestGetStatus(t *testing.T) {
+	backend := hsmtest.New(t)
+	dir := t.TempDir()
+	sessionPath := filepath.Join(dir, "session.json")
+	versionFile := filepath.Join(dir, "version.txt")
+	backend.WriteSessionFile(t, sessionPath)
+	backend.SetVersion(services.PatchlineRelease, "2026.02.01-release")
+	if err := utils.WriteVersion(versionFile, "2026.01.01-release"); err != nil {
+		t.Fatal(err)
+	}
+
+	status := services.GetStatus(t.Context(), backend.Client(), services.StatusOptions{
+		SessionStore: sessionstore.NewFileStore(sessionPath),
+		VersionFile:  versionFile,
+		Patchline:    services.PatchlineRelease,
+	})
+
+	if len(status.Errors) != 0 {
+		t.Fatalf("unexpected errors %v", status.Errors)
+	}
+	if !status.LoggedIn || !status.Token.Accepted || status.Token.Expired {
+		t.Fatalf("unexpected token status %+v", status.Token)
+	}
+	if status.Account.SelectedProfile != hsmtest.DefaultProfile.UUID {
+		t.Fatalf("expected default profile, got %q", status.Account.SelectedProfile)
+	}
+	if status.Install.LatestVersion != "2026.02.01-release" || !status.Install.UpdateAvailable {
+		t.Fatalf("expected update to be available, got %+v", status.Install)
+	}
+}
+
+func TestGetStatusDoesNotRefreshExpiredJWT(t *testing.T) {
+	backend := hsmtest.New(t)
+	sessionPath := filepath.Join(t.TempDir(), "session.json")
+
+	expiresAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "user-123",
+		"exp": expiresAt.Unix(),
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := &client.Session{Token: token, AccessToken: token, RefreshToken: "rt"}
+	if err := sessionstore.NewFileStore(sessionPath).Save(t.Context(), session); err != nil {
+		t.Fatal(err)
+	}
+
+	status := services.GetStatus(t.Context(), backend.Client(), services.StatusOptions{
+		SessionStore: sessionstore.NewFileStore(sessionPath),
+		Patchline:    services.PatchlineRelease,
+	})
+
+	if status.Token.Format != "jwt" || status.Token.Subject != "user-123" {
+		t.Fatalf("expected decoded JWT claims, got %+v", status.Token)
+	}
+	if !status.Token.Expired || !status.Token.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("expected token to expire at %v, got %+v", expiresAt, status.Token)
+	}
+	if len(status.Errors) != 1 {
+		t.Fatalf("expected one error, got %v", status.Errors)
+	}
+	if backend.Calls(hsmtest.RouteToken) != 0 || backend.Calls(hsmtest.RouteProfiles) != 0 {
+		t.Fatal("expected no upstream calls for an expired token")
+	}
+}
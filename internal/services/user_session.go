@@ -1,95 +1,628 @@
 package services
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"hsm/internal/client"
+	"log"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 )
 
+// MaxInstanceLength is the maximum length of an instance id
+const MaxInstanceLength = 128
+
+var (
+	// ErrInstanceLimit is returned when a subject already runs the maximum
+	// number of game sessions
+	ErrInstanceLimit = errors.New("maximum number of game sessions for subject reached")
+	// ErrInvalidInstance is returned for instance ids longer than MaxInstanceLength
+	ErrInvalidInstance = errors.New("invalid instance id")
+	// ErrSubjectBlocked is returned when a blocked subject requests a new game session
+	ErrSubjectBlocked = errors.New("subject is blocked from creating game sessions")
+)
+
+// SessionKey identifies a tracked game session. A subject may run several
+// instances, the empty instance is the subject's default session.
+type SessionKey struct {
+	Subject  string
+	Instance string
+}
+
+func (k SessionKey) String() string {
+	if k.Instance == "" {
+		return k.Subject
+	}
+	return k.Subject + "/" + k.Instance
+}
+
+// userSession is a game session tracked for a subject
+type userSession struct {
+	session *client.GameSession
+	profile string    // UUID of the profile the session was created for
+	account string    // pool account the session was created on
+	linked  bool      // created on the subject's own account instead of the pool
+	lease   time.Time // the session is terminated without a heartbeat until then
+	warned  bool      // an EventSessionExpiring was sent for the current lease, or the current game session without leases
+
+	created   time.Time
+	refreshed time.Time
+}
+
+// InstanceSession is a game session of one instance of a subject
+type InstanceSession struct {
+	Instance string
+	Session  *client.GameSession
+}
+
 // UserSessionService manages game sessions for multi-user mode.
-// Each user (JWT subject) can only have one active session at a time.
+// Each user (JWT subject) has one active session per instance, up to the
+// configured maximum. Sessions are spread across the accounts of the pool,
+// unless the subject linked its own account.
+//
+// Operations on a session hold the lock of its key across upstream calls, so
+// concurrent requests for a session are serialized while other sessions
+// proceed in parallel. mu only guards the maps and the fields of their entries
+// and is never held across upstream calls.
 type UserSessionService struct {
-	sessionService *SessionService
-	sessions       map[string]*client.GameSession // subject -> session
-	mu             sync.RWMutex
+	pool     *AccountPool
+	links    *LinkService
+	state    StateStore
+	locks    *sessionLocks
+	sessions map[SessionKey]*userSession
+	creating map[string]int // subject -> sessions being created
+	blocked  map[string]bool
+	mu       sync.RWMutex
+
+	maxPerSubject      int
+	refreshMargin      time.Duration
+	refreshConcurrency int
+	refreshFailures    map[SessionKey]RefreshFailure // failed background refreshes
+	leaseTTL           time.Duration
+	events             EventSinks
+}
+
+// UserSessionOption is a functional option for configuring the UserSessionService
+type UserSessionOption func(*UserSessionService)
+
+// WithLinks lets subjects create game sessions with their own linked account
+func WithLinks(links *LinkService) UserSessionOption {
+	return func(s *UserSessionService) {
+		s.links = links
+	}
+}
+
+// WithStateStore persists the tracked sessions so they survive restarts, see Restore
+func WithStateStore(state StateStore) UserSessionOption {
+	return func(s *UserSessionService) {
+		s.state = state
+	}
+}
+
+// WithMaxSessionsPerSubject limits the instances a subject may run at the same
+// time. The number is not limited by default.
+func WithMaxSessionsPerSubject(n int) UserSessionOption {
+	return func(s *UserSessionService) {
+		s.maxPerSubject = max(n, 0)
+	}
 }
 
-func NewUserSessionService(sessionService *SessionService) *UserSessionService {
-	return &UserSessionService{
-		sessionService: sessionService,
-		sessions:       make(map[string]*client.GameSession),
+// WithEventSink publishes the lifecycle events of the tracked sessions to sink
+func WithEventSink(sink EventSink) UserSessionOption {
+	return func(s *UserSessionService) {
+		s.events = append(s.events, sink)
 	}
 }
 
-// GetSession returns the session for a subject
-func (s *UserSessionService) GetSession(subject string) *client.GameSession {
+func NewUserSessionService(pool *AccountPool, opts ...UserSessionOption) *UserSessionService {
+	s := &UserSessionService{
+		pool:               pool,
+		locks:              newSessionLocks(),
+		sessions:           make(map[SessionKey]*userSession),
+		creating:           make(map[string]int),
+		blocked:            make(map[string]bool),
+		refreshMargin:      RefreshThreshold,
+		refreshConcurrency: DefaultRefreshConcurrency,
+		refreshFailures:    make(map[SessionKey]RefreshFailure),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Pool returns the account pool backing the service
+func (s *UserSessionService) Pool() *AccountPool {
+	return s.pool
+}
+
+// Links returns the link service, nil if linking is disabled
+func (s *UserSessionService) Links() *LinkService {
+	return s.links
+}
+
+// GetSession returns the session for key
+func (s *UserSessionService) GetSession(key SessionKey) *client.GameSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if entry, exists := s.sessions[key]; exists {
+		return entry.session
+	}
+	return nil
+}
+
+// ListSessions returns the sessions of all instances of subject, ordered by instance
+func (s *UserSessionService) ListSessions(subject string) []InstanceSession {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.sessions[subject]
+	var sessions []InstanceSession
+	for key, entry := range s.sessions {
+		if key.Subject == subject {
+			sessions = append(sessions, InstanceSession{Instance: key.Instance, Session: entry.session})
+		}
+	}
+	slices.SortFunc(sessions, func(a, b InstanceSession) int {
+		return cmp.Compare(a.Instance, b.Instance)
+	})
+	return sessions
 }
 
 // GetOrCreateSession returns an existing active session or creates a new one.
-// If an active session exists, it refreshes and returns it.
-func (s *UserSessionService) GetOrCreateSession(subject string) (*client.GameSession, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// If an active session exists, it refreshes and returns it. profile optionally
+// selects the game profile (UUID or username) and account optionally names the
+// pool account (see StrategyClaim); an existing session for another profile or
+// account is terminated and replaced. Subjects with a linked account always
+// use it.
+func (s *UserSessionService) GetOrCreateSession(ctx context.Context, key SessionKey, profile, account string) (*client.GameSession, error) {
+	if len(key.Instance) > MaxInstanceLength {
+		return nil, ErrInvalidInstance
+	}
+
+	unlock, err := s.locks.lock(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	linked, err := s.linkedService(ctx, key.Subject)
+	if err != nil {
+		return nil, err
+	}
 
 	// Check for existing session
-	if session, exists := s.sessions[subject]; exists {
-		if time.Now().Before(session.ExpiresAt) {
+	if entry := s.entry(key); entry != nil {
+		reuse, err := s.matches(ctx, key, entry, profile, account, linked != nil)
+		if err != nil {
+			return nil, err
+		}
+		if !reuse {
+			// Profile or account changed, release the old session upstream
+			s.release(ctx, key, entry)
+			s.emit(EventSessionTerminated, key, entry, nil)
+		} else if time.Now().Before(entry.session.ExpiresAt) {
 			// Refresh and return
-			if refreshed, err := s.sessionService.RefreshGameSession(session.SessionToken); err == nil {
-				s.sessions[subject] = refreshed
+			if refreshed, err := s.refresh(ctx, key, entry, true); err == nil {
 				return refreshed, nil
 			}
+			s.releaseSlot(entry)
+		} else {
+			s.releaseSlot(entry)
+			s.emit(EventSessionExpired, key, entry, nil)
 		}
 		// Session expired/invalid, clean up
-		delete(s.sessions, subject)
+		s.remove(ctx, key)
+	}
+
+	if err := s.reserve(key.Subject); err != nil {
+		return nil, err
 	}
+	defer s.unreserve(key.Subject)
 
 	// Create new session
-	session, err := s.sessionService.CreateGameSession()
+	var session *client.GameSession
+	entry := &userSession{linked: linked != nil}
+	if linked != nil {
+		session, err = linked.CreateGameSessionForProfile(ctx, profile)
+	} else {
+		session, entry.account, err = s.pool.CreateGameSession(ctx, key.Subject, account, profile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry.session = session
+
+	svc, err := s.service(ctx, key, entry)
+	var selected client.Profile
+	if err == nil {
+		selected, err = svc.ResolveProfile(profile)
+	}
 	if err != nil {
+		// The session cannot be tracked, end it and free its slot again
+		s.release(ctx, key, entry)
 		return nil, err
 	}
 
-	s.sessions[subject] = session
+	entry.profile = selected.UUID
+	entry.created = time.Now()
+	entry.refreshed = entry.created
+	s.renewLease(entry)
+	s.mu.Lock()
+	s.sessions[key] = entry
+	delete(s.refreshFailures, key)
+	s.mu.Unlock()
+
+	s.persist(ctx, key, entry)
+	s.emit(EventSessionCreated, key, entry, nil)
 	return session, nil
 }
 
-// DeleteSession deletes the session for a subject
-func (s *UserSessionService) DeleteSession(subject string) error {
+// reserve counts a session of subject being created against the maximum
+func (s *UserSessionService) reserve(subject string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.blocked[subject] {
+		return ErrSubjectBlocked
+	}
+	if s.maxPerSubject > 0 {
+		count := s.creating[subject]
+		for key := range s.sessions {
+			if key.Subject == subject {
+				count++
+			}
+		}
+		if count >= s.maxPerSubject {
+			return ErrInstanceLimit
+		}
+	}
+	s.creating[subject]++
+	return nil
+}
+
+func (s *UserSessionService) unreserve(subject string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.creating[subject]--; s.creating[subject] == 0 {
+		delete(s.creating, subject)
+	}
+}
+
+// entry returns the entry of key, nil if there is none
+func (s *UserSessionService) entry(key SessionKey) *userSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sessions[key]
+}
+
+// keys returns the keys of the sessions of subject
+func (s *UserSessionService) keys(subject string) []SessionKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var keys []SessionKey
+	for key := range s.sessions {
+		if key.Subject == subject {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// remove stops tracking the session of key
+func (s *UserSessionService) remove(ctx context.Context, key SessionKey) {
+	s.mu.Lock()
+	delete(s.sessions, key)
+	delete(s.refreshFailures, key)
+	s.mu.Unlock()
+	s.forget(ctx, key)
+}
+
+// linkedService returns the SessionService of subject's own account, nil if
+// subject has not linked one
+func (s *UserSessionService) linkedService(ctx context.Context, subject string) (*SessionService, error) {
+	if s.links == nil {
+		return nil, nil
+	}
+	return s.links.Service(ctx, subject)
+}
+
+// service returns the SessionService entry was created with
+func (s *UserSessionService) service(ctx context.Context, key SessionKey, entry *userSession) (*SessionService, error) {
+	if !entry.linked {
+		return s.pool.Service(entry.account)
+	}
+	svc, err := s.linkedService(ctx, key.Subject)
+	if err == nil && svc == nil {
+		err = ErrNotLinked
+	}
+	return svc, err
+}
+
+// matches reports whether entry satisfies a request for profile on account
+func (s *UserSessionService) matches(ctx context.Context, key SessionKey, entry *userSession, profile, account string, linked bool) (bool, error) {
+	if entry.linked != linked {
+		return false, nil
+	}
+	if !linked && account != "" && s.pool.Strategy() == StrategyClaim && account != entry.account {
+		return false, nil
+	}
+	svc, err := s.service(ctx, key, entry)
+	if err != nil {
+		return false, nil
+	}
+	selected, err := svc.ResolveProfile(profile)
+	if err != nil {
+		// The profile may belong to another account of the pool
+		if !linked && profile != "" && len(s.pool.members) > 1 {
+			return false, nil
+		}
+		return false, err
+	}
+	return selected.UUID == entry.profile, nil
+}
+
+// release terminates the session of entry upstream and frees its pool slot
+func (s *UserSessionService) release(ctx context.Context, key SessionKey, entry *userSession) {
+	if svc, err := s.service(ctx, key, entry); err == nil {
+		_ = svc.DeleteGameSession(ctx, entry.session.SessionToken)
+	}
+	s.releaseSlot(entry)
+}
+
+// releaseSlot frees the pool slot of entry
+func (s *UserSessionService) releaseSlot(entry *userSession) {
+	if !entry.linked {
+		s.pool.Release(entry.account)
+	}
+}
+
+// DeleteSession deletes the session for key
+func (s *UserSessionService) DeleteSession(ctx context.Context, key SessionKey) error {
+	unlock, err := s.locks.lock(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	session, exists := s.sessions[subject]
-	if !exists {
+	entry := s.entry(key)
+	if entry == nil {
 		return nil
 	}
 
-	if err := s.sessionService.DeleteGameSession(session.SessionToken); err != nil {
+	svc, err := s.service(ctx, key, entry)
+	if err != nil {
+		return err
+	}
+	if err := svc.DeleteGameSession(ctx, entry.session.SessionToken); err != nil {
 		return err
 	}
 
-	delete(s.sessions, subject)
+	s.releaseSlot(entry)
+	s.remove(ctx, key)
+	s.emit(EventSessionTerminated, key, entry, nil)
 	return nil
 }
 
-// RefreshSession refreshes the session for a subject
-func (s *UserSessionService) RefreshSession(subject string) (*client.GameSession, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// RefreshSession refreshes the session for key
+func (s *UserSessionService) RefreshSession(ctx context.Context, key SessionKey) (*client.GameSession, error) {
+	unlock, err := s.locks.lock(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
-	session, exists := s.sessions[subject]
-	if !exists {
+	entry := s.entry(key)
+	if entry == nil {
 		return nil, nil
 	}
 
-	refreshed, err := s.sessionService.RefreshGameSession(session.SessionToken)
+	return s.refresh(ctx, key, entry, true)
+}
+
+// refresh refreshes the session of entry on the account it was created on and
+// renews its lease if the refresh counts as a heartbeat of the game server.
+// The caller holds the lock of key.
+func (s *UserSessionService) refresh(ctx context.Context, key SessionKey, entry *userSession, heartbeat bool) (*client.GameSession, error) {
+	svc, err := s.service(ctx, key, entry)
 	if err != nil {
 		return nil, err
 	}
+	refreshed, err := svc.RefreshGameSession(ctx, entry.session.SessionToken)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			s.emit(EventSessionRefreshFailed, key, entry, err)
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	entry.session = refreshed
+	entry.refreshed = time.Now()
+	if heartbeat {
+		s.renewLease(entry)
+	}
+	if s.leaseTTL <= 0 {
+		entry.warned = false
+	}
+	delete(s.refreshFailures, key)
+	s.mu.Unlock()
 
-	s.sessions[subject] = refreshed
+	s.persist(ctx, key, entry)
+	s.emit(EventSessionRefreshed, key, entry, nil)
 	return refreshed, nil
 }
+
+// Unlink removes subject's own account. Its game sessions are terminated with
+// the account's login, later game sessions use the pool.
+func (s *UserSessionService) Unlink(ctx context.Context, subject string) (*LogoutResult, error) {
+	if s.links == nil {
+		return nil, ErrNotLinked
+	}
+
+	for _, key := range s.keys(subject) {
+		unlock, err := s.locks.lock(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if entry := s.entry(key); entry != nil && entry.linked {
+			s.remove(ctx, key)
+			s.emit(EventSessionTerminated, key, entry, nil)
+		}
+		unlock()
+	}
+	return s.links.Unlink(ctx, subject)
+}
+
+// Reset forgets all sessions created on the pool without contacting the
+// upstream, e.g. after logout already terminated them. Sessions on linked
+// accounts are kept.
+func (s *UserSessionService) Reset() {
+	s.mu.Lock()
+	removed := make(map[SessionKey]*userSession)
+	maps.DeleteFunc(s.sessions, func(key SessionKey, entry *userSession) bool {
+		if entry.linked {
+			return false
+		}
+		removed[key] = entry
+		delete(s.refreshFailures, key)
+		return true
+	})
+	s.pool.ResetCounts()
+	s.mu.Unlock()
+
+	// The state store and the event sinks may be slow, requests must not wait for them
+	for key, entry := range removed {
+		s.forget(context.Background(), key)
+		s.emit(EventSessionTerminated, key, entry, nil)
+	}
+}
+
+// Restore loads the sessions persisted by an earlier run and reconciles them
+// with the upstream: valid sessions are refreshed and tracked again, expired or
+// rejected ones are discarded. Sessions of accounts that are not logged in yet
+// are tracked as they are.
+func (s *UserSessionService) Restore(ctx context.Context) error {
+	if s.state == nil {
+		return nil
+	}
+	tracked, err := s.state.Load(ctx)
+	if err != nil {
+		return err
+	}
+	blocked, err := s.state.LoadBlocked(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	for _, subject := range blocked {
+		s.blocked[subject] = true
+	}
+	s.mu.Unlock()
+
+	restored := 0
+	for _, t := range tracked {
+		session := t.Session
+		entry := &userSession{
+			session:   &session,
+			profile:   t.Profile,
+			account:   t.Account,
+			linked:    t.Linked,
+			created:   t.CreatedAt,
+			refreshed: t.RefreshedAt,
+		}
+		if s.reconcile(ctx, t.Key(), entry) {
+			// Game servers could not heartbeat while HSM was down
+			s.renewLease(entry)
+			s.mu.Lock()
+			s.sessions[t.Key()] = entry
+			s.mu.Unlock()
+			if !entry.linked {
+				s.pool.Retain(entry.account)
+			}
+			restored++
+		} else {
+			s.forget(ctx, t.Key())
+			s.emit(EventSessionExpired, t.Key(), entry, nil)
+		}
+	}
+	if len(tracked) > 0 {
+		log.Printf("Restored %d of %d tracked game sessions", restored, len(tracked))
+	}
+	return nil
+}
+
+// reconcile refreshes a restored entry and reports whether it is still usable
+func (s *UserSessionService) reconcile(ctx context.Context, key SessionKey, entry *userSession) bool {
+	unlock, err := s.locks.lock(ctx, key)
+	if err != nil {
+		return false
+	}
+	defer unlock()
+
+	if !time.Now().Before(entry.session.ExpiresAt) {
+		return false
+	}
+	svc, err := s.service(ctx, key, entry)
+	if err != nil {
+		// The account left the pool or the subject unlinked it
+		return false
+	}
+	if !svc.LoggedIn() {
+		return true
+	}
+
+	_, err = s.refresh(ctx, key, entry, false)
+	switch {
+	case err == nil:
+		return true
+	case sessionGone(err):
+		return false
+	}
+	log.Printf("Failed to refresh restored game session of %s, keeping it: %v", key, err)
+	return true
+}
+
+// emit publishes an event of typ for the session of entry
+func (s *UserSessionService) emit(typ EventType, key SessionKey, entry *userSession, err error) {
+	if len(s.events) == 0 {
+		return
+	}
+	s.events.Publish(NewEvent(typ, key, entry.account, entry.session, err))
+}
+
+// sessionGone reports whether err means the upstream no longer knows a game session
+func sessionGone(err error) bool {
+	return errors.Is(err, client.ErrNotFound) || errors.Is(err, client.ErrUnauthorized)
+}
+
+// persist writes entry through to the state store. The caller holds the lock
+// of key, which keeps the writes of a session in order.
+func (s *UserSessionService) persist(ctx context.Context, key SessionKey, entry *userSession) {
+	if s.state == nil {
+		return
+	}
+	err := s.state.Put(ctx, TrackedSession{
+		Subject:     key.Subject,
+		Instance:    key.Instance,
+		Session:     *entry.session,
+		Profile:     entry.profile,
+		Account:     entry.account,
+		Linked:      entry.linked,
+		CreatedAt:   entry.created,
+		RefreshedAt: entry.refreshed,
+	})
+	if err != nil {
+		log.Printf("Failed to persist game session of %s: %v", key, err)
+	}
+}
+
+// forget removes the session of key from the state store
+func (s *UserSessionService) forget(ctx context.Context, key SessionKey) {
+	if s.state == nil {
+		return
+	}
+	if err := s.state.Delete(ctx, key); err != nil {
+		log.Printf("Failed to remove persisted game session of %s: %v", key, err)
+	}
+}
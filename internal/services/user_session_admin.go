@@ -0,0 +1,201 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SessionInfo describes a tracked game session for operators. It leaves out
+// the tokens of the session.
+type SessionInfo struct {
+	Key            SessionKey
+	Account        string // pool account, empty for linked accounts
+	Linked         bool
+	Profile        string
+	CreatedAt      time.Time
+	RefreshedAt    time.Time
+	ExpiresAt      time.Time
+	LeaseExpiresAt time.Time
+	RefreshFailure *RefreshFailure
+}
+
+// Sessions returns the tracked sessions of subject ordered by instance, or of
+// all subjects ordered by subject if subject is empty
+func (s *UserSessionService) Sessions(subject string) []SessionInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var sessions []SessionInfo
+	for key, entry := range s.sessions {
+		if subject != "" && key.Subject != subject {
+			continue
+		}
+		info := SessionInfo{
+			Key:            key,
+			Account:        entry.account,
+			Linked:         entry.linked,
+			Profile:        entry.profile,
+			CreatedAt:      entry.created,
+			RefreshedAt:    entry.refreshed,
+			ExpiresAt:      entry.session.ExpiresAt,
+			LeaseExpiresAt: entry.lease,
+		}
+		if failure, ok := s.refreshFailures[key]; ok {
+			info.RefreshFailure = &failure
+		}
+		sessions = append(sessions, info)
+	}
+	slices.SortFunc(sessions, func(a, b SessionInfo) int {
+		return cmp.Or(cmp.Compare(a.Key.Subject, b.Key.Subject), cmp.Compare(a.Key.Instance, b.Key.Instance))
+	})
+	return sessions
+}
+
+// RefreshSubject refreshes every game session of subject now and returns how
+// many were refreshed. Unlike requests of the game server, it does not renew
+// the leases.
+func (s *UserSessionService) RefreshSubject(ctx context.Context, subject string) (int, error) {
+	return s.forEach(ctx, s.keys(subject), func(key SessionKey) (bool, error) {
+		unlock, err := s.locks.lock(ctx, key)
+		if err != nil {
+			return false, err
+		}
+		defer unlock()
+
+		entry := s.entry(key)
+		if entry == nil {
+			return false, nil
+		}
+		if _, err := s.refresh(ctx, key, entry, false); err != nil {
+			return false, fmt.Errorf("%s: %w", key, err)
+		}
+		return true, nil
+	})
+}
+
+// TerminateSubject terminates every game session of subject upstream and
+// returns how many were terminated
+func (s *UserSessionService) TerminateSubject(ctx context.Context, subject string) (int, error) {
+	return s.forEach(ctx, s.keys(subject), func(key SessionKey) (bool, error) {
+		return s.terminate(ctx, key)
+	})
+}
+
+// TerminateAll terminates every tracked game session upstream and returns how
+// many were terminated
+func (s *UserSessionService) TerminateAll(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	keys := slices.Collect(maps.Keys(s.sessions))
+	s.mu.RUnlock()
+
+	return s.forEach(ctx, keys, func(key SessionKey) (bool, error) {
+		return s.terminate(ctx, key)
+	})
+}
+
+// terminate terminates the session of key upstream and stops tracking it.
+// Sessions the upstream no longer knows are removed as well.
+func (s *UserSessionService) terminate(ctx context.Context, key SessionKey) (bool, error) {
+	unlock, err := s.locks.lock(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	entry := s.entry(key)
+	if entry == nil {
+		return false, nil
+	}
+	svc, err := s.service(ctx, key, entry)
+	if err == nil {
+		err = svc.DeleteGameSession(ctx, entry.session.SessionToken)
+	}
+	if err != nil && !sessionGone(err) {
+		return false, fmt.Errorf("%s: %w", key, err)
+	}
+
+	s.releaseSlot(entry)
+	s.mu.Lock()
+	delete(s.refreshFailures, key)
+	s.mu.Unlock()
+	s.remove(ctx, key)
+	s.emit(EventSessionTerminated, key, entry, nil)
+	return true, nil
+}
+
+// forEach calls fn for keys with bounded concurrency and returns how many
+// calls reported success, along with all errors
+func (s *UserSessionService) forEach(ctx context.Context, keys []SessionKey, fn func(SessionKey) (bool, error)) (int, error) {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		count int
+		errs  []error
+		slots = make(chan struct{}, s.refreshConcurrency)
+	)
+	for _, key := range keys {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return count, errors.Join(append(errs, ctx.Err())...)
+		case slots <- struct{}{}:
+		}
+		wg.Go(func() {
+			defer func() { <-slots }()
+			ok, err := fn(key)
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				count++
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
+		})
+	}
+	wg.Wait()
+	return count, errors.Join(errs...)
+}
+
+// Block keeps subject from creating new game sessions. Its existing sessions
+// stay valid until they are terminated.
+func (s *UserSessionService) Block(ctx context.Context, subject string) error {
+	return s.setBlocked(ctx, subject, true)
+}
+
+// Unblock lets subject create game sessions again
+func (s *UserSessionService) Unblock(ctx context.Context, subject string) error {
+	return s.setBlocked(ctx, subject, false)
+}
+
+func (s *UserSessionService) setBlocked(ctx context.Context, subject string, blocked bool) error {
+	if s.state != nil {
+		if err := s.state.SetBlocked(ctx, subject, blocked); err != nil {
+			return fmt.Errorf("failed to persist blocked subject: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if blocked {
+		s.blocked[subject] = true
+	} else {
+		delete(s.blocked, subject)
+	}
+	log.Printf("Subject %s blocked: %v", subject, blocked)
+	return nil
+}
+
+// Blocked returns the blocked subjects in order
+func (s *UserSessionService) Blocked() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Sorted(maps.Keys(s.blocked))
+}
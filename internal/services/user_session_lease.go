@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"hsm/internal/client"
+)
+
+// errLeaseExpired is the error of the events of sessions that missed their heartbeat
+var errLeaseExpired = errors.New("lease expired without a heartbeat")
+
+// WithLeaseTTL requires subjects to heartbeat their game session within ttl,
+// see KeepLeases. Leases are disabled by default.
+func WithLeaseTTL(ttl time.Duration) UserSessionOption {
+	return func(s *UserSessionService) {
+		s.leaseTTL = max(ttl, 0)
+	}
+}
+
+// LeaseTTL returns the lease TTL, zero when leases are disabled
+func (s *UserSessionService) LeaseTTL() time.Duration {
+	return s.leaseTTL
+}
+
+// LeaseExpiresAt returns when the game session of key is terminated
+// without a heartbeat, zero when leases are disabled or there is no session
+func (s *UserSessionService) LeaseExpiresAt(key SessionKey) time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if entry, exists := s.sessions[key]; exists {
+		return entry.lease
+	}
+	return time.Time{}
+}
+
+// Heartbeat renews the lease of the game session of key and returns the
+// session, nil if there is none
+func (s *UserSessionService) Heartbeat(key SessionKey) *client.GameSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, exists := s.sessions[key]
+	if !exists {
+		return nil
+	}
+	s.renewLease(entry)
+	return entry.session
+}
+
+// renewLease extends the lease of entry by the lease TTL
+func (s *UserSessionService) renewLease(entry *userSession) {
+	if s.leaseTTL > 0 {
+		entry.lease = time.Now().Add(s.leaseTTL)
+		entry.warned = false
+	}
+}
+
+// KeepLeases terminates game sessions whose lease expired until ctx is
+// cancelled. It returns immediately when leases are disabled.
+func (s *UserSessionService) KeepLeases(ctx context.Context) {
+	if s.leaseTTL <= 0 {
+		return
+	}
+	ticker := time.NewTicker(min(s.leaseTTL/2, RefreshCheckInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.ReapExpiredLeases(ctx)
+		}
+	}
+}
+
+// ReapExpiredLeases removes the game sessions whose lease expired, terminates
+// them upstream and returns how many were removed. Subjects with a request in
+// flight are skipped, the request renews the lease. Sessions with less than
+// half of the TTL left get an EventSessionExpiring.
+func (s *UserSessionService) ReapExpiredLeases(ctx context.Context) int {
+	if s.leaseTTL <= 0 {
+		return 0
+	}
+
+	s.mu.Lock()
+	var expired []SessionKey
+	var expiring []Event
+	now := time.Now()
+	for key, entry := range s.sessions {
+		switch {
+		case now.After(entry.lease):
+			expired = append(expired, key)
+		case !entry.warned && entry.lease.Sub(now) < s.leaseTTL/2:
+			entry.warned = true
+			event := NewEvent(EventSessionExpiring, key, entry.account, nil, nil)
+			event.ExpiresAt = entry.lease
+			expiring = append(expiring, event)
+		}
+	}
+	s.mu.Unlock()
+	for _, event := range expiring {
+		s.events.Publish(event)
+	}
+
+	reaped := 0
+	for _, key := range expired {
+		if s.reap(ctx, key) {
+			reaped++
+		}
+	}
+	return reaped
+}
+
+// reap removes the session of key if its lease still is expired and
+// terminates it upstream
+func (s *UserSessionService) reap(ctx context.Context, key SessionKey) bool {
+	unlock, ok := s.locks.tryLock(key)
+	if !ok {
+		return false
+	}
+	defer unlock()
+
+	s.mu.Lock()
+	entry, exists := s.sessions[key]
+	if !exists || !time.Now().After(entry.lease) {
+		s.mu.Unlock()
+		return false
+	}
+	delete(s.sessions, key)
+	delete(s.refreshFailures, key)
+	s.mu.Unlock()
+	s.forget(ctx, key)
+	s.releaseSlot(entry)
+
+	log.Printf("Game session of %s missed its heartbeat, terminating it", key)
+	s.emit(EventSessionExpired, key, entry, errLeaseExpired)
+	svc, err := s.service(ctx, key, entry)
+	if err == nil {
+		err = svc.DeleteGameSession(ctx, entry.session.SessionToken)
+	}
+	if err != nil {
+		log.Printf("Failed to terminate game session of %s: %v", key, err)
+	}
+	return true
+}
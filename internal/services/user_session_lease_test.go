@@ -0,0 +1,85 @@
+package services_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+)
+
+func TestUserSessionServiceReapsExpiredLeases(t *testing.T) {
+	backend := hsmtest.New(t)
+	pool := services.NewSingleAccountPool(newSessionService(t, backend))
+	svc := services.NewUserSessionService(pool, services.WithLeaseTTL(50*time.Millisecond))
+
+	alice, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	bob, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "bob"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	if svc.LeaseExpiresAt(services.SessionKey{Subject: "alice"}).IsZero() {
+		t.Fatal("expected a lease for alice")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	// Only bob's game server is still alive
+	if svc.Heartbeat(services.SessionKey{Subject: "bob"}) == nil {
+		t.Fatal("expected bob's session to be found")
+	}
+
+	if got := svc.ReapExpiredLeases(t.Context()); got != 1 {
+		t.Fatalf("expected 1 reaped session, got %d", got)
+	}
+	if svc.GetSession(services.SessionKey{Subject: "alice"}) != nil {
+		t.Fatal("expected alice's session to be removed")
+	}
+	if backend.HasGameSession(alice.SessionToken) {
+		t.Fatal("expected alice's session to be terminated upstream")
+	}
+	if !backend.HasGameSession(bob.SessionToken) || svc.GetSession(services.SessionKey{Subject: "bob"}) == nil {
+		t.Fatal("expected bob's session to survive")
+	}
+	if got := pool.State()[0].ActiveSessions; got != 1 {
+		t.Fatalf("expected 1 active session, got %d", got)
+	}
+}
+
+func TestUserSessionServiceLeasesDisabled(t *testing.T) {
+	backend := hsmtest.New(t)
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(newSessionService(t, backend)))
+
+	if _, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", ""); err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	if got := svc.ReapExpiredLeases(t.Context()); got != 0 || svc.GetSession(services.SessionKey{Subject: "alice"}) == nil {
+		t.Fatal("expected sessions to live without heartbeats when leases are disabled")
+	}
+}
+
+func TestUserSessionServiceWarnsBeforeLeaseExpires(t *testing.T) {
+	backend := hsmtest.New(t)
+	recorder := &eventRecorder{}
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(newSessionService(t, backend)),
+		services.WithLeaseTTL(100*time.Millisecond), services.WithEventSink(recorder))
+	key := services.SessionKey{Subject: "alice"}
+
+	if _, err := svc.GetOrCreateSession(t.Context(), key, "", ""); err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	svc.ReapExpiredLeases(t.Context())
+	svc.ReapExpiredLeases(t.Context())
+
+	want := []services.EventType{services.EventSessionCreated, services.EventSessionExpiring}
+	if got := recorder.types(); !slices.Equal(got, want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	if expiring := recorder.events[1]; !expiring.ExpiresAt.Equal(svc.LeaseExpiresAt(key)) {
+		t.Fatalf("expected the event to carry the end of the lease, got %v", expiring.ExpiresAt)
+	}
+}
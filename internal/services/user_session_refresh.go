@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultRefreshConcurrency bounds the parallel upstream calls of the background refresh
+const DefaultRefreshConcurrency = 4
+
+// RefreshFailure records failed background refreshes of a game session
+type RefreshFailure struct {
+	Time     time.Time // time of the last failure
+	Error    string
+	Attempts int // consecutive failures
+}
+
+// WithRefreshMargin sets how long before ExpiresAt tracked game sessions are
+// refreshed in the background, RefreshThreshold by default
+func WithRefreshMargin(margin time.Duration) UserSessionOption {
+	return func(s *UserSessionService) {
+		if margin > 0 {
+			s.refreshMargin = margin
+		}
+	}
+}
+
+// WithRefreshConcurrency bounds the refreshes running at the same time,
+// DefaultRefreshConcurrency by default
+func WithRefreshConcurrency(n int) UserSessionOption {
+	return func(s *UserSessionService) {
+		if n > 0 {
+			s.refreshConcurrency = n
+		}
+	}
+}
+
+// KeepSessionsFresh refreshes tracked game sessions before they expire until
+// ctx is cancelled, so they stay valid when game servers do not call in
+func (s *UserSessionService) KeepSessionsFresh(ctx context.Context) {
+	ticker := time.NewTicker(RefreshCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.RefreshExpiring(ctx)
+		}
+	}
+}
+
+// RefreshExpiring refreshes every tracked game session that expires within
+// the refresh margin and returns how many were refreshed. Sessions the
+// upstream no longer knows are discarded.
+func (s *UserSessionService) RefreshExpiring(ctx context.Context) int {
+	type due struct {
+		key   SessionKey
+		entry *userSession
+	}
+	deadline := time.Now().Add(s.refreshMargin)
+
+	s.mu.RLock()
+	var sessions []due
+	for key, entry := range s.sessions {
+		if entry.session.ExpiresAt.Before(deadline) {
+			sessions = append(sessions, due{key, entry})
+		}
+	}
+	s.mu.RUnlock()
+
+	var (
+		wg        sync.WaitGroup
+		refreshed atomic.Int32
+		slots     = make(chan struct{}, s.refreshConcurrency)
+	)
+	for _, d := range sessions {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return int(refreshed.Load())
+		case slots <- struct{}{}:
+		}
+		wg.Go(func() {
+			defer func() { <-slots }()
+			if s.refreshInBackground(ctx, d.key, d.entry) {
+				refreshed.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+	return int(refreshed.Load())
+}
+
+// refreshInBackground refreshes the session of entry unless the
+// session changed or was refreshed by a request in the meantime
+func (s *UserSessionService) refreshInBackground(ctx context.Context, key SessionKey, entry *userSession) bool {
+	unlock, err := s.locks.lock(ctx, key)
+	if err != nil {
+		return false
+	}
+	defer unlock()
+
+	if s.entry(key) != entry || !entry.session.ExpiresAt.Before(time.Now().Add(s.refreshMargin)) {
+		return false
+	}
+
+	_, err = s.refresh(ctx, key, entry, false)
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	s.mu.Lock()
+	failure := s.refreshFailures[key]
+	s.refreshFailures[key] = RefreshFailure{Time: time.Now(), Error: err.Error(), Attempts: failure.Attempts + 1}
+	s.mu.Unlock()
+	log.Printf("Failed to refresh game session of %s: %v", key, err)
+
+	if sessionGone(err) || errors.Is(err, ErrNotLinked) {
+		// The session is gone upstream, the next request creates a new one
+		s.releaseSlot(entry)
+		s.remove(ctx, key)
+		s.emit(EventSessionExpired, key, entry, err)
+	} else if s.leaseTTL <= 0 {
+		// Without leases the game session itself is what runs out
+		s.warnExpiring(key, entry)
+	}
+	return false
+}
+
+// warnExpiring sends an EventSessionExpiring for the game session of entry,
+// once until it is refreshed. ExpiresAt is the end of the game session.
+func (s *UserSessionService) warnExpiring(key SessionKey, entry *userSession) {
+	s.mu.Lock()
+	if entry.warned {
+		s.mu.Unlock()
+		return
+	}
+	entry.warned = true
+	event := NewEvent(EventSessionExpiring, key, entry.account, entry.session, nil)
+	s.mu.Unlock()
+	s.events.Publish(event)
+}
+
+// RefreshFailure returns the failed background refreshes of key since its
+// game session was last refreshed successfully
+func (s *UserSessionService) RefreshFailure(key SessionKey) (RefreshFailure, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	failure, ok := s.refreshFailures[key]
+	return failure, ok
+}
@@ -0,0 +1,83 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+)
+
+func TestUserSessionServiceRefreshExpiring(t *testing.T) {
+	backend := hsmtest.New(t)
+	backend.SetGameSessionTTL(2 * time.Minute)
+	pool := services.NewSingleAccountPool(newSessionService(t, backend))
+	svc := services.NewUserSessionService(pool, services.WithRefreshMargin(5*time.Minute), services.WithRefreshConcurrency(2))
+
+	for _, subject := range []string{"alice", "bob", "carol"} {
+		if _, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: subject}, "", ""); err != nil {
+			t.Fatalf("GetOrCreateSession(%s): %v", subject, err)
+		}
+	}
+	backend.SetGameSessionTTL(time.Hour)
+	// carol's session ends upstream
+	if err := pool.Primary().Client().TerminateGameSession(t.Context(), svc.GetSession(services.SessionKey{Subject: "carol"}).SessionToken); err != nil {
+		t.Fatalf("TerminateGameSession: %v", err)
+	}
+
+	if got := svc.RefreshExpiring(t.Context()); got != 2 {
+		t.Fatalf("expected 2 refreshed sessions, got %d", got)
+	}
+	if until := time.Until(svc.GetSession(services.SessionKey{Subject: "alice"}).ExpiresAt); until < 30*time.Minute {
+		t.Fatalf("expected alice's session to be extended, expires in %v", until)
+	}
+	if svc.GetSession(services.SessionKey{Subject: "carol"}) != nil {
+		t.Fatal("expected the terminated session to be discarded")
+	}
+	if _, ok := svc.RefreshFailure(services.SessionKey{Subject: "carol"}); ok {
+		t.Fatal("expected no failure to be kept for the discarded session")
+	}
+	if _, ok := svc.RefreshFailure(services.SessionKey{Subject: "alice"}); ok {
+		t.Fatal("expected no failure for alice")
+	}
+
+	// Nothing is due once every session was extended
+	if got := svc.RefreshExpiring(t.Context()); got != 0 {
+		t.Fatalf("expected no refreshes, got %d", got)
+	}
+	if got := pool.State()[0].ActiveSessions; got != 2 {
+		t.Fatalf("expected 2 active sessions, got %d", got)
+	}
+}
+
+func TestUserSessionServiceWarnsExpiringWithoutLeases(t *testing.T) {
+	backend := hsmtest.New(t)
+	backend.SetGameSessionTTL(2 * time.Minute)
+	recorder := &eventRecorder{}
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(newSessionService(t, backend)), services.WithEventSink(recorder))
+	key := services.SessionKey{Subject: "alice"}
+	session, err := svc.GetOrCreateSession(t.Context(), key, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+
+	for range 2 {
+		backend.FailNext(hsmtest.RouteGameSessionRefresh, 503, `{"error":"unavailable"}`)
+		svc.RefreshExpiring(t.Context())
+	}
+
+	failure, ok := svc.RefreshFailure(key)
+	if !ok || failure.Attempts != 2 || failure.Error == "" {
+		t.Fatalf("expected 2 recorded failures, got %+v", failure)
+	}
+
+	var expiring []services.Event
+	for _, event := range recorder.events {
+		if event.Type == services.EventSessionExpiring {
+			expiring = append(expiring, event)
+		}
+	}
+	if len(expiring) != 1 || !expiring[0].ExpiresAt.Equal(session.ExpiresAt) {
+		t.Fatalf("expected one expiring event at the end of the game session, got %+v", expiring)
+	}
+}
@@ -0,0 +1,298 @@
+package services_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+	"hsm/internal/sessionstore"
+)
+
+func TestUserSessionServiceReusesSession(t *testing.T) {
+	backend := hsmtest.New(t)
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(newSessionService(t, backend)))
+
+	first, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	second, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+
+	if first.SessionToken != second.SessionToken {
+		t.Fatal("expected existing session to be reused")
+	}
+	if got := backend.Calls(hsmtest.RouteGameSessionNew); got != 1 {
+		t.Fatalf("expected 1 upstream create, got %d", got)
+	}
+	if got := backend.Calls(hsmtest.RouteGameSessionRefresh); got != 1 {
+		t.Fatalf("expected 1 upstream refresh, got %d", got)
+	}
+}
+
+func TestUserSessionServiceSeparatesSubjects(t *testing.T) {
+	backend := hsmtest.New(t)
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(newSessionService(t, backend)))
+
+	alice, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	bob, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "bob"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+
+	if alice.SessionToken == bob.SessionToken {
+		t.Fatal("expected distinct sessions per subject")
+	}
+	if backend.GameSessionCount() != 2 {
+		t.Fatalf("expected 2 live game sessions, got %d", backend.GameSessionCount())
+	}
+}
+
+func TestUserSessionServiceRecreatesInvalidSession(t *testing.T) {
+	backend := hsmtest.New(t)
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(newSessionService(t, backend)))
+
+	first, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+
+	backend.FailNext(hsmtest.RouteGameSessionRefresh, 401, `{"code":"unauthorized"}`)
+	second, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+
+	if first.SessionToken == second.SessionToken {
+		t.Fatal("expected a new session after refresh failure")
+	}
+}
+
+func TestUserSessionServiceDeleteAndRefresh(t *testing.T) {
+	backend := hsmtest.New(t)
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(newSessionService(t, backend)))
+
+	if session, err := svc.RefreshSession(t.Context(), services.SessionKey{Subject: "alice"}); err != nil || session != nil {
+		t.Fatalf("expected no session for unknown subject, got %+v, %v", session, err)
+	}
+
+	created, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+
+	refreshed, err := svc.RefreshSession(t.Context(), services.SessionKey{Subject: "alice"})
+	if err != nil {
+		t.Fatalf("RefreshSession: %v", err)
+	}
+	if refreshed.SessionToken != created.SessionToken {
+		t.Fatal("expected refresh to keep the session token")
+	}
+
+	if err := svc.DeleteSession(t.Context(), services.SessionKey{Subject: "alice"}); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if svc.GetSession(services.SessionKey{Subject: "alice"}) != nil {
+		t.Fatal("expected session to be removed")
+	}
+	if backend.HasGameSession(created.SessionToken) {
+		t.Fatal("expected upstream session to be terminated")
+	}
+}
+
+func TestUserSessionServiceSubjectsProceedInParallel(t *testing.T) {
+	backend := hsmtest.New(t)
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(newSessionService(t, backend)))
+	backend.SetLatency(200 * time.Millisecond)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Go(func() {
+			if _, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: fmt.Sprintf("subject-%d", i)}, "", ""); err != nil {
+				t.Errorf("GetOrCreateSession: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	// Serialized, the creates would take at least 10 * 200ms
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected subjects to be served in parallel, took %v", elapsed)
+	}
+}
+
+func TestUserSessionServiceCoalescesSubjectRequests(t *testing.T) {
+	backend := hsmtest.New(t)
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(newSessionService(t, backend)))
+	backend.SetLatency(50 * time.Millisecond)
+
+	tokens := make(chan string, 5)
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			session, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", "")
+			if err != nil {
+				t.Errorf("GetOrCreateSession: %v", err)
+				return
+			}
+			tokens <- session.SessionToken
+		})
+	}
+	wg.Wait()
+	close(tokens)
+
+	first := <-tokens
+	for token := range tokens {
+		if token != first {
+			t.Fatal("expected concurrent requests of a subject to share the session")
+		}
+	}
+	if got := backend.Calls(hsmtest.RouteGameSessionNew); got != 1 {
+		t.Fatalf("expected 1 upstream create, got %d", got)
+	}
+}
+
+// BenchmarkUserSessionServiceContention measures requests of many subjects
+// against an upstream with latency, which used to be served one at a time
+func BenchmarkUserSessionServiceContention(b *testing.B) {
+	backend := hsmtest.New(b)
+	sessionPath := filepath.Join(b.TempDir(), "session.json")
+	backend.WriteSessionFile(b, sessionPath)
+	sessionService, err := services.NewSessionService(b.Context(), backend.Client(), sessionstore.NewFileStore(sessionPath))
+	if err != nil {
+		b.Fatalf("NewSessionService: %v", err)
+	}
+	b.Cleanup(sessionService.Close)
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(sessionService))
+	backend.SetLatency(2 * time.Millisecond)
+
+	var next atomic.Int64
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			subject := fmt.Sprintf("subject-%d", next.Add(1)%64)
+			if _, err := svc.GetOrCreateSession(b.Context(), services.SessionKey{Subject: subject}, "", ""); err != nil {
+				b.Errorf("GetOrCreateSession: %v", err)
+			}
+		}
+	})
+}
+
+func TestUserSessionServiceInstances(t *testing.T) {
+	backend := hsmtest.New(t)
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(newSessionService(t, backend)), services.WithMaxSessionsPerSubject(2))
+
+	defaultKey := services.SessionKey{Subject: "alice"}
+	lobbyKey := services.SessionKey{Subject: "alice", Instance: "lobby"}
+	first, err := svc.GetOrCreateSession(t.Context(), defaultKey, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	lobby, err := svc.GetOrCreateSession(t.Context(), lobbyKey, "", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	if first.SessionToken == lobby.SessionToken {
+		t.Fatal("expected each instance to get its own session")
+	}
+
+	if _, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice", Instance: "survival"}, "", ""); !errors.Is(err, services.ErrInstanceLimit) {
+		t.Fatalf("expected ErrInstanceLimit, got %v", err)
+	}
+	// Other subjects are not affected by alice's limit
+	if _, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "bob"}, "", ""); err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	// Existing instances are still reused at the limit
+	if _, err := svc.GetOrCreateSession(t.Context(), lobbyKey, "", ""); err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+
+	sessions := svc.ListSessions("alice")
+	if len(sessions) != 2 || sessions[0].Instance != "" || sessions[1].Instance != "lobby" {
+		t.Fatalf("unexpected sessions %+v", sessions)
+	}
+
+	if err := svc.DeleteSession(t.Context(), lobbyKey); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if backend.HasGameSession(lobby.SessionToken) || !backend.HasGameSession(first.SessionToken) {
+		t.Fatal("expected only the lobby session to be terminated")
+	}
+	if _, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice", Instance: "survival"}, "", ""); err != nil {
+		t.Fatalf("expected room for another instance after delete: %v", err)
+	}
+}
+
+func TestUserSessionServiceAdmin(t *testing.T) {
+	backend := hsmtest.New(t)
+	pool := services.NewSingleAccountPool(newSessionService(t, backend))
+	statePath := filepath.Join(t.TempDir(), "game-sessions.json")
+	svc := services.NewUserSessionService(pool, services.WithStateStore(services.NewFileStateStore(statePath)))
+
+	for _, key := range []services.SessionKey{{Subject: "alice"}, {Subject: "alice", Instance: "lobby"}, {Subject: "bob"}} {
+		if _, err := svc.GetOrCreateSession(t.Context(), key, "", ""); err != nil {
+			t.Fatalf("GetOrCreateSession(%s): %v", key, err)
+		}
+	}
+
+	sessions := svc.Sessions("")
+	if len(sessions) != 3 || sessions[0].Key.Subject != "alice" || sessions[2].Key.Subject != "bob" {
+		t.Fatalf("unexpected sessions %+v", sessions)
+	}
+	if info := sessions[0]; info.Account != "default" || info.CreatedAt.IsZero() || info.RefreshedAt.IsZero() {
+		t.Fatalf("unexpected session info %+v", info)
+	}
+
+	if refreshed, err := svc.RefreshSubject(t.Context(), "alice"); err != nil || refreshed != 2 {
+		t.Fatalf("RefreshSubject: refreshed=%d err=%v", refreshed, err)
+	}
+	if terminated, err := svc.TerminateSubject(t.Context(), "alice"); err != nil || terminated != 2 {
+		t.Fatalf("TerminateSubject: terminated=%d err=%v", terminated, err)
+	}
+	if len(svc.Sessions("alice")) != 0 || len(svc.Sessions("bob")) != 1 {
+		t.Fatal("expected only alice's sessions to be terminated")
+	}
+
+	if err := svc.Block(t.Context(), "alice"); err != nil {
+		t.Fatalf("Block: %v", err)
+	}
+	if _, err := svc.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", ""); !errors.Is(err, services.ErrSubjectBlocked) {
+		t.Fatalf("expected ErrSubjectBlocked, got %v", err)
+	}
+
+	// Blocks survive a restart
+	restarted := services.NewUserSessionService(pool, services.WithStateStore(services.NewFileStateStore(statePath)))
+	if err := restarted.Restore(t.Context()); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if blocked := restarted.Blocked(); len(blocked) != 1 || blocked[0] != "alice" {
+		t.Fatalf("expected alice to stay blocked, got %v", blocked)
+	}
+	if err := restarted.Unblock(t.Context(), "alice"); err != nil {
+		t.Fatalf("Unblock: %v", err)
+	}
+	if _, err := restarted.GetOrCreateSession(t.Context(), services.SessionKey{Subject: "alice"}, "", ""); err != nil {
+		t.Fatalf("GetOrCreateSession after unblock: %v", err)
+	}
+
+	if terminated, err := restarted.TerminateAll(t.Context()); err != nil || terminated != 2 {
+		t.Fatalf("TerminateAll: terminated=%d err=%v", terminated, err)
+	}
+	if backend.GameSessionCount() != 0 {
+		t.Fatalf("expected no upstream sessions, got %d", backend.GameSessionCount())
+	}
+}
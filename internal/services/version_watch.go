@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// DefaultVersionCheckInterval is how often VersionWatcher looks for new versions
+const DefaultVersionCheckInterval = 10 * time.Minute
+
+// VersionWatcher publishes an EventVersionAvailable when the latest version
+// of a watched patchline changes
+type VersionWatcher struct {
+	downloads  *DownloadService
+	sink       EventSink
+	patchlines []string
+	known      map[string]string // patchline -> latest version
+}
+
+// NewVersionWatcher creates a VersionWatcher for patchlines, publishing to sink
+func NewVersionWatcher(downloads *DownloadService, sink EventSink, patchlines ...string) *VersionWatcher {
+	return &VersionWatcher{
+		downloads:  downloads,
+		sink:       sink,
+		patchlines: patchlines,
+		known:      make(map[string]string),
+	}
+}
+
+// Run checks for new versions every interval until ctx is cancelled
+func (w *VersionWatcher) Run(ctx context.Context, interval time.Duration) {
+	w.Check(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check(ctx)
+		}
+	}
+}
+
+// Check fetches the latest version of every patchline and publishes the ones
+// that changed since the last check. The first version seen of a patchline is
+// not published.
+func (w *VersionWatcher) Check(ctx context.Context) {
+	for _, patchline := range w.patchlines {
+		version, err := w.downloads.GetVersion(ctx, patchline)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				log.Printf("Failed to check the version of patchline %s: %v", patchline, err)
+			}
+			continue
+		}
+
+		known, seen := w.known[patchline]
+		w.known[patchline] = version
+		if !seen || known == version {
+			continue
+		}
+		log.Printf("New version %s on patchline %s", version, patchline)
+		event := NewEvent(EventVersionAvailable, SessionKey{}, "", nil, nil)
+		event.Patchline = patchline
+		event.Version = version
+		w.sink.Publish(event)
+	}
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"hsm/internal/utils"
+)
+
+const (
+	// WebhookSignatureHeader carries the HMAC-SHA256 of the request body,
+	// hex encoded and prefixed with "sha256="
+	WebhookSignatureHeader = "X-HSM-Signature-256"
+	// WebhookEventHeader carries the type of the event
+	WebhookEventHeader = "X-HSM-Event"
+	// WebhookDeliveryHeader carries the id of the event, which stays the same
+	// across retries
+	WebhookDeliveryHeader = "X-HSM-Delivery"
+
+	// DefaultWebhookMaxAttempts is how often a delivery is tried before it is dropped
+	DefaultWebhookMaxAttempts = 10
+	// DefaultWebhookMaxPending is how many deliveries the outbox holds before
+	// the oldest are dropped
+	DefaultWebhookMaxPending = 10000
+
+	webhookTimeout = 10 * time.Second
+)
+
+// webhookDelivery is an event waiting to be delivered to one target
+type webhookDelivery struct {
+	Target      string    `json:"target"`
+	Event       Event     `json:"event"`
+	Attempts    int       `json:"attempts,omitempty"`
+	NextAttempt time.Time `json:"nextAttempt,omitzero"`
+}
+
+// WebhookDispatcher posts game session events as signed JSON to the
+// configured targets. Events wait in an outbox until the target accepted them
+// with a 2xx response; failed deliveries are retried with exponential backoff.
+// Deliveries to a target keep the order of the events. The outbox is written
+// by Run, so publishing does not wait for the disk.
+type WebhookDispatcher struct {
+	targets []string
+	secret  []byte
+	client  *http.Client
+	outbox  string // file keeping undelivered events across restarts, memory only when empty
+	pending []webhookDelivery
+	dirty   bool // pending changed since the outbox was written
+	wake    chan struct{}
+	changed chan struct{}
+	mu      sync.Mutex
+	saveMu  sync.Mutex // keeps the writes of the outbox in order
+
+	maxAttempts    int
+	maxPending     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// WebhookOption is a functional option for configuring the WebhookDispatcher
+type WebhookOption func(*WebhookDispatcher)
+
+// WithWebhookOutbox keeps undelivered events in the file at path, so they
+// are delivered after a restart
+func WithWebhookOutbox(path string) WebhookOption {
+	return func(d *WebhookDispatcher) {
+		d.outbox = path
+	}
+}
+
+// WithWebhookRetries sets how often a delivery is tried and the bounds of the
+// backoff between attempts
+func WithWebhookRetries(maxAttempts int, initialBackoff, maxBackoff time.Duration) WebhookOption {
+	return func(d *WebhookDispatcher) {
+		if maxAttempts > 0 {
+			d.maxAttempts = maxAttempts
+		}
+		if initialBackoff > 0 {
+			d.initialBackoff = initialBackoff
+		}
+		if maxBackoff > 0 {
+			d.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithWebhookMaxPending sets how many deliveries the outbox holds before the
+// oldest are dropped, DefaultWebhookMaxPending by default
+func WithWebhookMaxPending(n int) WebhookOption {
+	return func(d *WebhookDispatcher) {
+		if n > 0 {
+			d.maxPending = n
+		}
+	}
+}
+
+// NewWebhookDispatcher creates a WebhookDispatcher posting to targets, signed
+// with secret. Events left in the outbox by an earlier run are loaded.
+func NewWebhookDispatcher(targets []string, secret string, opts ...WebhookOption) (*WebhookDispatcher, error) {
+	if secret == "" {
+		return nil, errors.New("webhooks need a signing secret")
+	}
+	d := &WebhookDispatcher{
+		targets:        targets,
+		secret:         []byte(secret),
+		client:         &http.Client{Timeout: webhookTimeout},
+		wake:           make(chan struct{}, 1),
+		changed:        make(chan struct{}, 1),
+		maxAttempts:    DefaultWebhookMaxAttempts,
+		maxPending:     DefaultWebhookMaxPending,
+		initialBackoff: time.Second,
+		maxBackoff:     5 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Publish queues event for every target. When the outbox is full, the oldest
+// deliveries are dropped.
+func (d *WebhookDispatcher) Publish(event Event) {
+	d.mu.Lock()
+	for _, target := range d.targets {
+		d.pending = append(d.pending, webhookDelivery{Target: target, Event: event})
+	}
+	d.dropOldestLocked()
+	d.changedLocked()
+	d.mu.Unlock()
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Pending returns the number of deliveries waiting in the outbox
+func (d *WebhookDispatcher) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.pending)
+}
+
+// Run delivers queued events and writes the outbox until ctx is cancelled.
+// Undelivered events stay in the outbox.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	go d.saveChanges(ctx)
+	defer d.save()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-timer.C:
+		}
+
+		next := d.deliverDue(ctx)
+		timer.Stop()
+		if !next.IsZero() {
+			timer.Reset(time.Until(next))
+		}
+	}
+}
+
+// deliverDue sends every due delivery and returns when the next one is due,
+// zero if none is waiting
+func (d *WebhookDispatcher) deliverDue(ctx context.Context) time.Time {
+	waiting := make(map[string]bool) // targets with an earlier delivery waiting for a retry
+	var next time.Time
+	for i := 0; ; i++ {
+		d.mu.Lock()
+		if i >= len(d.pending) {
+			d.mu.Unlock()
+			return next
+		}
+		delivery := d.pending[i]
+		d.mu.Unlock()
+
+		if waiting[delivery.Target] {
+			continue
+		}
+		if time.Now().Before(delivery.NextAttempt) {
+			waiting[delivery.Target] = true
+			if next.IsZero() || delivery.NextAttempt.Before(next) {
+				next = delivery.NextAttempt
+			}
+			continue
+		}
+
+		err := d.send(ctx, delivery)
+		if ctx.Err() != nil {
+			return time.Time{}
+		}
+
+		d.mu.Lock()
+		// Publish may have dropped older deliveries in the meantime
+		i = d.indexLocked(delivery)
+		if i < 0 {
+			// Dropped itself, the earlier deliveries are gone as well
+			d.mu.Unlock()
+			continue
+		}
+		if err == nil {
+			d.pending = append(d.pending[:i], d.pending[i+1:]...)
+			i--
+		} else if delivery.Attempts+1 >= d.maxAttempts {
+			log.Printf("Dropping webhook %s of event %s after %d attempts: %v", delivery.Target, delivery.Event.ID, delivery.Attempts+1, err)
+			d.pending = append(d.pending[:i], d.pending[i+1:]...)
+			i--
+		} else {
+			log.Printf("Failed to deliver webhook %s of event %s, retrying: %v", delivery.Target, delivery.Event.ID, err)
+			d.pending[i].Attempts++
+			d.pending[i].NextAttempt = time.Now().Add(d.backoff(d.pending[i].Attempts))
+			waiting[delivery.Target] = true
+			if next.IsZero() || d.pending[i].NextAttempt.Before(next) {
+				next = d.pending[i].NextAttempt
+			}
+		}
+		d.changedLocked()
+		d.mu.Unlock()
+	}
+}
+
+// dropOldestLocked drops the oldest deliveries beyond the size of the outbox
+func (d *WebhookDispatcher) dropOldestLocked() {
+	if over := len(d.pending) - d.maxPending; over > 0 {
+		log.Printf("Webhook outbox is full, dropping the %d oldest deliveries", over)
+		d.pending = slices.Delete(d.pending, 0, over)
+	}
+}
+
+// indexLocked returns the position of delivery in the outbox, -1 if it was dropped
+func (d *WebhookDispatcher) indexLocked(delivery webhookDelivery) int {
+	return slices.IndexFunc(d.pending, func(p webhookDelivery) bool {
+		return p.Target == delivery.Target && p.Event.ID == delivery.Event.ID
+	})
+}
+
+// send posts the event of delivery to its target
+func (d *WebhookDispatcher) send(ctx context.Context, delivery webhookDelivery) error {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(d.secret, body))
+	req.Header.Set(WebhookEventHeader, string(delivery.Event.Type))
+	req.Header.Set(WebhookDeliveryHeader, delivery.Event.ID)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// backoff returns the delay after the given failed attempt (1-based)
+func (d *WebhookDispatcher) backoff(attempt int) time.Duration {
+	wait := d.initialBackoff
+	for i := 1; i < attempt && wait < d.maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, d.maxBackoff)
+}
+
+// SignWebhook returns the value of the WebhookSignatureHeader for body
+func SignWebhook(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *WebhookDispatcher) load() error {
+	if d.outbox == "" {
+		return nil
+	}
+	data, err := os.ReadFile(d.outbox)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read webhook outbox: %w", err)
+	}
+	if err := json.Unmarshal(data, &d.pending); err != nil {
+		return fmt.Errorf("failed to parse webhook outbox %s: %w", d.outbox, err)
+	}
+	d.dropOldestLocked()
+	if len(d.pending) > 0 {
+		log.Printf("Loaded %d undelivered webhooks", len(d.pending))
+	}
+	return nil
+}
+
+// changedLocked marks the outbox for writing
+func (d *WebhookDispatcher) changedLocked() {
+	if d.outbox == "" {
+		return
+	}
+	d.dirty = true
+	select {
+	case d.changed <- struct{}{}:
+	default:
+	}
+}
+
+// saveChanges writes the outbox whenever it changed until ctx is cancelled
+func (d *WebhookDispatcher) saveChanges(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.changed:
+			d.save()
+		}
+	}
+}
+
+// save writes the pending deliveries to the outbox if they changed
+func (d *WebhookDispatcher) save() {
+	d.saveMu.Lock()
+	defer d.saveMu.Unlock()
+
+	d.mu.Lock()
+	if !d.dirty {
+		d.mu.Unlock()
+		return
+	}
+	d.dirty = false
+	pending := d.pending
+	if pending == nil {
+		pending = []webhookDelivery{}
+	}
+	data, err := json.MarshalIndent(pending, "", "  ")
+	d.mu.Unlock()
+
+	if err == nil {
+		err = utils.WriteSecretFile(d.outbox, data)
+	}
+	if err != nil {
+		log.Printf("Failed to write webhook outbox: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"hsm/internal/hsmtest"
+	"hsm/internal/services"
+)
+
+// webhookTarget records the events posted to it and fails the first
+// failures requests
+type webhookTarget struct {
+	*httptest.Server
+	mu       sync.Mutex
+	failures int
+	events   []services.Event
+	requests int
+	received chan struct{}
+}
+
+func newWebhookTarget(t *testing.T, secret string, failures int) *webhookTarget {
+	t.Helper()
+	target := &webhookTarget{failures: failures, received: make(chan struct{}, 100)}
+	target.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if got := r.Header.Get(services.WebhookSignatureHeader); got != services.SignWebhook([]byte(secret), body) {
+			t.Errorf("unexpected signature %q", got)
+		}
+
+		target.mu.Lock()
+		defer target.mu.Unlock()
+		target.requests++
+		if target.requests <= target.failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event services.Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		if r.Header.Get(services.WebhookEventHeader) != string(event.Type) || r.Header.Get(services.WebhookDeliveryHeader) != event.ID {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		target.events = append(target.events, event)
+		target.received <- struct{}{}
+	}))
+	t.Cleanup(target.Close)
+	return target
+}
+
+func (w *webhookTarget) wait(t *testing.T, n int) []services.Event {
+	t.Helper()
+	for range n {
+		select {
+		case <-w.received:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for webhook")
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.events)
+}
+
+func TestWebhookDispatcherRetries(t *testing.T) {
+	target := newWebhookTarget(t, "secret", 2)
+	dispatcher, err := services.NewWebhookDispatcher([]string{target.URL}, "secret", services.WithWebhookRetries(5, time.Millisecond, 10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewWebhookDispatcher: %v", err)
+	}
+	go dispatcher.Run(t.Context())
+
+	first := services.NewEvent(services.EventSessionCreated, services.SessionKey{Subject: "alice"}, "default", nil, nil)
+	second := services.NewEvent(services.EventSessionTerminated, services.SessionKey{Subject: "alice"}, "default", nil, nil)
+	dispatcher.Publish(first)
+	dispatcher.Publish(second)
+
+	events := target.wait(t, 2)
+	if events[0].ID != first.ID || events[1].ID != second.ID {
+		t.Fatalf("expected the events in order, got %+v", events)
+	}
+	if target.requests != 4 {
+		t.Fatalf("expected 2 failed and 2 successful requests, got %d", target.requests)
+	}
+}
+
+func TestWebhookDispatcherOutbox(t *testing.T) {
+	outbox := filepath.Join(t.TempDir(), "outbox.json")
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	dispatcher, err := services.NewWebhookDispatcher([]string{down.URL}, "secret", services.WithWebhookOutbox(outbox))
+	if err != nil {
+		t.Fatalf("NewWebhookDispatcher: %v", err)
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+	event := services.NewEvent(services.EventSessionExpired, services.SessionKey{Subject: "alice"}, "default", nil, nil)
+	dispatcher.Publish(event)
+
+	// The first run ended before the event could be delivered
+	cancel()
+	<-done
+	restarted, err := services.NewWebhookDispatcher([]string{down.URL}, "secret", services.WithWebhookOutbox(outbox))
+	if err != nil {
+		t.Fatalf("NewWebhookDispatcher: %v", err)
+	}
+	if got := restarted.Pending(); got != 1 {
+		t.Fatalf("expected 1 pending delivery, got %d", got)
+	}
+
+	if _, err := services.NewWebhookDispatcher(nil, "", services.WithWebhookOutbox(outbox)); err == nil {
+		t.Fatal("expected an error without a secret")
+	}
+}
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []services.Event
+}
+
+func (r *eventRecorder) Publish(event services.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *eventRecorder) types() []services.EventType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var types []services.EventType
+	for _, event := range r.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestUserSessionServiceEvents(t *testing.T) {
+	backend := hsmtest.New(t)
+	recorder := &eventRecorder{}
+	svc := services.NewUserSessionService(services.NewSingleAccountPool(newSessionService(t, backend)), services.WithEventSink(recorder))
+	key := services.SessionKey{Subject: "alice", Instance: "lobby"}
+
+	if _, err := svc.GetOrCreateSession(t.Context(), key, "", ""); err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	if _, err := svc.RefreshSession(t.Context(), key); err != nil {
+		t.Fatalf("RefreshSession: %v", err)
+	}
+	if err := svc.DeleteSession(t.Context(), key); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+
+	want := []services.EventType{services.EventSessionCreated, services.EventSessionRefreshed, services.EventSessionTerminated}
+	if got := recorder.types(); !slices.Equal(got, want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	created := recorder.events[0]
+	if created.Subject != "alice" || created.Instance != "lobby" || created.Account != "default" || created.ExpiresAt.IsZero() || created.ID == "" {
+		t.Fatalf("unexpected event %+v", created)
+	}
+}
+
+func TestWebhookDispatcherDropsOldestWhenFull(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	dispatcher, err := services.NewWebhookDispatcher([]string{down.URL}, "secret", services.WithWebhookMaxPending(2))
+	if err != nil {
+		t.Fatalf("NewWebhookDispatcher: %v", err)
+	}
+	for range 3 {
+		dispatcher.Publish(services.NewEvent(services.EventSessionCreated, services.SessionKey{Subject: "alice"}, "default", nil, nil))
+	}
+	if got := dispatcher.Pending(); got != 2 {
+		t.Fatalf("expected the outbox to hold 2 deliveries, got %d", got)
+	}
+}
@@ -0,0 +1,159 @@
+package sessionstore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	// envelopeVersion marks encrypted session documents
+	envelopeVersion = "hsm-session-v1"
+	// kdfPBKDF2 is the only supported key derivation function
+	kdfPBKDF2 = "pbkdf2-sha256"
+	// DefaultKDFIterations follows the OWASP recommendation for PBKDF2-HMAC-SHA256
+	DefaultKDFIterations = 600_000
+
+	saltSize = 16
+	keySize  = 32 // AES-256
+)
+
+var (
+	// ErrEncrypted is returned when reading an encrypted session without a key
+	ErrEncrypted = errors.New("session is encrypted, configure the session key")
+	// ErrDecrypt is returned when the session cannot be decrypted with the configured key
+	ErrDecrypt = errors.New("failed to decrypt session, wrong key or corrupted data")
+)
+
+// envelope is the on-disk format of an encrypted session
+type envelope struct {
+	Encrypted  string `json:"hsm_encrypted"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Cipher encrypts session documents with AES-256-GCM using a key derived from
+// a passphrase with PBKDF2. Derived keys are cached per salt because the
+// derivation is deliberately slow and sessions are saved frequently.
+type Cipher struct {
+	passphrase string
+	iterations int
+
+	mu   sync.Mutex
+	salt []byte            // salt used for encryption, created on first use
+	keys map[string][]byte // salt -> derived key
+}
+
+// NewCipher creates a Cipher for passphrase
+func NewCipher(passphrase []byte) (*Cipher, error) {
+	passphrase = bytes.TrimSpace(passphrase)
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("session key is empty")
+	}
+	return &Cipher{
+		passphrase: string(passphrase),
+		iterations: DefaultKDFIterations,
+		keys:       make(map[string][]byte),
+	}, nil
+}
+
+// IsEncrypted reports whether data is an encrypted session document
+func IsEncrypted(data []byte) bool {
+	var env struct {
+		Encrypted string `json:"hsm_encrypted"`
+	}
+	return json.Unmarshal(data, &env) == nil && env.Encrypted != ""
+}
+
+// Encrypt seals plaintext into an encrypted session document
+func (c *Cipher) Encrypt(plaintext []byte) ([]byte, error) {
+	c.mu.Lock()
+	if c.salt == nil {
+		c.salt = make([]byte, saltSize)
+		if _, err := rand.Read(c.salt); err != nil {
+			c.mu.Unlock()
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+	}
+	salt := c.salt
+	c.mu.Unlock()
+
+	aead, err := c.aead(salt, c.iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return json.MarshalIndent(envelope{
+		Encrypted:  envelopeVersion,
+		KDF:        kdfPBKDF2,
+		Iterations: c.iterations,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, []byte(envelopeVersion)),
+	}, "", "  ")
+}
+
+// Decrypt opens an encrypted session document
+func (c *Cipher) Decrypt(data []byte) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted session: %w", err)
+	}
+	if env.Encrypted != envelopeVersion || env.KDF != kdfPBKDF2 {
+		return nil, fmt.Errorf("unsupported session encryption %s/%s", env.Encrypted, env.KDF)
+	}
+	if env.Iterations <= 0 || len(env.Salt) == 0 {
+		return nil, fmt.Errorf("invalid encrypted session: missing key derivation parameters")
+	}
+
+	aead, err := c.aead(env.Salt, env.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid encrypted session: bad nonce")
+	}
+
+	plaintext, err := aead.Open(nil, env.Nonce, env.Ciphertext, []byte(envelopeVersion))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func (c *Cipher) aead(salt []byte, iterations int) (cipher.AEAD, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cacheKey := fmt.Sprintf("%d:%x", iterations, salt)
+	key, ok := c.keys[cacheKey]
+	if !ok {
+		var err error
+		key, err = pbkdf2.Key(sha256.New, c.passphrase, salt, iterations, keySize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key: %w", err)
+		}
+		c.keys[cacheKey] = key
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,82 @@
+package sessionstore_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"hsm/internal/client"
+	"hsm/internal/sessionstore"
+)
+
+func TestCipherRoundTrip(t *testing.T) {
+	c, err := sessionstore.NewCipher([]byte("correct horse\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := c.Encrypt([]byte(`{"refresh_token":"super-secret-refresh-token"}`))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !sessionstore.IsEncrypted(sealed) || bytes.Contains(sealed, []byte("super-secret-refresh-token")) {
+		t.Fatalf("expected an encrypted document, got %s", sealed)
+	}
+
+	opened, err := c.Decrypt(sealed)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if string(opened) != `{"refresh_token":"super-secret-refresh-token"}` {
+		t.Fatalf("unexpected plaintext %s", opened)
+	}
+
+	other, _ := sessionstore.NewCipher([]byte("battery staple"))
+	if _, err := other.Decrypt(sealed); !errors.Is(err, sessionstore.ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt with the wrong key, got %v", err)
+	}
+
+	if _, err := sessionstore.NewCipher([]byte(" \n")); err == nil {
+		t.Fatal("expected empty passphrase to be rejected")
+	}
+}
+
+func TestEncryptedFileStoreMigratesPlaintext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := sessionstore.NewFileStore(path).Save(t.Context(), &client.Session{Token: "at", RefreshToken: "rt"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := sessionstore.NewCipher([]byte("secret"))
+	store := sessionstore.NewFileStore(path, sessionstore.WithCipher(c))
+
+	// Plaintext is still readable with a key configured
+	session, err := store.Load(t.Context())
+	if err != nil {
+		t.Fatalf("Load plaintext: %v", err)
+	}
+	if err := store.Save(t.Context(), session); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sessionstore.IsEncrypted(data) {
+		t.Fatal("expected session to be encrypted after saving")
+	}
+
+	if _, err := sessionstore.NewFileStore(path).Load(t.Context()); !errors.Is(err, sessionstore.ErrEncrypted) {
+		t.Fatalf("expected ErrEncrypted without a key, got %v", err)
+	}
+	loaded, err := store.Load(t.Context())
+	if err != nil {
+		t.Fatalf("Load encrypted: %v", err)
+	}
+	if loaded.RefreshToken != "rt" {
+		t.Fatalf("unexpected session %+v", loaded)
+	}
+}
@@ -0,0 +1,58 @@
+package sessionstore
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"hsm/internal/client"
+)
+
+// EnvStore reads the session from an environment variable. The value is the
+// session JSON, either verbatim or base64 encoded. It cannot be written, so
+// refreshed tokens only live in memory.
+type EnvStore struct {
+	variable string
+	opts     *options
+}
+
+// NewEnvStore creates a read-only store for the environment variable
+func NewEnvStore(variable string, opts ...Option) *EnvStore {
+	return &EnvStore{variable: variable, opts: newOptions(opts)}
+}
+
+func (s *EnvStore) Load(ctx context.Context) (*client.Session, error) {
+	value := strings.TrimSpace(os.Getenv(s.variable))
+	if value == "" {
+		return nil, fmt.Errorf("%w: $%s is not set", ErrNotFound, s.variable)
+	}
+
+	data := []byte(value)
+	if !strings.HasPrefix(value, "{") {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode $%s: %w", s.variable, err)
+		}
+		data = decoded
+	}
+
+	session, err := s.opts.decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("$%s: %w", s.variable, err)
+	}
+	return session, nil
+}
+
+func (s *EnvStore) Save(ctx context.Context, session *client.Session) error {
+	return ErrReadOnly
+}
+
+func (s *EnvStore) Delete(ctx context.Context) error {
+	return ErrReadOnly
+}
+
+func (s *EnvStore) String() string {
+	return "env://" + s.variable
+}
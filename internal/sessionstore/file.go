@@ -0,0 +1,65 @@
+package sessionstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"hsm/internal/client"
+	"hsm/internal/utils"
+)
+
+// FileStore keeps the session in a local JSON file
+type FileStore struct {
+	path string
+	opts *options
+}
+
+// NewFileStore creates a store for the session file at path
+func NewFileStore(path string, opts ...Option) *FileStore {
+	return &FileStore{path: path, opts: newOptions(opts)}
+}
+
+// Path returns the location of the session file
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+func (s *FileStore) Load(ctx context.Context) (*client.Session, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, s.path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session file: %w", err)
+	}
+	session, err := s.opts.decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	return session, nil
+}
+
+func (s *FileStore) Save(ctx context.Context, session *client.Session) error {
+	data, err := s.opts.encode(session)
+	if err != nil {
+		return err
+	}
+	return utils.WriteSecretFile(s.path, data)
+}
+
+// Lock takes an advisory lock next to the session file so processes sharing it
+// do not refresh the session concurrently
+func (s *FileStore) Lock(ctx context.Context) (func(), error) {
+	return utils.LockFile(ctx, s.path)
+}
+
+// Delete overwrites the file before removing it
+func (s *FileStore) Delete(ctx context.Context) error {
+	return utils.RemoveSessionFile(s.path)
+}
+
+func (s *FileStore) String() string {
+	return s.path
+}
@@ -0,0 +1,245 @@
+package sessionstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"hsm/internal/client"
+)
+
+const (
+	// DefaultSecretKey is the data key holding the session JSON
+	DefaultSecretKey = "session.json"
+
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+// K8sSecretStore keeps the session in a key of a Kubernetes Secret. It talks to
+// the API server directly using the pod's service account, which needs get,
+// create and patch permissions on the Secret.
+type K8sSecretStore struct {
+	namespace  string
+	name       string
+	opts       *options
+	httpClient *http.Client
+}
+
+// WithSecretKey sets the Secret data key holding the session JSON
+func WithSecretKey(key string) Option {
+	return func(o *options) {
+		o.secretKey = key
+	}
+}
+
+// WithAPIServer sets the Kubernetes API server URL instead of the in-cluster address
+func WithAPIServer(apiServer string) Option {
+	return func(o *options) {
+		o.apiServer = strings.TrimSuffix(apiServer, "/")
+	}
+}
+
+// WithTokenFile sets the Kubernetes bearer token file instead of the service
+// account token. An empty path sends no token.
+func WithTokenFile(path string) Option {
+	return func(o *options) {
+		o.tokenFile = path
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for Kubernetes API server requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = httpClient
+	}
+}
+
+// NewK8sSecretStore creates a store for the Secret namespace/name. Without options
+// it uses the in-cluster API server address, CA and service account token.
+func NewK8sSecretStore(namespace, name string, opts ...Option) *K8sSecretStore {
+	s := &K8sSecretStore{
+		namespace:  namespace,
+		name:       name,
+		opts:       newOptions(opts),
+		httpClient: inClusterHTTPClient(),
+	}
+	if s.opts.httpClient != nil {
+		s.httpClient = s.opts.httpClient
+	}
+	return s
+}
+
+// inClusterHTTPClient trusts the service account CA when it is mounted
+func inClusterHTTPClient() *http.Client {
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	caCert, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return httpClient
+	}
+	pool := x509.NewCertPool()
+	if pool.AppendCertsFromPEM(caCert) {
+		httpClient.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	}
+	return httpClient
+}
+
+// secret is the subset of a Kubernetes Secret used by the store
+type secret struct {
+	APIVersion string            `json:"apiVersion,omitempty"`
+	Kind       string            `json:"kind,omitempty"`
+	Metadata   secretMetadata    `json:"metadata"`
+	Type       string            `json:"type,omitempty"`
+	Data       map[string][]byte `json:"data"` // base64 encoded by encoding/json
+}
+
+type secretMetadata struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+func (s *K8sSecretStore) Load(ctx context.Context) (*client.Session, error) {
+	var sec secret
+	status, err := s.request(ctx, http.MethodGet, s.secretURL(), "", nil, &sec)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, s)
+	}
+
+	data, ok := sec.Data[s.opts.secretKey]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no key %s", ErrNotFound, s, s.opts.secretKey)
+	}
+
+	session, err := s.opts.decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s, err)
+	}
+	return session, nil
+}
+
+// Save updates the session key with a merge patch, creating the Secret if needed.
+// Other keys of the Secret are left alone.
+func (s *K8sSecretStore) Save(ctx context.Context, session *client.Session) error {
+	data, err := s.opts.encode(session)
+	if err != nil {
+		return err
+	}
+
+	patch := map[string]map[string][]byte{"data": {s.opts.secretKey: data}}
+	status, err := s.request(ctx, http.MethodPatch, s.secretURL(), "application/merge-patch+json", patch, nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusNotFound {
+		return nil
+	}
+
+	create := secret{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata: secretMetadata{
+			Name:      s.name,
+			Namespace: s.namespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "hsm"},
+		},
+		Type: "Opaque",
+		Data: map[string][]byte{s.opts.secretKey: data},
+	}
+	collectionURL := fmt.Sprintf("%s/api/v1/namespaces/%s/secrets", s.opts.apiServer, url.PathEscape(s.namespace))
+	status, err = s.request(ctx, http.MethodPost, collectionURL, "application/json", create, nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusConflict {
+		// Created concurrently, patch the now existing Secret
+		_, err = s.request(ctx, http.MethodPatch, s.secretURL(), "application/merge-patch+json", patch, nil)
+	}
+	return err
+}
+
+// Delete removes the session key from the Secret. The Secret itself is kept
+// because it may be managed by other tooling.
+func (s *K8sSecretStore) Delete(ctx context.Context) error {
+	patch := map[string]map[string]any{"data": {s.opts.secretKey: nil}}
+	_, err := s.request(ctx, http.MethodPatch, s.secretURL(), "application/merge-patch+json", patch, nil)
+	return err
+}
+
+func (s *K8sSecretStore) String() string {
+	return fmt.Sprintf("k8s-secret://%s/%s", s.namespace, s.name)
+}
+
+func (s *K8sSecretStore) secretURL() string {
+	return fmt.Sprintf("%s/api/v1/namespaces/%s/secrets/%s", s.opts.apiServer, url.PathEscape(s.namespace), url.PathEscape(s.name))
+}
+
+// request sends a request to the API server. 404 and 409 are returned as status
+// for the caller to handle, other failures as error. out is decoded on success.
+func (s *K8sSecretStore) request(ctx context.Context, method, target, contentType string, in, out any) (int, error) {
+	if s.opts.apiServer == "" {
+		return 0, fmt.Errorf("kubernetes API server unknown: not running in a cluster")
+	}
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if s.opts.tokenFile != "" {
+		// Read the token on every request, Kubernetes rotates it
+		token, err := os.ReadFile(s.opts.tokenFile)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read service account token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request to kubernetes API failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to read kubernetes API response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusConflict:
+		return resp.StatusCode, nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return resp.StatusCode, fmt.Errorf("%s %s: unexpected status %d: %s", method, s, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode kubernetes API response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,157 @@
+package sessionstore_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"hsm/internal/client"
+	"hsm/internal/sessionstore"
+)
+
+// fakeAPIServer implements the Secret endpoints used by K8sSecretStore
+type fakeAPIServer struct {
+	mu      sync.Mutex
+	secrets map[string]map[string]any // namespace/name -> secret object
+	tokens  []string                  // bearer tokens seen
+}
+
+func newFakeAPIServer(t *testing.T) (*fakeAPIServer, *httptest.Server) {
+	f := &fakeAPIServer{secrets: make(map[string]map[string]any)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/namespaces/{ns}/secrets/{name}", f.get)
+	mux.HandleFunc("PATCH /api/v1/namespaces/{ns}/secrets/{name}", f.patch)
+	mux.HandleFunc("POST /api/v1/namespaces/{ns}/secrets", f.create)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeAPIServer) record(r *http.Request) {
+	f.tokens = append(f.tokens, r.Header.Get("Authorization"))
+}
+
+func (f *fakeAPIServer) get(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(r)
+	sec, ok := f.secrets[r.PathValue("ns")+"/"+r.PathValue("name")]
+	if !ok {
+		http.Error(w, `{"kind":"Status","reason":"NotFound"}`, http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(sec)
+}
+
+func (f *fakeAPIServer) patch(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(r)
+	if ct := r.Header.Get("Content-Type"); ct != "application/merge-patch+json" {
+		http.Error(w, "unsupported content type "+ct, http.StatusUnsupportedMediaType)
+		return
+	}
+	sec, ok := f.secrets[r.PathValue("ns")+"/"+r.PathValue("name")]
+	if !ok {
+		http.Error(w, `{"kind":"Status","reason":"NotFound"}`, http.StatusNotFound)
+		return
+	}
+	var patch struct {
+		Data map[string]any `json:"data"`
+	}
+	body, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &patch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, _ := sec["data"].(map[string]any)
+	if data == nil {
+		data = make(map[string]any)
+		sec["data"] = data
+	}
+	for k, v := range patch.Data {
+		if v == nil {
+			delete(data, k)
+		} else {
+			data[k] = v
+		}
+	}
+	_ = json.NewEncoder(w).Encode(sec)
+}
+
+func (f *fakeAPIServer) create(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(r)
+	var sec map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&sec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	meta, _ := sec["metadata"].(map[string]any)
+	name, _ := meta["name"].(string)
+	key := r.PathValue("ns") + "/" + name
+	if _, exists := f.secrets[key]; exists {
+		http.Error(w, `{"kind":"Status","reason":"AlreadyExists"}`, http.StatusConflict)
+		return
+	}
+	f.secrets[key] = sec
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(sec)
+}
+
+func TestK8sSecretStore(t *testing.T) {
+	fake, srv := newFakeAPIServer(t)
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("sa-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := sessionstore.NewK8sSecretStore("games", "hsm-session",
+		sessionstore.WithAPIServer(srv.URL),
+		sessionstore.WithTokenFile(tokenFile),
+		sessionstore.WithHTTPClient(srv.Client()),
+	)
+
+	if _, err := store.Load(t.Context()); !errors.Is(err, sessionstore.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// The first save creates the Secret, the second one patches it
+	for _, token := range []string{"at-1", "at-2"} {
+		if err := store.Save(t.Context(), &client.Session{Token: token, RefreshToken: "rt"}); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		session, err := store.Load(t.Context())
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		if session.Token != token {
+			t.Fatalf("expected token %s, got %s", token, session.Token)
+		}
+	}
+
+	if err := store.Delete(t.Context()); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Load(t.Context()); !errors.Is(err, sessionstore.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after Delete, got %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if _, ok := fake.secrets["games/hsm-session"]; !ok {
+		t.Fatal("expected Secret to be kept after Delete")
+	}
+	for _, auth := range fake.tokens {
+		if auth != "Bearer sa-token" {
+			t.Fatalf("expected service account token, got %q", auth)
+		}
+	}
+}
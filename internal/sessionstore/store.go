@@ -0,0 +1,175 @@
+// Package sessionstore persists the OAuth session outside of the process.
+package sessionstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"hsm/internal/client"
+)
+
+var (
+	// ErrNotFound is returned by Load when the store holds no session
+	ErrNotFound = errors.New("session not found")
+	// ErrReadOnly is returned by Save and Delete of stores that cannot be written
+	ErrReadOnly = errors.New("session store is read-only")
+)
+
+// Store loads and saves the OAuth session
+type Store interface {
+	// Load returns the stored session or an error matching ErrNotFound
+	Load(ctx context.Context) (*client.Session, error)
+	// Save replaces the stored session
+	Save(ctx context.Context, session *client.Session) error
+	// Delete removes the stored session, a missing session is not an error
+	Delete(ctx context.Context) error
+	// String describes the location of the store for messages
+	String() string
+}
+
+// Locker is implemented by stores that can be locked across processes sharing them
+type Locker interface {
+	// Lock waits for exclusive access to the store, call unlock to release it
+	Lock(ctx context.Context) (unlock func(), err error)
+}
+
+// Lock locks store if it implements Locker. Other stores are not shared between
+// processes or rely on the backend for consistency, Lock is a no-op for them.
+func Lock(ctx context.Context, store Store) (func(), error) {
+	if locker, ok := store.(Locker); ok {
+		return locker.Lock(ctx)
+	}
+	return func() {}, nil
+}
+
+// options holds the settings of all store implementations, each store uses the ones relevant to it
+type options struct {
+	cipher     *Cipher
+	secretKey  string
+	apiServer  string
+	tokenFile  string
+	httpClient *http.Client
+}
+
+// Option is a functional option for configuring a store
+type Option func(*options)
+
+// WithCipher encrypts saved sessions. Plaintext sessions are still read so
+// existing stores migrate on the next save.
+func WithCipher(c *Cipher) Option {
+	return func(o *options) {
+		o.cipher = c
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		secretKey: DefaultSecretKey,
+		tokenFile: serviceAccountDir + "/token",
+	}
+	if host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT"); host != "" {
+		o.apiServer = "https://" + net.JoinHostPort(host, port)
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// encode serializes session, encrypting it if a cipher is configured
+func (o *options) encode(session *client.Session) ([]byte, error) {
+	return Encode(session, o.cipher)
+}
+
+// decode parses a plaintext or encrypted session document
+func (o *options) decode(data []byte) (*client.Session, error) {
+	return Decode(data, o.cipher)
+}
+
+// Encode serializes session as stored, encrypted with cipher unless it is nil
+func Encode(session *client.Session, cipher *Cipher) ([]byte, error) {
+	if session == nil {
+		return nil, fmt.Errorf("session cannot be nil")
+	}
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal session: %w", err)
+	}
+	if cipher == nil {
+		return data, nil
+	}
+	return cipher.Encrypt(data)
+}
+
+// Decode parses a plaintext or encrypted session document. Encrypted documents
+// need cipher and fail with ErrEncrypted without one.
+func Decode(data []byte, cipher *Cipher) (*client.Session, error) {
+	if IsEncrypted(data) {
+		if cipher == nil {
+			return nil, ErrEncrypted
+		}
+		plaintext, err := cipher.Decrypt(data)
+		if err != nil {
+			return nil, err
+		}
+		data = plaintext
+	}
+
+	var session client.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+	return &session, nil
+}
+
+// Open returns the store for a URI:
+//
+//	file:///data/session.json       local file (a plain path works as well)
+//	env://HSM_SESSION               read-only, session JSON (or base64 of it) in an environment variable
+//	k8s-secret://namespace/name     Kubernetes Secret, ?key= selects the data key (default session.json)
+func Open(uri string, opts ...Option) (Store, error) {
+	if uri == "" {
+		return nil, fmt.Errorf("empty session store URI")
+	}
+	if !strings.Contains(uri, "://") {
+		return NewFileStore(uri, opts...), nil
+	}
+
+	if path, ok := strings.CutPrefix(uri, "file://"); ok {
+		if path == "" {
+			return nil, fmt.Errorf("invalid session store %q: missing path", uri)
+		}
+		return NewFileStore(path, opts...), nil
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session store %q: %w", uri, err)
+	}
+
+	switch u.Scheme {
+	case "env":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid session store %q: missing variable name", uri)
+		}
+		return NewEnvStore(u.Host, opts...), nil
+	case "k8s-secret":
+		name := strings.Trim(u.Path, "/")
+		if u.Host == "" || name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("invalid session store %q: expected k8s-secret://namespace/name", uri)
+		}
+		if key := u.Query().Get("key"); key != "" {
+			opts = append(opts, WithSecretKey(key))
+		}
+		return NewK8sSecretStore(u.Host, name, opts...), nil
+	}
+
+	return nil, fmt.Errorf("unsupported session store scheme %q", u.Scheme)
+}
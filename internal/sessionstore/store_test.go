@@ -0,0 +1,90 @@
+package sessionstore_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"hsm/internal/client"
+	"hsm/internal/sessionstore"
+)
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"/data/session.json", "/data/session.json"},
+		{"file:///data/session.json", "/data/session.json"},
+		{"env://HSM_SESSION", "env://HSM_SESSION"},
+		{"k8s-secret://games/hsm-session", "k8s-secret://games/hsm-session"},
+		{"k8s-secret://games/hsm-session?key=token.json", "k8s-secret://games/hsm-session"},
+	}
+	for _, tt := range tests {
+		store, err := sessionstore.Open(tt.uri)
+		if err != nil {
+			t.Fatalf("Open(%q): %v", tt.uri, err)
+		}
+		if got := store.String(); got != tt.want {
+			t.Errorf("Open(%q) = %q, want %q", tt.uri, got, tt.want)
+		}
+	}
+
+	for _, uri := range []string{"", "file://", "env://", "k8s-secret://games", "k8s-secret://games/a/b", "s3://bucket/key"} {
+		if _, err := sessionstore.Open(uri); err == nil {
+			t.Errorf("Open(%q): expected error", uri)
+		}
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	store := sessionstore.NewFileStore(filepath.Join(t.TempDir(), "nested", "session.json"))
+
+	if _, err := store.Load(t.Context()); !errors.Is(err, sessionstore.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := store.Save(t.Context(), &client.Session{Token: "at", RefreshToken: "rt"}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	session, err := store.Load(t.Context())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if session.Token != "at" || session.RefreshToken != "rt" {
+		t.Fatalf("unexpected session %+v", session)
+	}
+
+	if err := store.Delete(t.Context()); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Load(t.Context()); !errors.Is(err, sessionstore.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after Delete, got %v", err)
+	}
+}
+
+func TestEnvStore(t *testing.T) {
+	store := sessionstore.NewEnvStore("HSM_TEST_SESSION")
+
+	if _, err := store.Load(t.Context()); !errors.Is(err, sessionstore.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	data, _ := json.Marshal(client.Session{Token: "at"})
+	for _, value := range []string{string(data), base64.StdEncoding.EncodeToString(data)} {
+		t.Setenv("HSM_TEST_SESSION", value)
+		session, err := store.Load(t.Context())
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		if session.Token != "at" {
+			t.Fatalf("unexpected session %+v", session)
+		}
+	}
+
+	if err := store.Save(t.Context(), &client.Session{}); !errors.Is(err, sessionstore.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"archive/zip"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,8 +12,13 @@ import (
 )
 
 // DownloadFile downloads a file from url to dest with progress bar
-func DownloadFile(url, dest string) error {
-	resp, err := http.Get(url)
+func DownloadFile(ctx context.Context, url, dest string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("request failed: %w", err)
 	}
@@ -104,7 +110,7 @@ func extractFile(f *zip.File, destAbs string) error {
 }
 
 // DownloadAndExtract downloads a zip from url and extracts to dest
-func DownloadAndExtract(url, dest string) error {
+func DownloadAndExtract(ctx context.Context, url, dest string) error {
 	tmp, err := os.CreateTemp("", "download-*.zip")
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
@@ -113,7 +119,7 @@ func DownloadAndExtract(url, dest string) error {
 	_ = tmp.Close()
 	defer func() { _ = os.Remove(tmpPath) }()
 
-	if err := DownloadFile(url, tmpPath); err != nil {
+	if err := DownloadFile(ctx, url, tmpPath); err != nil {
 		return err
 	}
 
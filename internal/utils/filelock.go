@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockPollInterval is how often a contended lock is retried
+const lockPollInterval = 50 * time.Millisecond
+
+// LockFile takes an exclusive advisory lock on path+".lock", waiting until it is
+// available or ctx is done. The lock coordinates processes sharing a file, it
+// does not prevent other writers. Call the returned function to release it.
+func LockFile(ctx context.Context, path string) (func(), error) {
+	lockPath := path + ".lock"
+	if dir := filepath.Dir(lockPath); dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+
+	for {
+		unlock, err := tryLockFile(lockPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock %s: %w", lockPath, err)
+		}
+		if unlock != nil {
+			return unlock, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to lock %s: %w", lockPath, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
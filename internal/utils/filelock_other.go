@@ -0,0 +1,32 @@
+//go:build !unix
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// staleLockAge is after how long a lock file left behind by a crashed process is ignored
+const staleLockAge = 2 * time.Minute
+
+// tryLockFile creates lockPath exclusively on platforms without flock. It
+// returns a nil unlock function if the lock is held by someone else.
+func tryLockFile(lockPath string) (func(), error) {
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			_ = os.Remove(lockPath)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	_ = f.Close()
+
+	return func() {
+		_ = os.Remove(lockPath)
+	}, nil
+}
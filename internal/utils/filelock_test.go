@@ -0,0 +1,67 @@
+package utils_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hsm/internal/utils"
+)
+
+func TestLockFileIsExclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	unlock, err := utils.LockFile(t.Context(), path)
+	if err != nil {
+		t.Fatalf("LockFile: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := utils.LockFile(ctx, path); err == nil {
+		t.Fatal("expected second lock to wait until the context is done")
+	}
+
+	unlock()
+	unlock2, err := utils.LockFile(t.Context(), path)
+	if err != nil {
+		t.Fatalf("LockFile after unlock: %v", err)
+	}
+	unlock2()
+}
+
+func TestWriteSecretFileReplacesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "session.json")
+
+	for _, content := range []string{"first", "second"} {
+		if err := utils.WriteSecretFile(path, []byte(content)); err != nil {
+			t.Fatalf("WriteSecretFile: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "second" {
+		t.Fatalf("unexpected content %q", data)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("expected mode 0600, got %v", perm)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected no temporary files to be left behind, got %d entries", len(entries))
+	}
+}
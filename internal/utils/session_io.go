@@ -1,18 +1,16 @@
 package utils
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"hsm/internal/client"
 	"os"
 	"path/filepath"
 )
 
-func SaveSessionToFile(filePath string, session *client.Session) error {
-	if session == nil {
-		return fmt.Errorf("session cannot be nil")
-	}
-
+// WriteSecretFile atomically replaces filePath with data readable only by the
+// current user, creating the parent directory if needed. Readers see either the
+// old or the new content, never a partial write.
+func WriteSecretFile(filePath string, data []byte) error {
 	dir := filepath.Dir(filePath)
 	if dir != "" && dir != "." {
 		if err := os.MkdirAll(dir, 0755); err != nil {
@@ -20,28 +18,62 @@ func SaveSessionToFile(filePath string, session *client.Session) error {
 		}
 	}
 
-	data, err := json.MarshalIndent(session, "", "  ")
+	// The temporary file must live in the same directory for the rename to be atomic
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+".*.tmp")
 	if err != nil {
-		return fmt.Errorf("failed to marshal session: %w", err)
+		return fmt.Errorf("failed to create temporary session file: %w", err)
 	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }() // no-op after a successful rename
 
-	if err := os.WriteFile(filePath, data, 0600); err != nil {
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0600)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
 		return fmt.Errorf("failed to write session file: %w", err)
 	}
 
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("failed to replace session file: %w", err)
+	}
+
 	return nil
 }
 
-func ReadSessionFromFile(filePath string) (*client.Session, error) {
-	data, err := os.ReadFile(filePath)
+// RemoveSessionFile overwrites the session file with zeros before removing it so
+// the tokens do not linger on disk. A missing file is not an error.
+func RemoveSessionFile(filePath string) error {
+	f, err := os.OpenFile(filePath, os.O_WRONLY, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read session file: %w", err)
+		return fmt.Errorf("failed to open session file: %w", err)
 	}
 
-	var session client.Session
-	if err := json.Unmarshal(data, &session); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	info, err := f.Stat()
+	if err == nil {
+		_, err = f.Write(make([]byte, info.Size()))
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to overwrite session file: %w", err)
 	}
 
-	return &session, nil
+	if err := os.Remove(filePath); err != nil {
+		return fmt.Errorf("failed to remove session file: %w", err)
+	}
+	return nil
 }